@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AdaptiveTimeout подстраивает таймаут цели под её задержки: таймаут равен
+// multiplier × p99 последних успешных проверок в пределах [min, max]
+type AdaptiveTimeout struct {
+	Multiplier float64       `yaml:"multiplier,omitempty"` // по умолчанию 3
+	Min        time.Duration `yaml:"min,omitempty"`        // по умолчанию 100ms
+	Max        time.Duration `yaml:"max,omitempty"`        // по умолчанию 30s
+	// Число последних успешных проверок, по которым считается p99
+	Window int `yaml:"window,omitempty"` // по умолчанию 200
+}
+
+const (
+	defaultAdaptiveMultiplier = 3
+	defaultAdaptiveMin        = 100 * time.Millisecond
+	defaultAdaptiveMax        = 30 * time.Second
+	defaultAdaptiveWindow     = 200
+	// Пока проверок меньше, действует timeout цели, а если он не задан — max
+	adaptiveMinSamples = 20
+)
+
+// adaptiveState — скользящее окно задержек цели; общее для копий цели
+type adaptiveState struct {
+	cfg AdaptiveTimeout
+
+	mu      sync.Mutex
+	samples []time.Duration // кольцевой буфер
+	next    int
+}
+
+func (a *AdaptiveTimeout) compile() (*adaptiveState, error) {
+	cfg := *a
+	if cfg.Multiplier == 0 {
+		cfg.Multiplier = defaultAdaptiveMultiplier
+	}
+	if cfg.Min == 0 {
+		cfg.Min = defaultAdaptiveMin
+	}
+	if cfg.Max == 0 {
+		cfg.Max = defaultAdaptiveMax
+	}
+	if cfg.Window == 0 {
+		cfg.Window = defaultAdaptiveWindow
+	}
+	if cfg.Multiplier < 1 {
+		return nil, fmt.Errorf("adaptive_timeout.multiplier должен быть не меньше 1")
+	}
+	if cfg.Min < 0 || cfg.Max < cfg.Min {
+		return nil, fmt.Errorf("adaptive_timeout: нужно 0 <= min <= max")
+	}
+	if cfg.Window < adaptiveMinSamples {
+		return nil, fmt.Errorf("adaptive_timeout.window должен быть не меньше %d", adaptiveMinSamples)
+	}
+	return &adaptiveState{cfg: cfg}, nil
+}
+
+// observe добавляет задержку успешной проверки в окно
+func (s *adaptiveState) observe(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) < s.cfg.Window {
+		s.samples = append(s.samples, latency)
+		return
+	}
+	s.samples[s.next] = latency
+	s.next = (s.next + 1) % s.cfg.Window
+}
+
+// timeout возвращает текущий таймаут; configured — timeout цели для периода накопления окна
+func (s *adaptiveState) timeout(configured time.Duration) time.Duration {
+	s.mu.Lock()
+	if len(s.samples) < adaptiveMinSamples {
+		s.mu.Unlock()
+		if configured > 0 {
+			return configured
+		}
+		return s.cfg.Max
+	}
+	sorted := append([]time.Duration(nil), s.samples...)
+	s.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	d := time.Duration(float64(percentile(sorted, 99)) * s.cfg.Multiplier)
+	return min(max(d, s.cfg.Min), s.cfg.Max)
+}
+
+// effectiveTimeout возвращает таймаут очередной проверки цели
+func (t Target) effectiveTimeout() time.Duration {
+	if t.adaptive == nil {
+		return t.Timeout
+	}
+	return t.adaptive.timeout(t.Timeout)
+}
+
+// observeLatency учитывает результат проверки в адаптивном таймауте цели
+func (t Target) observeLatency(r CheckResult) {
+	if t.adaptive != nil && r.Success && !r.Shed {
+		t.adaptive.observe(r.Latency)
+	}
+}
+
+// seedAdaptiveTimeouts заполняет окна адаптивных таймаутов задержками из базы результатов,
+// чтобы после перезапуска таймаут не начинал с периода накопления
+func seedAdaptiveTimeouts(store *ResultStore, targets []Target) error {
+	for _, t := range targets {
+		if t.adaptive == nil {
+			continue
+		}
+		latencies, err := store.RecentLatencies(t.Name, t.adaptive.cfg.Window)
+		if err != nil {
+			return err
+		}
+		// База возвращает задержки от новых к старым
+		for i := len(latencies) - 1; i >= 0; i-- {
+			t.adaptive.observe(latencies[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	agentFlushInterval  = 5 * time.Second
+	agentRetryInterval  = 10 * time.Second
+	agentRequestTimeout = 30 * time.Second
+	// Сколько результатов агент хранит, пока координатор недоступен
+	agentMaxPending = 10000
+)
+
+// runAgent реализует подкоманду agent: цели и интервал проверок берутся у координатора
+// (apichecker -daemon -http), результаты с пометкой региона отправляются ему обратно
+func runAgent(args []string) error {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	coordinator := fs.String("coordinator", "", "Адрес координатора, например http://checker.internal:8080")
+	region := fs.String("region", "", "Регион агента, например eu-west")
+	hostname, _ := os.Hostname()
+	name := fs.String("name", hostname, "Имя агента")
+	token := fs.String("token", os.Getenv("APICHECKER_API_TOKEN"), "Токен API координатора (-api-token), по умолчанию из APICHECKER_API_TOKEN")
+	interval := fs.Duration("t", 0, "Интервал проверок (по умолчанию как у координатора)")
+	refresh := fs.Duration("refresh", time.Minute, "Как часто запрашивать у координатора изменения целей")
+	concurrency := fs.Int("concurrency", 0, "Максимальное число одновременных проверок, 0 — без ограничения")
+	fs.Parse(args)
+
+	if *coordinator == "" || *region == "" {
+		return fmt.Errorf("использование: agent -coordinator url -region регион [флаги]")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &agentClient{
+		base:   strings.TrimSuffix(*coordinator, "/"),
+		token:  *token,
+		name:   *name,
+		region: *region,
+		client: &http.Client{Timeout: agentRequestTimeout},
+	}
+
+	// Без целей проверять нечего: ждём, пока координатор станет доступен
+	var assignment agentAssignment
+	for {
+		var err error
+		if assignment, err = a.fetch(ctx); err == nil {
+			break
+		}
+		slog.Warn("Координатор недоступен", "error", err, "retry", agentRetryInterval)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(agentRetryInterval):
+		}
+	}
+	if *interval <= 0 {
+		*interval = assignment.Interval
+	}
+	slog.Info("Агент запущен", "agent", a.name, "region", a.region, "targets", len(assignment.Targets), "interval", *interval)
+
+	registry := newTargetRegistry(assignment.Targets, TargetDefaults{})
+	go a.refresh(ctx, registry, *refresh)
+	go a.run(ctx)
+
+	runTests(ctx, registry, runOptions{
+		Interval:    *interval,
+		Concurrency: *concurrency,
+		OnResult:    a.add,
+	})
+
+	// Оставшиеся результаты отправляются после остановки проверок
+	flushCtx, cancel := context.WithTimeout(context.Background(), sinkShutdownTimeout)
+	defer cancel()
+	if err := a.flush(flushCtx); err != nil {
+		slog.Warn("Ошибка при отправке результатов координатору", "error", err)
+	}
+	return nil
+}
+
+// agentClient получает цели у координатора и отправляет ему результаты пачками
+type agentClient struct {
+	base, token  string
+	name, region string
+	client       *http.Client
+
+	mu      sync.Mutex
+	pending []CheckResult
+	dropped int
+}
+
+func (a *agentClient) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// fetch запрашивает цели у координатора; цели с ошибками пропускаются
+func (a *agentClient) fetch(ctx context.Context) (agentAssignment, error) {
+	var assignment agentAssignment
+	data, err := a.do(ctx, http.MethodGet, "/api/agent/targets", nil)
+	if err != nil {
+		return assignment, err
+	}
+	if err := yaml.Unmarshal(data, &assignment); err != nil {
+		return assignment, err
+	}
+
+	// Координатор присылает цели с уже применёнными defaults
+	targets := assignment.Targets[:0]
+	for _, t := range assignment.Targets {
+		if err := t.normalize(); err != nil {
+			slog.Warn("Цель от координатора пропущена", "target", t.Name, "error", err)
+			continue
+		}
+		targets = append(targets, t)
+	}
+	assignment.Targets = targets
+	return assignment, nil
+}
+
+func (a *agentClient) refresh(ctx context.Context, registry *targetRegistry, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			assignment, err := a.fetch(ctx)
+			if err != nil {
+				slog.Warn("Ошибка при обновлении целей", "error", err)
+				continue
+			}
+			registry.replace(assignment.Targets)
+		}
+	}
+}
+
+func (a *agentClient) add(r CheckResult) {
+	if r.Shed {
+		return
+	}
+	r.Region = a.region
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, r)
+	if over := len(a.pending) - agentMaxPending; over > 0 {
+		a.pending = a.pending[over:]
+		a.dropped += over
+	}
+}
+
+func (a *agentClient) run(ctx context.Context) {
+	ticker := time.NewTicker(agentFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.flush(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("Ошибка при отправке результатов координатору", "error", err)
+			}
+		}
+	}
+}
+
+// flush отправляет накопленные результаты; при ошибке они остаются в очереди
+func (a *agentClient) flush(ctx context.Context) error {
+	a.mu.Lock()
+	batch, dropped := a.pending, a.dropped
+	a.pending, a.dropped = nil, 0
+	a.mu.Unlock()
+	if dropped > 0 {
+		slog.Warn("Координатор долго недоступен, результаты отброшены", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(agentReport{Agent: a.name, Region: a.region, Results: batch})
+	if err == nil {
+		_, err = a.do(ctx, http.MethodPost, "/api/agent/results", data)
+	}
+	if err != nil {
+		a.mu.Lock()
+		a.pending = append(batch, a.pending...)
+		if over := len(a.pending) - agentMaxPending; over > 0 {
+			a.pending = a.pending[over:]
+			a.dropped += over
+		}
+		a.mu.Unlock()
+	}
+	return err
+}
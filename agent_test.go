@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Строки набора данных передаются агенту вместе с целью: у агента нет файла набора
+func TestAgentFetchDatasetTargets(t *testing.T) {
+	dir := t.TempDir()
+	csv := filepath.Join(dir, "products.csv")
+	if err := ioutil.WriteFile(csv, []byte("id,sku\n42,a-1\n43,b-2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := `targets:
+  - name: products
+    url: https://api.example.com/products/{{.id}}
+    headers:
+      X-Sku: "{{.sku}}"
+    dataset:
+      file: ` + csv + `
+      name: id
+`
+	if err := ioutil.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &controlAPI{
+		registry: newTargetRegistry(loaded.Targets, TargetDefaults{}),
+		live:     newLiveStore(),
+		health:   newHealthTracker(defaultHealthConfig()),
+		regions:  newRegionCollector(""),
+		interval: time.Minute,
+	}
+	mux := http.NewServeMux()
+	registerControlAPI(mux, api)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	agent := &agentClient{base: srv.URL, name: "a", client: srv.Client()}
+	assignment, err := agent.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assignment.Targets) != 2 {
+		t.Fatalf("получено целей %d, ожидалось 2", len(assignment.Targets))
+	}
+
+	want := map[string]renderedRequest{
+		"products [42]": {url: "https://api.example.com/products/42", headers: map[string]string{"X-Sku": "a-1"}},
+		"products [43]": {url: "https://api.example.com/products/43", headers: map[string]string{"X-Sku": "b-2"}},
+	}
+	for _, target := range assignment.Targets {
+		if target.Dataset != nil || target.DatasetRow == nil || target.DatasetRow.Dataset != "products" {
+			t.Errorf("%s: dataset %+v, dataset_row %+v", target.Name, target.Dataset, target.DatasetRow)
+			continue
+		}
+		got, err := target.render()
+		if err != nil {
+			t.Errorf("%s: %v", target.Name, err)
+			continue
+		}
+		if w := want[target.Name]; got.url != w.url || !reflect.DeepEqual(got.headers, w.headers) {
+			t.Errorf("%s: получено %+v, ожидалось %+v", target.Name, got, w)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Шаблоны сообщений по умолчанию
+const (
+	defaultAlertTemplate   = `🔴 {{.Target}}: проверки не проходят ({{.Reason}}). Успешных за окно: {{printf "%.2f" .SuccessRate}}%. Последняя ошибка: {{.LastError}}`
+	defaultResolveTemplate = `🟢 {{.Target}}: работа восстановлена. Успешных за окно: {{printf "%.2f" .SuccessRate}}%.`
+	defaultBurnTemplate    = `🔥 {{.Target}}: {{.Reason}}. Успешных за длинное окно: {{printf "%.2f" .SuccessRate}}%.`
+	defaultBurnResolve     = `🟢 {{.Target}}: {{.Reason}} прекратилось.`
+)
+
+type AlertConfig struct {
+	// Порог процента успешных проверок в окне последних Window проверок
+	SuccessThreshold float64 `yaml:"success_threshold"`
+	Window           int     `yaml:"window"`
+	// Количество подряд неуспешных проверок, после которого отправляется оповещение
+	ConsecutiveFailures int `yaml:"consecutive_failures"`
+
+	Template        string `yaml:"template"`
+	ResolveTemplate string `yaml:"resolve_template"`
+
+	// Оповещения о скорости расходования бюджета ошибок для целей с SLO
+	BurnRate *BurnRateConfig `yaml:"burn_rate"`
+
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+}
+
+type NotifierConfig struct {
+	Type string `yaml:"type"` // webhook, slack или telegram
+
+	URL     string `yaml:"url"`     // webhook и входящий вебхук Slack
+	Channel string `yaml:"channel"` // slack
+
+	BotToken string `yaml:"bot_token"` // telegram
+	ChatID   string `yaml:"chat_id"`   // telegram
+}
+
+// Alert передаётся в шаблоны сообщений и в тело webhook-оповещения
+type Alert struct {
+	Target              string    `json:"target"`
+	Resolved            bool      `json:"resolved"`
+	Reason              string    `json:"reason,omitempty"`
+	SuccessRate         float64   `json:"success_rate"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	BurnRate            float64   `json:"burn_rate,omitempty"`
+	Time                time.Time `json:"time"`
+	Message             string    `json:"message"`
+}
+
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// Alerter рассылает оповещения о переходах целей в состояние down и обратно в healthy;
+// условия переходов задаются правилами состояний здоровья (health)
+type Alerter struct {
+	cfg       AlertConfig
+	notifiers []Notifier
+	// Собственные каналы целей, заданные в target.notifiers или defaults.notifiers
+	targetNotifiers map[string][]Notifier
+	alertTmpl       *template.Template
+	resolved        *template.Template
+	burnTmpl        *template.Template
+	burnResolved    *template.Template
+
+	mu sync.Mutex
+	// Цели, о сбое которых отправлено оповещение без последующего восстановления
+	firing map[string]bool
+	wg     sync.WaitGroup
+}
+
+// newAlerter создаёт рассылку оповещений; withHealth сообщает, что правила переходов
+// заданы в разделе health, и пороги в alerts не обязательны
+func newAlerter(cfg AlertConfig, withHealth bool, targets []Target) (*Alerter, error) {
+	if cfg.Template == "" {
+		cfg.Template = defaultAlertTemplate
+	}
+	if cfg.ResolveTemplate == "" {
+		cfg.ResolveTemplate = defaultResolveTemplate
+	}
+	if !withHealth && cfg.SuccessThreshold <= 0 && cfg.ConsecutiveFailures <= 0 && cfg.BurnRate == nil {
+		return nil, fmt.Errorf("alerts: нужно задать success_threshold, consecutive_failures, burn_rate или раздел health")
+	}
+
+	a := &Alerter{
+		cfg:             cfg,
+		firing:          make(map[string]bool),
+		targetNotifiers: make(map[string][]Notifier),
+	}
+
+	var err error
+	if a.alertTmpl, err = template.New("alert").Parse(cfg.Template); err != nil {
+		return nil, fmt.Errorf("alerts.template: %w", err)
+	}
+	if a.resolved, err = template.New("resolve").Parse(cfg.ResolveTemplate); err != nil {
+		return nil, fmt.Errorf("alerts.resolve_template: %w", err)
+	}
+	burnTemplate, burnResolve := defaultBurnTemplate, defaultBurnResolve
+	if cfg.BurnRate != nil {
+		if cfg.BurnRate.Template != "" {
+			burnTemplate = cfg.BurnRate.Template
+		}
+		if cfg.BurnRate.ResolveTemplate != "" {
+			burnResolve = cfg.BurnRate.ResolveTemplate
+		}
+	}
+	if a.burnTmpl, err = template.New("burn").Parse(burnTemplate); err != nil {
+		return nil, fmt.Errorf("alerts.burn_rate.template: %w", err)
+	}
+	if a.burnResolved, err = template.New("burn_resolve").Parse(burnResolve); err != nil {
+		return nil, fmt.Errorf("alerts.burn_rate.resolve_template: %w", err)
+	}
+
+	for _, nc := range cfg.Notifiers {
+		n, err := newNotifier(nc)
+		if err != nil {
+			return nil, err
+		}
+		a.notifiers = append(a.notifiers, n)
+	}
+
+	for _, t := range targets {
+		if err := a.setTargetNotifiers(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// setTargetNotifiers подключает собственные каналы оповещений цели, если они заданы
+func (a *Alerter) setTargetNotifiers(t Target) error {
+	if t.Notifiers == nil {
+		a.mu.Lock()
+		delete(a.targetNotifiers, t.Name)
+		a.mu.Unlock()
+		return nil
+	}
+
+	notifiers := []Notifier{}
+	for _, nc := range t.Notifiers {
+		n, err := newNotifier(nc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	a.mu.Lock()
+	a.targetNotifiers[t.Name] = notifiers
+	a.mu.Unlock()
+	return nil
+}
+
+// forget сбрасывает состояние удалённой цели
+func (a *Alerter) forget(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.firing, name)
+	delete(a.targetNotifiers, name)
+}
+
+func newNotifier(nc NotifierConfig) (Notifier, error) {
+	switch nc.Type {
+	case "webhook":
+		if nc.URL == "" {
+			return nil, fmt.Errorf("webhook: не указан url")
+		}
+		return webhookNotifier{url: nc.URL}, nil
+	case "slack":
+		if nc.URL == "" {
+			return nil, fmt.Errorf("slack: не указан url вебхука")
+		}
+		return slackNotifier{url: nc.URL, channel: nc.Channel}, nil
+	case "telegram":
+		if nc.BotToken == "" || nc.ChatID == "" {
+			return nil, fmt.Errorf("telegram: нужны bot_token и chat_id")
+		}
+		return telegramNotifier{token: nc.BotToken, chatID: nc.ChatID}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный тип оповещения %q", nc.Type)
+	}
+}
+
+// onHealthTransition отправляет оповещение при переходе цели в down и о восстановлении
+// при возврате в healthy. Повторный переход в down до восстановления оповещения не вызывает.
+func (a *Alerter) onHealthTransition(tr HealthTransition) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alert := Alert{
+		Target:              tr.Target,
+		Reason:              tr.Reason,
+		SuccessRate:         tr.State.SuccessRate,
+		ConsecutiveFailures: tr.State.ConsecutiveFailures,
+		LastError:           tr.State.LastError,
+		Time:                tr.Time,
+	}
+
+	switch {
+	case tr.To == HealthDown && !a.firing[tr.Target]:
+		a.firing[tr.Target] = true
+		a.send(a.alertTmpl, alert)
+	case tr.To == HealthHealthy && a.firing[tr.Target]:
+		delete(a.firing, tr.Target)
+		alert.Resolved = true
+		a.send(a.resolved, alert)
+	}
+}
+
+func (a *Alerter) send(tmpl *template.Template, alert Alert) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, alert); err != nil {
+		slog.Error("Ошибка при формировании оповещения", "target", alert.Target, "error", err)
+		return
+	}
+	alert.Message = buf.String()
+	slog.Info("Оповещение", "target", alert.Target, "resolved", alert.Resolved, "message", alert.Message)
+
+	notifiers, ok := a.targetNotifiers[alert.Target]
+	if !ok {
+		notifiers = a.notifiers
+	}
+
+	for _, n := range notifiers {
+		a.wg.Add(1)
+		go func(n Notifier) {
+			defer a.wg.Done()
+			if err := n.Notify(alert); err != nil {
+				slog.Error("Ошибка при отправке оповещения", "target", alert.Target, "error", err)
+			}
+		}(n)
+	}
+}
+
+// notifyBurn отправляет оповещение о расходе бюджета ошибок или о его прекращении
+func (a *Alerter) notifyBurn(alert Alert) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if alert.Resolved {
+		a.send(a.burnResolved, alert)
+	} else {
+		a.send(a.burnTmpl, alert)
+	}
+}
+
+// Wait дожидается отправки всех оповещений
+func (a *Alerter) Wait() {
+	a.wg.Wait()
+}
+
+func successRate(window []bool) float64 {
+	if len(window) == 0 {
+		return 100
+	}
+	ok := 0
+	for _, s := range window {
+		if s {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(window)) * 100
+}
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+func postJSON(endpoint string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := notifyClient.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		// Адрес не попадает в текст ошибки: в нём может быть токен
+		if ue, ok := err.(*url.Error); ok {
+			return ue.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("неожиданный статус %s", resp.Status)
+	}
+	return nil
+}
+
+type webhookNotifier struct {
+	url string
+}
+
+func (n webhookNotifier) Notify(alert Alert) error {
+	if err := postJSON(n.url, alert); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+type slackNotifier struct {
+	url     string
+	channel string
+}
+
+func (n slackNotifier) Notify(alert Alert) error {
+	payload := map[string]string{"text": alert.Message}
+	if n.channel != "" {
+		payload["channel"] = n.channel
+	}
+	if err := postJSON(n.url, payload); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	return nil
+}
+
+type telegramNotifier struct {
+	token  string
+	chatID string
+}
+
+func (n telegramNotifier) Notify(alert Alert) error {
+	endpoint := "https://api.telegram.org/bot" + n.token + "/sendMessage"
+	if err := postJSON(endpoint, map[string]string{"chat_id": n.chatID, "text": alert.Message}); err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	return nil
+}
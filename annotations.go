@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Annotation — пометка оператора для периода времени: выкладка, известная проблема, заметка.
+// Аннотация-момент (например, выкладка) имеет End, равный Start.
+type Annotation struct {
+	ID     int64     `json:"id"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Target string    `json:"target,omitempty"` // пустое значение относится ко всем целям
+	Kind   string    `json:"kind,omitempty"`   // например deploy, issue, note
+	Text   string    `json:"text"`
+}
+
+// normalize проставляет время по умолчанию и проверяет аннотацию
+func (a *Annotation) normalize() error {
+	if strings.TrimSpace(a.Text) == "" {
+		return fmt.Errorf("не указан текст аннотации")
+	}
+	if a.Start.IsZero() {
+		a.Start = time.Now()
+	}
+	if a.End.IsZero() {
+		a.End = a.Start
+	}
+	if a.End.Before(a.Start) {
+		return fmt.Errorf("конец аннотации раньше начала")
+	}
+	return nil
+}
+
+// covers сообщает, пересекается ли аннотация с периодом [from, to] и относится ли к цели
+func (a Annotation) covers(target string, from, to time.Time) bool {
+	if a.Target != "" && target != "" && a.Target != target {
+		return false
+	}
+	return !a.Start.After(to) && !a.End.Before(from)
+}
+
+// runAnnotate реализует подкоманду annotate:
+//
+//	annotate [-target name] [-kind deploy] [-at time] [-to time | -duration d] текст
+//	annotate -list [-since 24h] [-target name]
+//	annotate -delete id
+func runAnnotate(args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+	dbPath := fs.String("db", "results.db", "Путь к базе результатов")
+	target := fs.String("target", "", "Цель аннотации (по умолчанию все цели)")
+	kind := fs.String("kind", "", "Вид аннотации, например deploy или issue")
+	at := fs.String("at", "", "Момент или начало периода (RFC3339, по умолчанию сейчас)")
+	toStr := fs.String("to", "", "Конец периода (RFC3339)")
+	duration := fs.Duration("duration", 0, "Длительность периода от -at")
+	list := fs.Bool("list", false, "Показать аннотации")
+	since := fs.Duration("since", 24*time.Hour, "Период для -list от текущего момента")
+	del := fs.Int64("delete", 0, "Удалить аннотацию с указанным идентификатором")
+	fs.Parse(args)
+
+	store, err := openStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch {
+	case *list:
+		now := time.Now()
+		annotations, err := store.Annotations(*target, now.Add(-*since), now)
+		if err != nil {
+			return err
+		}
+		if len(annotations) == 0 {
+			fmt.Println("Аннотаций нет.")
+			return nil
+		}
+		return printAnnotations(os.Stdout, annotations)
+
+	case *del != 0:
+		ok, err := store.DeleteAnnotation(*del)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("аннотация %d не найдена", *del)
+		}
+		fmt.Printf("Аннотация %d удалена.\n", *del)
+		return nil
+	}
+
+	a := Annotation{Target: *target, Kind: *kind, Text: strings.Join(fs.Args(), " "), Start: time.Now()}
+	if *at != "" {
+		if a.Start, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fmt.Errorf("-at: %w", err)
+		}
+	}
+	switch {
+	case *toStr != "":
+		if a.End, err = time.Parse(time.RFC3339, *toStr); err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+	case *duration > 0:
+		a.End = a.Start.Add(*duration)
+	}
+	if err := a.normalize(); err != nil {
+		return err
+	}
+
+	a, err = store.AddAnnotation(a)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Аннотация %d добавлена.\n", a.ID)
+	return nil
+}
+
+// printAnnotations выводит аннотации таблицей
+func printAnnotations(out io.Writer, annotations []Annotation) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "id\tпериод\tцель\tвид\tтекст")
+	for _, a := range annotations {
+		target := a.Target
+		if target == "" {
+			target = "все"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", a.ID, a.period(), target, a.Kind, a.Text)
+	}
+	return w.Flush()
+}
+
+// printRunAnnotations выводит аннотации, пересекающиеся с временем проверок запусков
+func printRunAnnotations(out io.Writer, store *ResultStore, runs ...TestResult) error {
+	var from, to time.Time
+	for _, run := range runs {
+		for _, r := range run.Results {
+			if from.IsZero() || r.Timestamp.Before(from) {
+				from = r.Timestamp
+			}
+			if r.Timestamp.After(to) {
+				to = r.Timestamp
+			}
+		}
+	}
+	if from.IsZero() {
+		return nil
+	}
+
+	annotations, err := store.Annotations("", from, to.Add(time.Nanosecond))
+	if err != nil || len(annotations) == 0 {
+		return err
+	}
+	fmt.Fprintln(out, "\nАннотации за период запусков:")
+	return printAnnotations(out, annotations)
+}
+
+// period форматирует время аннотации
+func (a Annotation) period() string {
+	const layout = "2006-01-02 15:04:05"
+	if a.End.Equal(a.Start) {
+		return a.Start.Format(layout)
+	}
+	return a.Start.Format(layout) + " — " + a.End.Format(layout)
+}
+
+// parseAnnotationID разбирает идентификатор из пути API
+func parseAnnotationID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("некорректный идентификатор аннотации %q", s)
+	}
+	return id, nil
+}
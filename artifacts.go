@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultArtifactBody — сколько байт тела запроса и ответа сохраняется по умолчанию
+const defaultArtifactBody = 64 << 10
+
+// artifactWriter сохраняет запрос и ответ неуспешных проверок в каталог запуска:
+// по файлу на неуспешную попытку, путь к нему записывается в результат
+type artifactWriter struct {
+	dir     string
+	maxBody int
+	seq     uint64
+}
+
+// artifacts включается флагом -artifacts
+var artifacts *artifactWriter
+
+// newArtifactWriter создаёт в root каталог текущего запуска
+func newArtifactWriter(root string, maxBody int) (*artifactWriter, error) {
+	if maxBody <= 0 {
+		maxBody = defaultArtifactBody
+	}
+	dir := filepath.Join(root, "run-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &artifactWriter{dir: dir, maxBody: maxBody}, nil
+}
+
+// bodyLimit возвращает, сколько байт тела ответа нужно сохранить для артефакта
+func (a *artifactWriter) bodyLimit() int {
+	if a == nil {
+		return 0
+	}
+	return a.maxBody
+}
+
+// capture сохраняет запрос, ответ (nil, если он не получен) и начало тела ответа
+// неуспешной проверки и записывает путь к файлу в результат
+func (a *artifactWriter) capture(target Target, req *http.Request, resp *http.Response, body []byte, result *CheckResult) {
+	if a == nil || result.Success {
+		return
+	}
+	n := atomic.AddUint64(&a.seq, 1)
+	path := filepath.Join(a.dir, fmt.Sprintf("%04d-%s.txt", n, artifactName(target.Name)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# цель: %s\n# время: %s\n# ошибка: %s\n\n", target.Name, result.Timestamp.Format(time.RFC3339Nano), result.Error)
+
+	fmt.Fprintf(&b, "%s %s %s\n", req.Method, redactURL(req.URL), req.Proto)
+	writeArtifactHeaders(&b, req.Header)
+	// Тело запроса уже в памяти: GetBody возвращает его копию
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			a.writeBody(&b, data, int64(len(data)))
+		}
+	}
+
+	if resp != nil {
+		fmt.Fprintf(&b, "\n%s %s\n", resp.Proto, resp.Status)
+		writeArtifactHeaders(&b, resp.Header)
+		a.writeBody(&b, body, result.Size)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		slog.Error("Ошибка при сохранении запроса и ответа проверки", "target", target.Name, "error", err)
+		return
+	}
+	result.Artifact = path
+}
+
+func (a *artifactWriter) writeBody(b *strings.Builder, data []byte, size int64) {
+	b.WriteString("\n")
+	if len(data) > a.maxBody {
+		data = data[:a.maxBody]
+	}
+	b.Write(data)
+	if size > int64(len(data)) {
+		fmt.Fprintf(b, "\n# тело обрезано: сохранено %d из %d байт", len(data), size)
+	}
+	b.WriteString("\n")
+}
+
+// writeArtifactHeaders записывает заголовки по алфавиту со скрытыми значениями секретов
+func writeArtifactHeaders(b *strings.Builder, h http.Header) {
+	redacted := redactHeaders(h)
+	names := make([]string, 0, len(redacted))
+	for name := range redacted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "%s: %s\n", name, redacted[name])
+	}
+}
+
+// artifactName превращает имя цели в часть имени файла
+func artifactName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return name
+}
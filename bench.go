@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// runBench реализует подкоманду bench: запросы к одной цели выполняются -c параллельными
+// исполнителями, пока не будет выполнено -n запросов или не истечёт -duration,
+// после чего выводится отчёт о пропускной способности и задержках
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	rawURL := fs.String("url", "", "URL цели")
+	configPath := fs.String("config", "", "Конфигурация, из которой берётся описание запроса")
+	targetName := fs.String("target", "", "Имя цели из -config (по умолчанию первая)")
+	method := fs.String("method", "GET", "HTTP-метод, если не задан -config")
+	body := fs.String("body", "", "Тело запроса, если не задан -config")
+	headers := headerFlags{}
+	fs.Var(headers, "H", "Заголовок запроса \"Имя: значение\", можно повторять")
+	requests := fs.Int("n", 200, "Количество запросов")
+	concurrency := fs.Int("c", 10, "Количество параллельных исполнителей")
+	duration := fs.Duration("duration", 0, "Выполнять запросы в течение указанного времени вместо -n")
+	timeout := fs.Duration("timeout", 20*time.Second, "Таймаут каждого запроса")
+	var warmup warmupFlag
+	fs.Var(&warmup, "warmup", "Прогрев перед замером: число запросов или длительность; в отчёт не попадает")
+	fs.Parse(args)
+
+	if *rawURL == "" && *configPath == "" {
+		return fmt.Errorf("использование: bench -url url [-n 1000] [-c 50] [флаги]")
+	}
+	if *concurrency <= 0 || (*duration <= 0 && *requests <= 0) {
+		return fmt.Errorf("-c и -n должны быть положительными")
+	}
+
+	template := Target{Method: *method, Body: *body, Headers: headers, Timeout: *timeout}
+	if *configPath != "" {
+		var err error
+		if template, err = configTarget(*configPath, *targetName); err != nil {
+			return err
+		}
+	}
+	if *rawURL == "" {
+		*rawURL = template.URL
+	}
+	target, err := derivedTarget(template, "bench", *rawURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Прогрев выполняется теми же исполнителями, чтобы открыть соединения для каждого из них
+	warmed := 0
+	if warmup.enabled() {
+		slog.Info("Прогрев", "url", target.URL, "warmup", warmup.String())
+		prev := logOutput.set(ioutil.Discard)
+		wctx, cancel := warmup.context(ctx)
+		results, _ := benchTarget(wctx, target, warmup.checks, warmup.duration > 0, *concurrency)
+		cancel()
+		logOutput.set(prev)
+		warmed = len(results)
+	}
+
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	if *duration > 0 {
+		slog.Info("Нагрузка", "url", target.URL, "concurrency", *concurrency, "duration", *duration)
+	} else {
+		slog.Info("Нагрузка", "url", target.URL, "requests", *requests, "concurrency", *concurrency)
+	}
+
+	// Проверки пишут в журнал каждую ошибку; на время замера журнал отключается,
+	// ошибки попадают в отчёт
+	prev := logOutput.set(ioutil.Discard)
+	results, elapsed := benchTarget(ctx, target, *requests, *duration > 0, *concurrency)
+	logOutput.set(prev)
+
+	printBench(os.Stdout, results, elapsed, *concurrency)
+	if warmed > 0 {
+		fmt.Printf("\nПрогревочных запросов (не учитываются): %d\n", warmed)
+	}
+	return nil
+}
+
+// benchTarget выполняет проверки цели, пока не выполнено n проверок (или, если untilDone,
+// до отмены ctx); отмена ctx всегда прекращает выдачу новых проверок
+func benchTarget(ctx context.Context, target Target, n int, untilDone bool, concurrency int) ([]CheckResult, time.Duration) {
+	jobs := make(chan struct{})
+	var mu sync.Mutex
+	var results []CheckResult
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				r := executeCheck(ctx, target)
+				if r.Aborted {
+					// Запросы, прерванные окончанием замера, не учитываются
+					continue
+				}
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := 0; untilDone || i < n; i++ {
+		select {
+		case jobs <- struct{}{}:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, time.Since(start)
+}
+
+// printBench выводит отчёт в духе wrk/hey: сводку, гистограмму и распределение задержек,
+// коды ответов и ошибки
+func printBench(out io.Writer, results []CheckResult, elapsed time.Duration, concurrency int) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "Не выполнено ни одного запроса.")
+		return
+	}
+
+	latencies := make([]time.Duration, len(results))
+	var total time.Duration
+	successful := 0
+	statuses := make(map[int]int)
+	errors := make(map[string]int)
+	for i, r := range results {
+		latencies[i] = r.Latency
+		total += r.Latency
+		if r.Success {
+			successful++
+		}
+		if r.Status != 0 {
+			statuses[r.Status]++
+		}
+		if r.Error != "" {
+			errors[r.Error]++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	avg := total / time.Duration(len(latencies))
+
+	var variance float64
+	for _, l := range latencies {
+		d := float64(l - avg)
+		variance += d * d
+	}
+	stdev := time.Duration(math.Sqrt(variance / float64(len(latencies))))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Сводка:")
+	fmt.Fprintf(w, "  Время:\t%v\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Исполнителей:\t%d\n", concurrency)
+	fmt.Fprintf(w, "  Запросов:\t%d (успешных %d, %.2f%%)\n", len(results), successful, float64(successful)/float64(len(results))*100)
+	fmt.Fprintf(w, "  Запросов в секунду:\t%.2f\n", float64(len(results))/elapsed.Seconds())
+	if limited := countRateLimited(results); limited > 0 {
+		fmt.Fprintf(w, "  Ограничено частотой (429/503):\t%d (%.2f%%)\n", limited, float64(limited)/float64(len(results))*100)
+	}
+	var received int64
+	for _, r := range results {
+		received += r.Size
+	}
+	fmt.Fprintf(w, "  Получено:\t%s (%s/с)\n", formatBytes(float64(received)), formatBytes(float64(received)/elapsed.Seconds()))
+	fmt.Fprintf(w, "  Быстрейший:\t%v\n", roundLatency(latencies[0]))
+	fmt.Fprintf(w, "  Средний:\t%v\n", roundLatency(avg))
+	fmt.Fprintf(w, "  Медленнейший:\t%v\n", roundLatency(latencies[len(latencies)-1]))
+	fmt.Fprintf(w, "  Стандартное отклонение:\t%v\n", roundLatency(stdev))
+	w.Flush()
+
+	fmt.Fprintln(out, "\nГистограмма задержек:")
+	printLatencyHistogram(out, latencies, 10)
+
+	fmt.Fprintln(out, "\nРаспределение задержек:")
+	for _, p := range []float64{10, 25, 50, 75, 90, 95, 99, 99.9} {
+		fmt.Fprintf(out, "  %5g%%  %v\n", p, roundLatency(percentile(latencies, p)))
+	}
+
+	if len(statuses) > 0 {
+		codes := make([]int, 0, len(statuses))
+		for code := range statuses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		fmt.Fprintln(out, "\nКоды ответов:")
+		for _, code := range codes {
+			fmt.Fprintf(out, "  [%d]  %d\n", code, statuses[code])
+		}
+	}
+
+	if len(errors) > 0 {
+		messages := make([]string, 0, len(errors))
+		for msg := range errors {
+			messages = append(messages, msg)
+		}
+		sort.Slice(messages, func(i, j int) bool {
+			if errors[messages[i]] != errors[messages[j]] {
+				return errors[messages[i]] > errors[messages[j]]
+			}
+			return messages[i] < messages[j]
+		})
+		fmt.Fprintln(out, "\nОшибки:")
+		for _, msg := range messages {
+			fmt.Fprintf(out, "  [%d]  %s\n", errors[msg], truncate(msg, 120))
+		}
+	}
+}
+
+// printLatencyHistogram делит диапазон задержек на равные интервалы и рисует число запросов в каждом
+func printLatencyHistogram(out io.Writer, sorted []time.Duration, buckets int) {
+	const barWidth = 40
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	step := (hi - lo) / time.Duration(buckets)
+	if step <= 0 {
+		fmt.Fprintf(out, "  %v [%d]\t|%s\n", roundLatency(hi), len(sorted), strings.Repeat("■", barWidth))
+		return
+	}
+
+	counts := make([]int, buckets)
+	for _, l := range sorted {
+		i := int((l - lo) / step)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		counts[i]++
+	}
+	peak := 0
+	for _, c := range counts {
+		peak = max(peak, c)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	for i, c := range counts {
+		upper := lo + step*time.Duration(i+1)
+		if i == buckets-1 {
+			upper = hi
+		}
+		fmt.Fprintf(w, "  %v\t[%d]\t|%s\n", roundLatency(upper), c, strings.Repeat("■", c*barWidth/peak))
+	}
+	w.Flush()
+}
+
+// roundLatency округляет задержку до точности, удобной для чтения
+func roundLatency(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// responseBody — прочитанное тело ответа и его метрики
+type responseBody struct {
+	data    []byte // только если тело нужно для проверок
+	head    []byte // начало тела для сохранения неуспешной проверки
+	size    int64
+	sum     string
+	elapsed time.Duration
+}
+
+// readResponseBody дочитывает и закрывает тело ответа, чтобы соединение вернулось в пул.
+// keep сохраняет тело для проверок, checksum считает его SHA-256, head сохраняет
+// первые head байт тела.
+func readResponseBody(resp *http.Response, keep, checksum bool, head int) (responseBody, error) {
+	defer resp.Body.Close()
+
+	var b responseBody
+	var buf bytes.Buffer
+	var sum hash.Hash
+	writers := []io.Writer{ioutil.Discard}
+	if keep {
+		writers = append(writers, &buf)
+	}
+	headBuf := &headWriter{limit: head}
+	if head > 0 && !keep {
+		writers = append(writers, headBuf)
+	}
+	if checksum {
+		sum = sha256.New()
+		writers = append(writers, sum)
+	}
+
+	started := time.Now()
+	n, err := io.Copy(io.MultiWriter(writers...), resp.Body)
+	b.elapsed = time.Since(started)
+	b.size = n
+	if err != nil {
+		return b, fmt.Errorf("чтение тела ответа: %w", err)
+	}
+	if keep {
+		b.data = buf.Bytes()
+		b.head = b.data[:min(len(b.data), head)]
+	} else {
+		b.head = headBuf.data
+	}
+	if sum != nil {
+		b.sum = hex.EncodeToString(sum.Sum(nil))
+	}
+	return b, nil
+}
+
+// headWriter сохраняет первые limit байт записанного
+type headWriter struct {
+	limit int
+	data  []byte
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if n := w.limit - len(w.data); n > 0 {
+		w.data = append(w.data, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// apply записывает размер, время загрузки, скорость и контрольную сумму тела в результат
+func (b responseBody) apply(r *CheckResult) {
+	r.Size = b.size
+	r.Download = b.elapsed
+	if b.elapsed > 0 {
+		r.Throughput = float64(b.size) / b.elapsed.Seconds()
+	}
+	r.SHA256 = b.sum
+}
+
+// checkSHA256 проверяет значение sha256 нормализуемой цели
+func (t *Target) checkSHA256() error {
+	if t.SHA256 == "" {
+		return nil
+	}
+	t.SHA256 = strings.ToLower(t.SHA256)
+	if _, err := hex.DecodeString(t.SHA256); err != nil || len(t.SHA256) != sha256.Size*2 {
+		return fmt.Errorf("sha256 должен содержать %d шестнадцатеричных символов", sha256.Size*2)
+	}
+	return nil
+}
+
+// checksumError сравнивает контрольную сумму тела с ожидаемой
+func (t Target) checksumError(b responseBody) error {
+	if t.SHA256 == "" || b.sum == t.SHA256 {
+		return nil
+	}
+	return fmt.Errorf("SHA-256 ответа %s, ожидался %s", b.sum, t.SHA256)
+}
+
+// formatBytes выводит объём данных в двоичных единицах: 512 Б, 1.5 КиБ, 3.2 МиБ
+func formatBytes(n float64) string {
+	units := []string{"Б", "КиБ", "МиБ", "ГиБ"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Вердикты канареечной проверки
+const (
+	VerdictPromote  = "promote"
+	VerdictRollback = "rollback"
+)
+
+// canaryVerdict — итог сравнения канареечной и стабильной версий
+type canaryVerdict struct {
+	Verdict string       `json:"verdict"`
+	Stable  latencyStats `json:"stable"`
+	Canary  latencyStats `json:"canary"`
+	// Изменение p95 канарейки относительно стабильной версии, %
+	P95Change float64 `json:"p95_change"`
+	// Рост доли ошибок канарейки, п.п.
+	ErrorRateDelta float64  `json:"error_rate_delta"`
+	LatencyP       float64  `json:"latency_p"`
+	SuccessP       float64  `json:"success_p"`
+	Reasons        []string `json:"reasons,omitempty"`
+}
+
+// Уровень значимости по умолчанию для отличий канарейки от стабильной версии
+const defaultCanaryAlpha = 0.05
+
+// headerFlags — повторяемый флаг -H "Имя: значение"
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	parts := make([]string, 0, len(h))
+	for k, v := range h {
+		parts = append(parts, k+": "+v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h headerFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("заголовок должен иметь вид \"Имя: значение\"")
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
+// runCanary реализует подкоманду canary: одинаковые запросы к стабильной и канареечной
+// версиям выполняются параллельно в течение -duration, после чего выносится вердикт.
+// Возвращает true, если канарейку нужно откатить.
+func runCanary(args []string) (bool, error) {
+	fs := flag.NewFlagSet("canary", flag.ExitOnError)
+	stableURL := fs.String("stable", "", "URL стабильной версии")
+	canaryURL := fs.String("canary", "", "URL канареечной версии")
+	configPath := fs.String("config", "", "Конфигурация, из которой берётся описание запроса")
+	targetName := fs.String("target", "", "Имя цели из -config (по умолчанию первая)")
+	method := fs.String("method", "GET", "HTTP-метод, если не задан -config")
+	body := fs.String("body", "", "Тело запроса, если не задан -config")
+	headers := headerFlags{}
+	fs.Var(headers, "H", "Заголовок запроса \"Имя: значение\", можно повторять")
+	duration := fs.Duration("duration", 5*time.Minute, "Продолжительность сравнения")
+	interval := fs.Duration("t", time.Second, "Интервал между парами запросов")
+	maxP95 := fs.Float64("max-p95-regression", defaultMaxP95Regression, "Допустимый рост p95 канарейки относительно стабильной версии, %")
+	maxDrop := fs.Float64("max-success-drop", defaultMaxSuccessDrop, "Допустимое падение процента успешных у канарейки, п.п.")
+	alpha := fs.Float64("alpha", defaultCanaryAlpha, "Уровень значимости: отклонения с большим p-значением считаются шумом")
+	jsonOut := fs.Bool("json", false, "Вывести вердикт в формате JSON")
+	fs.Parse(args)
+
+	if *stableURL == "" || *canaryURL == "" {
+		return false, fmt.Errorf("использование: canary -stable url -canary url [флаги]")
+	}
+	if *interval <= 0 || *duration < *interval {
+		return false, fmt.Errorf("-duration должен быть не меньше интервала -t")
+	}
+
+	template := Target{Method: *method, Body: *body, Headers: headers}
+	if *configPath != "" {
+		var err error
+		if template, err = configTarget(*configPath, *targetName); err != nil {
+			return false, err
+		}
+	}
+
+	stable, err := derivedTarget(template, "stable", *stableURL)
+	if err != nil {
+		return false, err
+	}
+	canary, err := derivedTarget(template, "canary", *canaryURL)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration+time.Minute)
+	defer cancel()
+
+	slog.Info("Канареечная проверка", "canary", *canaryURL, "stable", *stableURL, "duration", *duration)
+	result := runTests(ctx, newTargetRegistry([]Target{stable, canary}, TargetDefaults{}), runOptions{
+		Interval:  *interval,
+		NumChecks: int(*duration / *interval),
+	})
+
+	_, groups := groupByTarget(result.Results)
+	verdict := judgeCanary(groups[stable.Name], groups[canary.Name], regressionThresholds{*maxP95, *maxDrop}, *alpha)
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(verdict); err != nil {
+			return false, err
+		}
+	} else {
+		printCanaryVerdict(verdict)
+	}
+	return verdict.Verdict == VerdictRollback, nil
+}
+
+// configTarget возвращает цель с именем name из конфигурации (пустое имя — первую цель)
+func configTarget(path, name string) (Target, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return Target{}, err
+	}
+	if name == "" {
+		return cfg.Targets[0], nil
+	}
+	for _, t := range cfg.Targets {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Target{}, fmt.Errorf("цель %s не найдена в %s", name, path)
+}
+
+// derivedTarget строит цель с тем же запросом, что и шаблон, но другим адресом
+func derivedTarget(template Target, name, rawURL string) (Target, error) {
+	t := template
+	t.Name = name
+	t.URL = rawURL
+	// Повторы скрывали бы разницу в доле ошибок
+	t.Retries = nil
+	if err := t.normalize(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// judgeCanary выносит вердикт: отклонение канарейки сверх порога приводит к откату,
+// только если оно статистически значимо на уровне alpha
+func judgeCanary(stable, canary []CheckResult, th regressionThresholds, alpha float64) canaryVerdict {
+	c := compareTarget("canary", stable, canary, th)
+	v := canaryVerdict{
+		Verdict:        VerdictPromote,
+		Stable:         c.Baseline,
+		Canary:         c.Current,
+		P95Change:      c.P95Change,
+		ErrorRateDelta: c.Baseline.SuccessRate() - c.Current.SuccessRate(),
+		LatencyP:       c.LatencyP,
+		SuccessP:       c.SuccessP,
+	}
+
+	if c.Current.Checks == 0 {
+		v.Reasons = append(v.Reasons, "нет результатов канарейки")
+	}
+	if v.P95Change > th.MaxP95Regression && v.LatencyP < alpha {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("p95 вырос на %.1f%%", v.P95Change))
+	}
+	if v.ErrorRateDelta > th.MaxSuccessDrop && v.SuccessP < alpha {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("успешных меньше на %.2f п.п.", v.ErrorRateDelta))
+	}
+	if len(v.Reasons) > 0 {
+		v.Verdict = VerdictRollback
+	}
+	return v
+}
+
+func printCanaryVerdict(v canaryVerdict) {
+	fmt.Printf("Стабильная версия: проверок %d, успешных %.2f%%, p95 %v\n",
+		v.Stable.Checks, v.Stable.SuccessRate(), v.Stable.P95.Round(time.Millisecond))
+	fmt.Printf("Канарейка:         проверок %d, успешных %.2f%%, p95 %v\n",
+		v.Canary.Checks, v.Canary.SuccessRate(), v.Canary.P95.Round(time.Millisecond))
+	fmt.Printf("Изменение p95: %+.1f%% (p = %.3f), рост доли ошибок: %+.2f п.п. (p = %.3f)\n",
+		v.P95Change, v.LatencyP, v.ErrorRateDelta, v.SuccessP)
+	fmt.Printf("Вердикт: %s\n", v.Verdict)
+	for _, r := range v.Reasons {
+		fmt.Printf("  - %s\n", r)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// chaosProxy — обратный прокси к цели, который вносит задержки, обрывы соединений
+// и ошибочные ответы, чтобы проверить повторы и отступ клиентов
+type chaosProxy struct {
+	proxy      *httputil.ReverseProxy
+	latency    time.Duration // добавочная задержка каждого запроса
+	jitter     time.Duration // случайная добавка к задержке от 0 до jitter
+	dropRate   float64       // доля запросов, соединение которых закрывается без ответа
+	errorRate  float64       // доля запросов, на которые возвращается один из errorCodes
+	errorCodes []int
+	retryAfter time.Duration // Retry-After для ответов 429 и 503
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	requests, dropped, failed, forwarded atomic.Int64
+}
+
+func runChaos(args []string) error {
+	fs := flag.NewFlagSet("chaos", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8081", "Адрес, на котором принимаются запросы")
+	upstream := fs.String("upstream", "", "Адрес цели, например https://api.example.com")
+	latency := fs.Duration("latency", 0, "Добавочная задержка каждого запроса")
+	jitter := fs.Duration("jitter", 0, "Случайная добавка к задержке: от 0 до указанной")
+	drop := fs.Float64("drop", 0, "Доля запросов (0–1), соединение которых обрывается без ответа")
+	errorRate := fs.Float64("error-rate", 0, "Доля запросов (0–1), на которые возвращается ошибка")
+	errorCodes := fs.String("error-codes", "503", "Коды ошибочных ответов через запятую, выбираются случайно")
+	retryAfter := fs.Duration("retry-after", 0, "Заголовок Retry-After для ошибочных ответов 429 и 503")
+	seed := fs.Int64("seed", 0, "Начальное значение генератора случайных чисел для воспроизводимых запусков, 0 — по времени")
+	fs.Parse(args)
+
+	if *upstream == "" {
+		return fmt.Errorf("использование: chaos -upstream url [-listen адрес] [флаги]")
+	}
+	u, err := url.Parse(*upstream)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("некорректный адрес цели %q", *upstream)
+	}
+	if *latency < 0 || *jitter < 0 || *retryAfter < 0 {
+		return fmt.Errorf("latency, jitter и retry-after не могут быть отрицательными")
+	}
+	if *drop < 0 || *errorRate < 0 || *drop+*errorRate > 1 {
+		return fmt.Errorf("drop и error-rate должны быть не меньше 0, а их сумма — не больше 1")
+	}
+	codes, err := parseStatusCodes(*errorCodes)
+	if err != nil {
+		return err
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	c := &chaosProxy{
+		proxy:      httputil.NewSingleHostReverseProxy(u),
+		latency:    *latency,
+		jitter:     *jitter,
+		dropRate:   *drop,
+		errorRate:  *errorRate,
+		errorCodes: codes,
+		retryAfter: *retryAfter,
+		rand:       rand.New(rand.NewSource(*seed)),
+	}
+	// Запросы к цели отправляются с её именем хоста, а не с адресом прокси
+	director := c.proxy.Director
+	c.proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = u.Host
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *listen, Handler: c}
+	errs := make(chan error, 1)
+	go func() { errs <- server.ListenAndServe() }()
+	slog.Info("Прокси с неисправностями запущен", "address", *listen, "upstream", u.String(),
+		"latency", *latency, "jitter", *jitter, "drop", *drop, "error_rate", *errorRate, "seed", *seed)
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("веб-сервер: %w", err)
+	case <-ctx.Done():
+	}
+	server.Close()
+	slog.Info("Прокси остановлен", "requests", c.requests.Load(), "forwarded", c.forwarded.Load(),
+		"dropped", c.dropped.Load(), "errors", c.failed.Load())
+	return nil
+}
+
+// parseStatusCodes разбирает список кодов ответа через запятую
+func parseStatusCodes(list string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("некорректный код ответа %q", part)
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("не задано ни одного кода ответа")
+	}
+	return codes, nil
+}
+
+// fault выбирает задержку и неисправность запроса: roll < dropRate — обрыв,
+// затем ошибочный ответ с кодом code
+func (c *chaosProxy) fault() (delay time.Duration, roll float64, code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delay = c.latency
+	if c.jitter > 0 {
+		delay += time.Duration(c.rand.Int63n(int64(c.jitter) + 1))
+	}
+	roll = c.rand.Float64()
+	code = c.errorCodes[c.rand.Intn(len(c.errorCodes))]
+	return delay, roll, code
+}
+
+func (c *chaosProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.requests.Add(1)
+	delay, roll, code := c.fault()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case roll < c.dropRate:
+		c.dropped.Add(1)
+		slog.Debug("Соединение оборвано", "method", r.Method, "url", r.URL.String())
+		// Сервер закрывает соединение, не отправляя ответ
+		panic(http.ErrAbortHandler)
+	case roll < c.dropRate+c.errorRate:
+		c.failed.Add(1)
+		slog.Debug("Возвращена ошибка", "method", r.Method, "url", r.URL.String(), "status", code)
+		if c.retryAfter > 0 && (code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(c.retryAfter.Round(time.Second).Seconds())))
+		}
+		http.Error(w, http.StatusText(code), code)
+	default:
+		c.forwarded.Add(1)
+		c.proxy.ServeHTTP(w, r)
+	}
+}
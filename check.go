@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CheckResult struct {
+	Target    string        `json:"target"`
+	Timestamp time.Time     `json:"timestamp"`
+	Success   bool          `json:"success"`
+	Status    int           `json:"status,omitempty"` // HTTP-статус ответа
+	Latency   time.Duration `json:"latency"`          // время до получения заголовков ответа, нс
+	Error     string        `json:"error,omitempty"`
+	// Проверка отброшена планировщиком или прервана остановкой и не учитывается в статистике
+	Shed bool `json:"shed,omitempty"`
+	// Запрос проверки прерван при остановке (SIGINT, -grace); такие проверки также Shed
+	Aborted bool `json:"aborted,omitempty"`
+	// Прогревочная проверка (-warmup), не учитывается в статистике
+	Warmup bool `json:"warmup,omitempty"`
+	// ok, warning или critical — если у цели заданы правила серьёзности
+	Severity string        `json:"severity,omitempty"`
+	Phases   *PhaseTimings `json:"phases,omitempty"`
+	// Число попыток, если проверка повторялась
+	Attempts int `json:"attempts,omitempty"`
+	// Нарушения схемы ответа с путями полей, например "$.items[0].id: обязательное поле отсутствует"
+	Violations []string `json:"violations,omitempty"`
+	// Расхождения с эталонным ответом, например "$.total: значение 3, ожидалось 2"
+	Diff []string `json:"diff,omitempty"`
+	// Части составной проверки (dns, tls, http)
+	SubChecks []SubCheckResult `json:"sub_checks,omitempty"`
+	// Таймаут проверки, если он подбирается по задержкам цели
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Имя исходной цели, если проверялась строка набора данных
+	Dataset string `json:"dataset,omitempty"`
+	// Версия HTTP, по которой получен ответ, например HTTP/2.0
+	Protocol string `json:"protocol,omitempty"`
+	// IP-адрес сервера и параметры TLS соединения, если у цели не отключено enrich
+	RemoteIP string      `json:"remote_ip,omitempty"`
+	TLS      *TLSDetails `json:"tls,omitempty"`
+	// Цепочка перенаправлений до итогового ответа и время, затраченное на них
+	Redirects       []RedirectHop `json:"redirects,omitempty"`
+	RedirectLatency time.Duration `json:"redirect_latency,omitempty"`
+	// Размер тела ответа в байтах, время его загрузки и скорость, байт/с
+	Size       int64         `json:"size,omitempty"`
+	Download   time.Duration `json:"download,omitempty"`
+	Throughput float64       `json:"throughput,omitempty"`
+	// SHA-256 тела, если у цели задана ожидаемая контрольная сумма
+	SHA256 string `json:"sha256,omitempty"`
+	// Идентификатор трассы проверки, если включена трассировка
+	TraceID string `json:"trace_id,omitempty"`
+	// Показатели, которые записал скрипт проверки или вернула проверка типа из плагина
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Регион, из которого выполнена проверка (-region или агент распределённого режима)
+	Region string `json:"region,omitempty"`
+	// Проверка выполнена во время обслуживания цели: сбой не учитывается в доступности
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Файл с запросом и ответом неуспешной проверки, если задан -artifacts
+	Artifact string `json:"artifact,omitempty"`
+	// Ответ 429 или 503 с Retry-After: время из Retry-After и заголовки лимитов X-RateLimit-*
+	RateLimited bool              `json:"rate_limited,omitempty"`
+	RetryAfter  time.Duration     `json:"retry_after,omitempty"`
+	RateLimit   map[string]string `json:"rate_limit,omitempty"`
+	// дополнительные поля, если нужно
+}
+
+type TestResult struct {
+	Results []CheckResult `json:"results"`
+	// Время, версия утилиты, хост, аргументы и метки запуска
+	Run *RunMetadata `json:"run,omitempty"`
+	// Гистограммы задержек целей
+	Histograms map[string]HistogramSnapshot `json:"histograms,omitempty"`
+	// Файлы с сырыми результатами длительного теста (-rotate)
+	Files []string `json:"files,omitempty"`
+	// Число ранних результатов, не сохранённых в режиме мониторинга (см. daemonMaxResults)
+	Dropped int `json:"dropped,omitempty"`
+	// дополнительные поля, если нужно
+}
+
+func performCheck(ctx context.Context, wg *sync.WaitGroup, target Target, results chan<- CheckResult) {
+	defer wg.Done()
+
+	// Отмена ctx прерывает запрос; прерванная проверка записывается с пометкой aborted
+	results <- executeCheck(ctx, target)
+}
+
+// PhaseTimings — длительности фаз HTTP-запроса
+type PhaseTimings struct {
+	DNS     time.Duration `json:"dns"`
+	Connect time.Duration `json:"connect"`
+	TLS     time.Duration `json:"tls"`
+	TTFB    time.Duration `json:"ttfb"` // от отправки запроса до первого байта ответа
+	// reused или new; пусто, если соединение не было получено
+	Connection string `json:"connection,omitempty"`
+
+	// Моменты начала фаз для спанов трассировки
+	dnsAt, connectAt, tlsAt, requestAt time.Time
+}
+
+// Уровни серьёзности результата
+const (
+	SeverityOK       = "ok"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// executeCheck выполняет проверку цели с учётом повторов и возвращает итоговый результат
+func executeCheck(ctx context.Context, target Target) CheckResult {
+	result := executeAttempt(ctx, target)
+	// Ограниченные по частоте проверки не повторяются, если цель ждёт Retry-After
+	for attempt := 1; !result.Success && !target.honorsRetryAfter(result) && ctx.Err() == nil && attempt <= target.retries(); attempt++ {
+		slog.Info("Повтор проверки после ошибки", "target", target.Name, "attempt", attempt, "retries", target.retries(), "error", result.Error)
+		started := result.Timestamp
+		result = executeAttempt(ctx, target)
+		result.Timestamp = started
+		result.Attempts = attempt + 1
+	}
+	if !result.Success && ctx.Err() != nil {
+		result.Aborted, result.Shed = true, true
+		result.Error = "проверка прервана при остановке: " + result.Error
+		slog.Info("Проверка прервана при остановке", "target", target.Name)
+	}
+	if target.DatasetRow != nil {
+		result.Dataset = target.DatasetRow.Dataset
+	}
+	result.Maintenance = target.inMaintenance(result.Timestamp)
+	target.observeLatency(result)
+	target.observeRateLimit(result)
+	return result
+}
+
+// executeAttempt выполняет одну попытку проверки
+func executeAttempt(ctx context.Context, target Target) CheckResult {
+	if target.Type == CheckTypeComposite {
+		return executeComposite(ctx, target)
+	}
+	if target.Type == CheckTypeScenario {
+		return executeScenario(ctx, target)
+	}
+	if target.checker != nil {
+		return executePlugin(ctx, target)
+	}
+	return executeRequest(ctx, target)
+}
+
+// executeRequest выполняет HTTP-запрос цели и оценивает ответ
+func executeRequest(ctx context.Context, target Target) CheckResult {
+	result := CheckResult{Target: target.Name, Timestamp: time.Now()}
+
+	req, err := newTargetRequest(ctx, target)
+	if err != nil {
+		slog.Error("Ошибка при подготовке запроса", "target", target.Name, "error", err)
+		result.Error = err.Error()
+		return result
+	}
+
+	if tracer != nil {
+		tc := newTraceContext()
+		result.TraceID = tc.traceID
+		if tracer.propagate && req.Header.Get("traceparent") == "" {
+			req.Header.Set("traceparent", tc.traceparent())
+		}
+		defer func() { tracer.record(tc, target, req, result) }()
+	}
+
+	phases := &PhaseTimings{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), phaseTrace(phases)))
+	var conn *connRecorder
+	if target.enriched() {
+		conn = &connRecorder{}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), conn.trace()))
+	}
+
+	redirects := target.newRedirectRecorder(result.Timestamp)
+	client := &http.Client{
+		Timeout:       target.effectiveTimeout(),
+		Transport:     target.roundTripper(),
+		CheckRedirect: redirects.checkRedirect,
+		Jar:           target.jar,
+	}
+	if client.Jar == nil && target.session != nil {
+		client.Jar = target.session.jar()
+	}
+	if target.adaptive != nil {
+		result.Timeout = client.Timeout
+	}
+	logRequest(target, req)
+	resp, err := client.Do(req)
+	result.Latency = time.Since(result.Timestamp)
+	result.Phases = phases
+	redirects.apply(&result)
+	if conn != nil {
+		conn.apply(&result, resp)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		if ctx.Err() != nil {
+			// Прерванная остановкой проверка — не сбой цели
+			return result
+		}
+		slog.Warn("Ошибка при выполнении запроса", "target", target.Name, "error", err)
+		target.applySeverity(&result, signalsFor(result, false))
+		artifacts.capture(target, req, nil, nil, &result)
+		return result
+	}
+
+	result.Status = resp.StatusCode
+	result.Protocol = resp.Proto
+	applyRateLimit(resp, &result)
+	logResponse(target, resp, result.Latency)
+
+	// Проверяем успешность запроса и выполняем дополнительные проверки, если нужно
+	var statusErr, assertErr error
+	if handled, err := target.redirectVerdict(resp, len(result.Redirects)); handled {
+		statusErr = err
+	} else if !target.statusExpected(resp.StatusCode) && result.RateLimited {
+		statusErr = rateLimitError(result, resp.Status)
+	} else if !target.statusExpected(resp.StatusCode) {
+		statusErr = fmt.Errorf("неожиданный статус %s", resp.Status)
+	} else if err := target.negotiatedVersionError(resp); err != nil {
+		statusErr = err
+	}
+
+	needBody := target.Type == CheckTypeGraphQL || target.responseSchema != nil || target.reference != nil || target.script != nil
+	body, err := readResponseBody(resp, needBody, target.SHA256 != "", artifacts.bodyLimit())
+	body.apply(&result)
+	if err != nil {
+		assertErr = err
+	} else if statusErr == nil || target.successCond != nil {
+		var errs []error
+		if target.Type == CheckTypeGraphQL {
+			errs = append(errs, target.GraphQL.evaluate(body.data))
+		} else {
+			var schemaErr, diffErr error
+			if target.responseSchema != nil {
+				result.Violations, schemaErr = target.responseSchema.check(body.data)
+			}
+			if target.reference != nil {
+				result.Diff, diffErr = target.reference.check(body.data)
+			}
+			errs = append(errs, schemaErr, diffErr)
+		}
+		errs = append(errs, target.checksumError(body), target.cookieError(append(redirects.cookies, resp.Cookies()...)))
+		if target.script != nil {
+			var scriptErr error
+			result.Metrics, scriptErr = target.script.run(resp, body.data, result)
+			errs = append(errs, scriptErr)
+		}
+		// В результат попадает первая из ошибок проверок тела
+		for _, e := range errs {
+			if e != nil {
+				assertErr = e
+				break
+			}
+		}
+	}
+
+	signals := signalsFor(result, assertErr == nil)
+
+	if target.successCond != nil {
+		// Условие успеха цели заменяет правило «статус 200 и все утверждения выполнены»
+		ok, err := target.successCond.evalBool(signals)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case !ok:
+			result.Error = "не выполнено условие успеха: " + target.successCond.source
+			if assertErr != nil {
+				result.Error += " (" + assertErr.Error() + ")"
+			}
+		default:
+			result.Success = true
+		}
+	} else {
+		result.Success = statusErr == nil && assertErr == nil
+		if statusErr != nil {
+			result.Error = statusErr.Error()
+		} else if assertErr != nil {
+			result.Error = assertErr.Error()
+		}
+	}
+
+	target.applySeverity(&result, signals)
+	artifacts.capture(target, req, resp, body.head, &result)
+	return result
+}
+
+func phaseTrace(p *PhaseTimings) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { p.dnsAt = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { p.DNS = time.Since(p.dnsAt) },
+		ConnectStart:      func(string, string) { p.connectAt = time.Now() },
+		ConnectDone:       func(string, string, error) { p.Connect = time.Since(p.connectAt) },
+		TLSHandshakeStart: func() { p.tlsAt = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { p.TLS = time.Since(p.tlsAt) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { p.requestAt = time.Now() },
+		GotFirstResponseByte: func() {
+			if !p.requestAt.IsZero() {
+				p.TTFB = time.Since(p.requestAt)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.Connection = ConnectionNew
+			if info.Reused {
+				p.Connection = ConnectionReused
+			}
+		},
+	}
+}
+
+// signalsFor собирает значения сигналов для выражений условий
+func signalsFor(result CheckResult, assertionsOK bool) signalEnv {
+	env := signalEnv{
+		"status":     {num: float64(result.Status)},
+		"latency":    {num: float64(result.Latency)},
+		"assertions": {b: assertionsOK, isBool: true},
+		"error":      {b: result.Status == 0, isBool: true},
+		"redirects":  {num: float64(len(result.Redirects))},
+		"size":       {num: float64(result.Size)},
+	}
+	if p := result.Phases; p != nil {
+		env["dns"] = exprValue{num: float64(p.DNS)}
+		env["connect"] = exprValue{num: float64(p.Connect)}
+		env["tls"] = exprValue{num: float64(p.TLS)}
+		env["ttfb"] = exprValue{num: float64(p.TTFB)}
+	}
+	return env
+}
+
+// applySeverity выставляет уровень серьёзности по первому сработавшему правилу цели.
+// Без правил уровень не выставляется.
+func (t Target) applySeverity(result *CheckResult, signals signalEnv) {
+	if len(t.severityConds) == 0 {
+		return
+	}
+
+	for i, cond := range t.severityConds {
+		matched, err := cond.evalBool(signals)
+		if err != nil {
+			slog.Error("Ошибка в правиле серьёзности", "target", t.Name, "error", err)
+			continue
+		}
+		if matched {
+			result.Severity = t.Severity[i].Level
+			return
+		}
+	}
+
+	if result.Success {
+		result.Severity = SeverityOK
+	} else {
+		result.Severity = SeverityCritical
+	}
+}
+
+// newTargetRequest строит HTTP-запрос для цели в зависимости от типа проверки
+func newTargetRequest(ctx context.Context, target Target) (*http.Request, error) {
+	rendered, err := target.render()
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if rendered.body != "" {
+		body = strings.NewReader(rendered.body)
+	}
+
+	if target.Type == CheckTypeGraphQL {
+		payload, err := target.GraphQL.requestBody()
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, target.Method, rendered.url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Type == CheckTypeGraphQL {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+	}
+	for k, v := range rendered.headers {
+		req.Header.Set(k, v)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,94 @@
+// Пакет checker описывает проверки собственных типов: протоколы, которых нет в утилите,
+// добавляются без её изменения. Реализация регистрируется под именем типа — из init
+// плагина Go (.so), подключённого разделом plugins конфигурации, или импортом пакета
+// при сборке своей версии утилиты:
+//
+//	func init() {
+//		checker.Register("amqp", amqpChecker{})
+//	}
+//
+// Цель выбирает проверку по имени в type, параметры передаются из options:
+//
+//	targets:
+//	  - name: orders-queue
+//	    type: amqp
+//	    url: amqp://rabbit:5672/
+//	    options:
+//	      queue: orders
+//
+// Check вызывается одновременно для разных целей и должен соблюдать ctx: его
+// отмена означает истечение таймаута цели. Ошибка Check означает, что проверку
+// не удалось выполнить; неуспешный результат — что она выполнена и не прошла.
+package checker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Request — проверка одной цели
+type Request struct {
+	Target  string                 `json:"target"`
+	URL     string                 `json:"url"`
+	Timeout time.Duration          `json:"timeout,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// Result — итог проверки
+type Result struct {
+	Success bool   `json:"success"`
+	Status  int    `json:"status,omitempty"` // код ответа протокола, если он есть
+	Error   string `json:"error,omitempty"`
+	// Задержка, измеренная самой проверкой; если не задана, учитывается всё время Check
+	Latency time.Duration `json:"latency,omitempty"`
+	// Дополнительные числовые показатели проверки
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+// Checker выполняет проверки одного типа
+type Checker interface {
+	Check(ctx context.Context, req Request) (Result, error)
+}
+
+var (
+	mu       sync.RWMutex
+	checkers = make(map[string]Checker)
+)
+
+// Register регистрирует проверку под именем типа. Повторная регистрация имени — ошибка программы.
+func Register(name string, c Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c == nil {
+		panic("checker: Register с nil для " + name)
+	}
+	if _, dup := checkers[name]; dup {
+		panic("checker: повторная регистрация " + name)
+	}
+	checkers[name] = c
+}
+
+// Lookup возвращает зарегистрированную проверку
+func Lookup(name string) (Checker, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok := checkers[name]
+	return c, ok
+}
+
+// Names возвращает имена зарегистрированных проверок по алфавиту
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
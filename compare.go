@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// Пороги регрессии по умолчанию
+const (
+	defaultMaxP95Regression = 10.0 // проценты
+	defaultMaxSuccessDrop   = 1.0  // процентные пункты
+)
+
+type regressionThresholds struct {
+	MaxP95Regression float64
+	MaxSuccessDrop   float64
+}
+
+// targetComparison — сравнение результатов одной цели между двумя запусками
+type targetComparison struct {
+	Target   string
+	Baseline latencyStats
+	Current  latencyStats
+	// Изменение p95 в процентах относительно базового запуска
+	P95Change float64
+	// p-значения: критерий Манна — Уитни для задержек и z-критерий для долей успешных
+	LatencyP  float64
+	SuccessP  float64
+	Regressed bool
+	Reasons   []string
+}
+
+// runCompare реализует подкоманду compare baseline.json current.json, а с флагами -url —
+// одновременную проверку нескольких равнозначных адресов и их сравнение
+func runCompare(args []string) (bool, error) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	maxP95 := fs.Float64("max-p95-regression", defaultMaxP95Regression, "Допустимый рост p95 задержки, %")
+	maxDrop := fs.Float64("max-success-drop", defaultMaxSuccessDrop, "Допустимое падение процента успешных, п.п.")
+	dbPath := fs.String("db", "", "База результатов, из которой показываются аннотации за период запусков")
+	var endpoints endpointFlags
+	fs.Var(&endpoints, "url", "Адрес для сравнения [имя=]url, можно повторять; первый — эталон")
+	configPath := fs.String("config", "", "С -url: конфигурация, из которой берётся описание запроса")
+	targetName := fs.String("target", "", "С -url: имя цели из -config (по умолчанию первая)")
+	method := fs.String("method", "GET", "С -url: HTTP-метод, если не задан -config")
+	body := fs.String("body", "", "С -url: тело запроса, если не задан -config")
+	headers := headerFlags{}
+	fs.Var(headers, "H", "С -url: заголовок запроса \"Имя: значение\", можно повторять")
+	duration := fs.Duration("duration", time.Minute, "С -url: продолжительность сравнения")
+	interval := fs.Duration("t", time.Second, "С -url: интервал между запросами к каждому адресу")
+	alpha := fs.Float64("alpha", defaultCanaryAlpha, "С -url: уровень значимости отличий от эталона")
+	jsonOut := fs.Bool("json", false, "С -url: вывести сравнение в формате JSON")
+	fs.Parse(args)
+
+	if len(endpoints) > 0 {
+		if fs.NArg() != 0 {
+			return false, fmt.Errorf("с -url файлы результатов не указываются")
+		}
+		template := Target{Method: *method, Body: *body, Headers: headers}
+		if *configPath != "" {
+			var err error
+			if template, err = configTarget(*configPath, *targetName); err != nil {
+				return false, err
+			}
+		}
+		return compareEndpoints(os.Stdout, template, endpoints, *duration, *interval, regressionThresholds{*maxP95, *maxDrop}, *alpha, *jsonOut)
+	}
+
+	if fs.NArg() != 2 {
+		return false, fmt.Errorf("использование: compare [флаги] baseline.json current.json или compare -url эталон -url адрес... [флаги]")
+	}
+
+	baseline, err := loadTestResult(fs.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	current, err := loadTestResult(fs.Arg(1))
+	if err != nil {
+		return false, err
+	}
+
+	comparisons := compareRuns(baseline, current, regressionThresholds{*maxP95, *maxDrop})
+	regressed := printComparison(os.Stdout, comparisons)
+
+	if *dbPath != "" {
+		store, err := openStore(*dbPath)
+		if err != nil {
+			return regressed, err
+		}
+		defer store.Close()
+		if err := printRunAnnotations(os.Stdout, store, baseline, current); err != nil {
+			return regressed, err
+		}
+	}
+	return regressed, nil
+}
+
+func loadTestResult(path string) (TestResult, error) {
+	var tr TestResult
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return tr, err
+	}
+	if err := json.Unmarshal(data, &tr); err != nil {
+		return tr, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	return tr, nil
+}
+
+// compareRuns сравнивает цели, присутствующие в обоих запусках
+func compareRuns(baseline, current TestResult, th regressionThresholds) []targetComparison {
+	_, baseGroups := groupByTarget(baseline.Results)
+	names, curGroups := groupByTarget(current.Results)
+
+	var comparisons []targetComparison
+	for _, name := range names {
+		base, ok := baseGroups[name]
+		if !ok {
+			continue
+		}
+		comparisons = append(comparisons, compareTarget(name, base, curGroups[name], th))
+	}
+	return comparisons
+}
+
+func compareTarget(name string, base, cur []CheckResult, th regressionThresholds) targetComparison {
+	c := targetComparison{
+		Target:   name,
+		Baseline: computeStats(base),
+		Current:  computeStats(cur),
+	}
+
+	if c.Baseline.P95 > 0 {
+		c.P95Change = float64(c.Current.P95-c.Baseline.P95) / float64(c.Baseline.P95) * 100
+	}
+	c.LatencyP = mannWhitneyP(successLatencies(base), successLatencies(cur))
+	c.SuccessP = twoProportionP(c.Baseline.Successful, c.Baseline.Checks, c.Current.Successful, c.Current.Checks)
+
+	if c.P95Change > th.MaxP95Regression {
+		c.Regressed = true
+		c.Reasons = append(c.Reasons, fmt.Sprintf("p95 вырос на %.1f%%", c.P95Change))
+	}
+	if drop := c.Baseline.SuccessRate() - c.Current.SuccessRate(); drop > th.MaxSuccessDrop {
+		c.Regressed = true
+		c.Reasons = append(c.Reasons, fmt.Sprintf("успешных меньше на %.2f п.п.", drop))
+	}
+
+	return c
+}
+
+// successLatencies возвращает задержки успешных проверок
+func successLatencies(results []CheckResult) []time.Duration {
+	var out []time.Duration
+	for _, r := range results {
+		if r.Success && !r.Shed && !r.Warmup {
+			out = append(out, r.Latency)
+		}
+	}
+	return out
+}
+
+// printComparison выводит таблицу сравнения и возвращает true при наличии регрессий
+func printComparison(out io.Writer, comparisons []targetComparison) bool {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tp95 было\tp95 стало\tизменение\tуспешных было\tуспешных стало\tp (задержки)\tp (успешные)\tитог")
+
+	regressed := false
+	for _, c := range comparisons {
+		verdict := "ок"
+		if c.Regressed {
+			regressed = true
+			verdict = "регрессия"
+			for _, r := range c.Reasons {
+				verdict += "; " + r
+			}
+		}
+		fmt.Fprintf(w, "%s\t%v\t%v\t%+.1f%%\t%.2f%%\t%.2f%%\t%.3f\t%.3f\t%s\n",
+			c.Target,
+			c.Baseline.P95.Round(time.Millisecond), c.Current.P95.Round(time.Millisecond), c.P95Change,
+			c.Baseline.SuccessRate(), c.Current.SuccessRate(),
+			c.LatencyP, c.SuccessP, verdict)
+	}
+	w.Flush()
+
+	if len(comparisons) == 0 {
+		fmt.Fprintln(out, "Нет общих целей для сравнения.")
+	}
+	return regressed
+}
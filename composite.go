@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CompositeCheck — настройки составной проверки домена: разрешение DNS,
+// проверка TLS-сертификата и HTTP-запрос выполняются вместе и дают общий итог
+type CompositeCheck struct {
+	DNS struct {
+		// Адреса, среди которых должен быть хотя бы один из разрешённых; пусто — любой
+		Expect []string `yaml:"expect,omitempty"`
+	} `yaml:"dns,omitempty"`
+	TLS struct {
+		// Минимальный оставшийся срок действия сертификата, дней
+		MinValidDays *int `yaml:"min_valid_days,omitempty"`
+	} `yaml:"tls,omitempty"`
+}
+
+const (
+	defaultMinValidDays = 7
+	// Таймаут подпроверок DNS и TLS, если у цели не задан timeout
+	defaultSubCheckTimeout = 10 * time.Second
+)
+
+// SubCheckResult — результат одной части составной проверки
+type SubCheckResult struct {
+	Name    string        `json:"name"` // dns, tls или http
+	Success bool          `json:"success"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+	// Результат в кратком виде: адреса, срок сертификата
+	Detail string `json:"detail,omitempty"`
+}
+
+// executeComposite выполняет подпроверки параллельно и объединяет их в один результат;
+// задержка и статус результата берутся из HTTP-запроса
+func executeComposite(ctx context.Context, target Target) CheckResult {
+	raw, err := target.renderURL()
+	if err != nil {
+		return CheckResult{Target: target.Name, Timestamp: time.Now(), Error: err.Error()}
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return CheckResult{Target: target.Name, Timestamp: time.Now(), Error: err.Error()}
+	}
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultSubCheckTimeout
+	}
+
+	var dnsCheck, tlsCheck *SubCheckResult
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if net.ParseIP(u.Hostname()) == nil {
+			dnsCheck = checkDNS(ctx, target, u, target.Composite.DNS.Expect, timeout)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if u.Scheme == "https" {
+			tlsCheck = checkCertificate(ctx, target, u, target.Composite.minValidDays(), timeout)
+		}
+	}()
+
+	result := executeRequest(ctx, target)
+	wg.Wait()
+
+	httpCheck := SubCheckResult{Name: "http", Success: result.Success, Latency: result.Latency, Error: result.Error}
+	if result.Status != 0 {
+		httpCheck.Detail = fmt.Sprintf("статус %d", result.Status)
+	}
+
+	var failed []string
+	for _, sub := range []*SubCheckResult{dnsCheck, tlsCheck, &httpCheck} {
+		if sub == nil {
+			continue
+		}
+		result.SubChecks = append(result.SubChecks, *sub)
+		if !sub.Success {
+			failed = append(failed, sub.Name+": "+sub.Error)
+		}
+	}
+
+	result.Success = len(failed) == 0
+	result.Error = strings.Join(failed, "; ")
+	if !result.Success && result.Severity != "" {
+		result.Severity = SeverityCritical
+	}
+	return result
+}
+
+func (c *CompositeCheck) minValidDays() int {
+	if c.TLS.MinValidDays == nil {
+		return defaultMinValidDays
+	}
+	return *c.TLS.MinValidDays
+}
+
+// checkDNS разрешает имя хоста (с учётом dns_server и resolve цели) и сверяет адреса с ожидаемыми
+func checkDNS(ctx context.Context, target Target, u *url.URL, expect []string, timeout time.Duration) *SubCheckResult {
+	sub := &SubCheckResult{Name: "dns"}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := target.lookupHost(ctx, u.Hostname(), urlPort(u))
+	sub.Latency = time.Since(start)
+	if err != nil {
+		sub.Error = err.Error()
+		return sub
+	}
+	sort.Strings(addrs)
+	sub.Detail = strings.Join(addrs, ", ")
+
+	if len(expect) > 0 {
+		found := false
+		for _, a := range addrs {
+			for _, e := range expect {
+				if a == e {
+					found = true
+				}
+			}
+		}
+		if !found {
+			sub.Error = fmt.Sprintf("адреса %s не содержат ожидаемых %s", sub.Detail, strings.Join(expect, ", "))
+			return sub
+		}
+	}
+
+	sub.Success = true
+	return sub
+}
+
+// checkCertificate устанавливает TLS-соединение, проверяет цепочку, имя хоста
+// и оставшийся срок действия сертификата
+func checkCertificate(ctx context.Context, target Target, u *url.URL, minValidDays int, timeout time.Duration) *SubCheckResult {
+	sub := &SubCheckResult{Name: "tls"}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	raw, err := target.dialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), urlPort(u)))
+	if err != nil {
+		sub.Latency = time.Since(start)
+		sub.Error = err.Error()
+		return sub
+	}
+	conn := tls.Client(raw, &tls.Config{ServerName: u.Hostname()})
+	err = conn.HandshakeContext(ctx)
+	sub.Latency = time.Since(start)
+	if err != nil {
+		raw.Close()
+		sub.Error = err.Error()
+		return sub
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		sub.Error = "сервер не предъявил сертификат"
+		return sub
+	}
+	leaf := certs[0]
+	daysLeft := int(time.Until(leaf.NotAfter).Hours() / 24)
+	sub.Detail = fmt.Sprintf("действителен до %s (%d дн.), выдан %s",
+		leaf.NotAfter.Format("2006-01-02"), daysLeft, leaf.Issuer.CommonName)
+
+	if daysLeft < minValidDays {
+		sub.Error = fmt.Sprintf("сертификат истекает через %d дн., минимум %d", daysLeft, minValidDays)
+		return sub
+	}
+
+	sub.Success = true
+	return sub
+}
+
+// urlPort возвращает порт URL, для пустого — порт по умолчанию для схемы
+func urlPort(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"test/checker"
+)
+
+// Типы проверок
+const (
+	CheckTypeHTTP    = "http"
+	CheckTypeGraphQL = "graphql"
+	// DNS, TLS-сертификат и HTTP одного домена вместе
+	CheckTypeComposite = "composite"
+	// Последовательность запросов с общими cookie, например вход и запрос с сессией
+	CheckTypeScenario = "scenario"
+)
+
+// Классы приоритета целей
+const (
+	PriorityCritical = "critical"
+	PriorityNormal   = "normal"
+	PriorityBulk     = "bulk"
+)
+
+// Числовые уровни приоритета: меньше — важнее
+const (
+	priorityCritical = iota
+	priorityNormal
+	priorityBulk
+	priorityLevels
+)
+
+type Config struct {
+	// Значения по умолчанию, которые наследуют все цели
+	Defaults TargetDefaults `yaml:"defaults"`
+	Targets  []Target       `yaml:"targets"`
+	Alerts   *AlertConfig   `yaml:"alerts"`
+	// Правила переходов между состояниями здоровья целей
+	Health *HealthConfig `yaml:"health"`
+	// Приёмники результатов из пакета sink
+	Sinks []SinkConfig `yaml:"sinks"`
+	// Экспорт трасс проверок по OTLP
+	Tracing *TracingConfig `yaml:"tracing"`
+	// Внешние типы проверок и приёмники
+	Plugins []PluginConfig `yaml:"plugins"`
+	// Выгрузка файла результатов после запуска: S3, GCS или HTTP
+	Uploads []UploadConfig `yaml:"uploads"`
+}
+
+// TargetDefaults — общие настройки целей; цель может переопределить любое из них
+type TargetDefaults struct {
+	Timeout   time.Duration     `yaml:"timeout"`
+	Retries   *int              `yaml:"retries"`
+	Priority  string            `yaml:"priority"`
+	Headers   map[string]string `yaml:"headers"` // объединяются с заголовками цели
+	Notifiers []NotifierConfig  `yaml:"notifiers"`
+	SLO       *SLOConfig        `yaml:"slo"`
+	Schedule  []ScheduleRule    `yaml:"schedule"`
+	// Периоды обслуживания, общие для целей без своих
+	Maintenance []MaintenanceWindow `yaml:"maintenance"`
+	RateLimit   *RateLimitPolicy    `yaml:"rate_limit"`
+	Proxy       string              `yaml:"proxy"`
+	DNSServer   string              `yaml:"dns_server"`
+	Resolve     map[string]string   `yaml:"resolve"`
+	IPVersion   string              `yaml:"ip_version"`
+	// Версия HTTP: 1.1, 2 или compare
+	HTTPVersion string `yaml:"http_version"`
+	// Соединения: warm, cold или cold-dns
+	Connection string `yaml:"connection"`
+	Enrich     *bool  `yaml:"enrich"`
+}
+
+type Target struct {
+	Name     string            `yaml:"name,omitempty"`
+	Type     string            `yaml:"type,omitempty"`     // http (по умолчанию), graphql, composite, scenario или тип из плагина
+	Priority string            `yaml:"priority,omitempty"` // critical, normal (по умолчанию) или bulk
+	URL      string            `yaml:"url,omitempty"`
+	Method   string            `yaml:"method,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Body     string            `yaml:"body,omitempty"`
+
+	Timeout time.Duration `yaml:"timeout,omitempty"` // 0 — без ограничения
+	// timeout указан в конфигурации явно: тогда 0 отменяет унаследованный таймаут
+	timeoutSet bool
+	// Таймаут по недавним задержкам цели вместо постоянного timeout
+	AdaptiveTimeout *AdaptiveTimeout `yaml:"adaptive_timeout,omitempty"`
+	// Число повторов неуспешной проверки; 0 в цели отключает унаследованные повторы
+	Retries *int `yaml:"retries,omitempty"`
+	// Каналы оповещений цели вместо общих alerts.notifiers
+	Notifiers []NotifierConfig `yaml:"notifiers,omitempty"`
+	// Целевой уровень доступности для оповещений о бюджете ошибок
+	SLO *SLOConfig `yaml:"slo,omitempty"`
+	// Коды ответа, при которых цель доступна, например [200, 401] или [2xx, 304]; по умолчанию 200
+	ExpectedStatus []string `yaml:"expected_status,omitempty"`
+	// Периоды обслуживания, в которые сбои не учитываются и не вызывают оповещений
+	Maintenance []MaintenanceWindow `yaml:"maintenance,omitempty"`
+	// Реакция на ответы 429 и 503 с Retry-After
+	RateLimit *RateLimitPolicy `yaml:"rate_limit,omitempty"`
+	// Прокси-сервер: http://, https://, socks5:// или socks5h:// с необязательными логином
+	// и паролем в URL; direct — без прокси, даже если он задан в окружении
+	Proxy string `yaml:"proxy,omitempty"`
+	// DNS-сервер для имён цели (адрес[:порт]) и статические адреса хостов в духе curl --resolve:
+	// ключ — host или host:port, значение — IP-адрес
+	DNSServer string            `yaml:"dns_server,omitempty"`
+	Resolve   map[string]string `yaml:"resolve,omitempty"`
+	// Семейство адресов: 4, 6 или dual — проверять по IPv4 и IPv6 отдельно; по умолчанию любое
+	IPVersion string `yaml:"ip_version,omitempty"`
+	// Версия HTTP: 1.1, 2 или compare — проверять по HTTP/1.1 и HTTP/2 отдельно; по умолчанию
+	// HTTP/2 согласуется для https, если его поддерживает сервер
+	HTTPVersion string `yaml:"http_version,omitempty"`
+	// Соединения: warm (по умолчанию) — переиспользовать keep-alive, cold — новое соединение
+	// для каждого запроса, cold-dns — ещё и разрешение имени в обход системного кэша DNS
+	Connection string `yaml:"connection,omitempty"`
+	// Записывать в результаты IP-адрес сервера, версию TLS, шифр и серийный номер
+	// сертификата; по умолчанию включено
+	Enrich *bool `yaml:"enrich,omitempty"`
+	// Интервал проверок цели вместо общего -t; правила schedule важнее
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Интервалы проверок по дням недели и времени суток вместо общего -t
+	Schedule []ScheduleRule `yaml:"schedule,omitempty"`
+	// Число проверок цели вместо общего -n и время, в течение которого цель проверяется;
+	// если заданы оба, проверки прекращаются по первому из ограничений
+	Checks   int           `yaml:"checks,omitempty"`
+	Duration time.Duration `yaml:"duration,omitempty"`
+	// Число одновременных проверок цели в каждой итерации, по умолчанию 1
+	Concurrency int `yaml:"concurrency,omitempty"`
+
+	GraphQL   *GraphQLCheck   `yaml:"graphql,omitempty"`
+	Composite *CompositeCheck `yaml:"composite,omitempty"`
+	// Шаги сценария (type: scenario) — цели, выполняемые по порядку с общими cookie
+	Steps []Target `yaml:"steps,omitempty"`
+	// Хранение cookie между запросами и проверками цели
+	Session *SessionConfig `yaml:"session,omitempty"`
+	// Cookie, которые должен установить ответ: имя — регулярное выражение для значения
+	SetCookies map[string]string `yaml:"set_cookies,omitempty"`
+	// Параметры проверки типа из плагина
+	Options map[string]interface{} `yaml:"options,omitempty"`
+	// JSON Schema, которой должно соответствовать тело ответа: в конфигурации, в отдельном
+	// файле или схема ответа операции из документа OpenAPI
+	Schema     map[string]interface{} `yaml:"schema,omitempty"`
+	SchemaFile string                 `yaml:"schema_file,omitempty"`
+	OpenAPI    *OpenAPIResponse       `yaml:"openapi,omitempty"`
+	// Ожидаемая контрольная сумма SHA-256 тела ответа в шестнадцатеричном виде
+	SHA256 string `yaml:"sha256,omitempty"`
+	// Перенаправления: следовать ли им, предельное число и считать ли их успехом
+	Redirects *RedirectPolicy `yaml:"redirects,omitempty"`
+	// Скрипт проверки ответа на подмножестве JavaScript, в конфигурации или в отдельном файле
+	Script     string `yaml:"script,omitempty"`
+	ScriptFile string `yaml:"script_file,omitempty"`
+	// Эталонный ответ: тело сравнивается с JSON-документом из файла по полям
+	Reference *ResponseReference `yaml:"reference,omitempty"`
+	// Набор данных: цель проверяется для каждой его строки
+	Dataset *DatasetConfig `yaml:"dataset,omitempty"`
+	// Строка набора данных, заполняется при разворачивании и передаётся агентам вместе с целью
+	DatasetRow *DatasetRow `yaml:"dataset_row,omitempty"`
+
+	// Выражение условия успеха, например "status in [200, 204] && latency < 500ms"
+	Success string `yaml:"success,omitempty"`
+	// Правила уровня серьёзности, проверяются по порядку
+	Severity []SeverityRule `yaml:"severity,omitempty"`
+
+	successCond    *compiledCondition
+	severityConds  []*compiledCondition
+	responseSchema *responseSchema
+	reference      *responseReference
+	script         *checkScript
+	templates      *requestTemplates
+	transport      *http.Transport
+	dialer         *targetDialer
+	adaptive       *adaptiveState
+	rateLimit      *rateLimitState
+	checker        checker.Checker
+	session        *sessionState
+	expectedStatus []statusRange
+	setCookies     map[string]*regexp.Regexp
+	// Cookie сценария, которому принадлежит шаг
+	jar http.CookieJar
+	// Имя исходной цели, если она проверяется по IPv4 и IPv6 отдельно
+	dualStackName string
+	// Имя исходной цели, если она проверяется по нескольким версиям HTTP
+	protocolName string
+}
+
+type SeverityRule struct {
+	When  string `yaml:"when"`
+	Level string `yaml:"level"` // ok, warning или critical
+}
+
+// defaultTarget используется, если конфигурация не задана
+func defaultTarget() Target {
+	return Target{Name: "thecatapi", Type: CheckTypeHTTP, URL: "https://thecatapi.com"}
+}
+
+func loadConfig(path string) (Config, error) {
+	return readConfig(path, true)
+}
+
+// readConfig загружает конфигурацию; без requireTargets допускается файл только
+// с defaults и общими разделами, например для целей из стандартного ввода
+func readConfig(path string, requireTargets bool) (Config, error) {
+	var cfg Config
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("разбор %s: %w", path, err)
+	}
+
+	if cfg.Alerts != nil && cfg.Alerts.BurnRate != nil {
+		if err := cfg.Alerts.BurnRate.check(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := loadPlugins(cfg.Plugins); err != nil {
+		return cfg, err
+	}
+
+	if requireTargets && len(cfg.Targets) == 0 {
+		return cfg, fmt.Errorf("%s: не описано ни одной цели", path)
+	}
+
+	var targets []Target
+	for i, t := range cfg.Targets {
+		t.inherit(cfg.Defaults)
+		rows, err := t.expandDataset()
+		if err != nil {
+			return cfg, fmt.Errorf("цель #%d: %w", i+1, err)
+		}
+		var expanded []Target
+		for _, row := range rows {
+			expanded = append(expanded, row.expandVariants()...)
+		}
+		for j := range expanded {
+			if err := expanded[j].normalize(); err != nil {
+				return cfg, fmt.Errorf("цель #%d: %w", i+1, err)
+			}
+		}
+		targets = append(targets, expanded...)
+	}
+	cfg.Targets = targets
+
+	return cfg, nil
+}
+
+// expandVariants заменяет цель её вариантами по версиям HTTP и семействам адресов
+func (t Target) expandVariants() []Target {
+	var out []Target
+	for _, pt := range t.expandProtocols() {
+		out = append(out, pt.expandDualStack()...)
+	}
+	return out
+}
+
+// UnmarshalYAML запоминает, указан ли timeout, чтобы timeout: 0 отличался от отсутствующего
+func (t *Target) UnmarshalYAML(node *yaml.Node) error {
+	type plain Target
+	if err := node.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "timeout" {
+			t.timeoutSet = true
+		}
+	}
+	return nil
+}
+
+// inherit заполняет незаданные в цели поля значениями из defaults
+func (t *Target) inherit(d TargetDefaults) {
+	if t.Timeout == 0 && !t.timeoutSet {
+		t.Timeout = d.Timeout
+	}
+	if t.Retries == nil {
+		t.Retries = d.Retries
+	}
+	if t.Priority == "" {
+		t.Priority = d.Priority
+	}
+	if t.Notifiers == nil {
+		t.Notifiers = d.Notifiers
+	}
+	if t.SLO == nil {
+		t.SLO = d.SLO
+	}
+	if t.Schedule == nil {
+		t.Schedule = d.Schedule
+	}
+	if t.Maintenance == nil {
+		t.Maintenance = d.Maintenance
+	}
+	if t.RateLimit == nil {
+		t.RateLimit = d.RateLimit
+	}
+	if t.Proxy == "" {
+		t.Proxy = d.Proxy
+	}
+	if t.DNSServer == "" {
+		t.DNSServer = d.DNSServer
+	}
+	if t.Resolve == nil {
+		t.Resolve = d.Resolve
+	}
+	if t.IPVersion == "" {
+		t.IPVersion = d.IPVersion
+	}
+	if t.HTTPVersion == "" {
+		t.HTTPVersion = d.HTTPVersion
+	}
+	if t.Connection == "" {
+		t.Connection = d.Connection
+	}
+	if t.Enrich == nil {
+		t.Enrich = d.Enrich
+	}
+	// Имена заголовков не зависят от регистра: authorization в цели заменяет Authorization из defaults
+	if len(d.Headers) > 0 || len(t.Headers) > 0 {
+		headers := make(map[string]string, len(d.Headers)+len(t.Headers))
+		for k, v := range d.Headers {
+			headers[http.CanonicalHeaderKey(k)] = v
+		}
+		for k, v := range t.Headers {
+			headers[http.CanonicalHeaderKey(k)] = v
+		}
+		t.Headers = headers
+	}
+}
+
+func (t Target) retries() int {
+	if t.Retries == nil || *t.Retries < 0 {
+		return 0
+	}
+	return *t.Retries
+}
+
+// normalize проставляет значения по умолчанию и проверяет корректность цели
+func (t *Target) normalize() error {
+	if t.URL == "" {
+		return fmt.Errorf("не указан url")
+	}
+	if t.Name == "" {
+		t.Name = t.URL
+	}
+	if t.Type == "" {
+		t.Type = CheckTypeHTTP
+	}
+	if t.Priority == "" {
+		t.Priority = PriorityNormal
+	}
+	if t.Priority != PriorityCritical && t.Priority != PriorityNormal && t.Priority != PriorityBulk {
+		return fmt.Errorf("%s: неизвестный приоритет %q", t.Name, t.Priority)
+	}
+
+	switch t.Type {
+	case CheckTypeHTTP, CheckTypeComposite, CheckTypeScenario:
+		if t.Method == "" {
+			t.Method = "GET"
+		}
+		if t.Type == CheckTypeComposite && t.Composite == nil {
+			t.Composite = &CompositeCheck{}
+		}
+	case CheckTypeGraphQL:
+		if t.GraphQL == nil || t.GraphQL.Query == "" {
+			return fmt.Errorf("%s: для типа graphql нужен graphql.query", t.Name)
+		}
+		t.Method = "POST"
+	default:
+		c, ok := checker.Lookup(t.Type)
+		if !ok {
+			return fmt.Errorf("%s: неизвестный тип проверки %q", t.Name, t.Type)
+		}
+		t.checker = c
+	}
+
+	switch t.IPVersion {
+	case "", IPVersion4, IPVersion6:
+	case IPVersionDual:
+		return fmt.Errorf("%s: ip_version: dual поддерживается только в файле конфигурации и флагом -dual-stack", t.Name)
+	default:
+		return fmt.Errorf("%s: неизвестное значение ip_version %q", t.Name, t.IPVersion)
+	}
+
+	if err := t.checkHTTPVersion(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if err := t.checkConnection(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	if t.Dataset != nil {
+		return fmt.Errorf("%s: dataset поддерживается только в файле конфигурации", t.Name)
+	}
+
+	if t.Interval < 0 || t.Checks < 0 || t.Duration < 0 || t.Concurrency < 0 {
+		return fmt.Errorf("%s: interval, checks, duration и concurrency не могут быть отрицательными", t.Name)
+	}
+
+	if t.SLO != nil {
+		if err := t.SLO.check(); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+
+	t.adaptive = nil
+	if t.AdaptiveTimeout != nil {
+		state, err := t.AdaptiveTimeout.compile()
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		t.adaptive = state
+	}
+
+	if err := t.compileTransport(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	if err := t.compileTemplates(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	if err := t.compileResponseSchema(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if err := t.checkSHA256(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if t.Redirects != nil {
+		if err := t.Redirects.compile(); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+	if err := t.compileReference(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if err := t.compileScript(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if err := t.compileSession(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if err := t.compileSteps(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	if err := t.compileExpectedStatus(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	if err := t.compileRateLimit(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	if err := t.compileMaintenance(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	// Правила копируются, чтобы не разделять разобранные значения с defaults
+	t.Schedule = append([]ScheduleRule(nil), t.Schedule...)
+	for i := range t.Schedule {
+		if err := t.Schedule[i].compile(); err != nil {
+			return fmt.Errorf("%s: schedule #%d: %w", t.Name, i+1, err)
+		}
+	}
+
+	if t.Success != "" {
+		cond, err := compileCondition(t.Success)
+		if err != nil {
+			return fmt.Errorf("%s: success: %w", t.Name, err)
+		}
+		t.successCond = cond
+	}
+
+	t.severityConds = nil
+	for i, rule := range t.Severity {
+		switch rule.Level {
+		case SeverityOK, SeverityWarning, SeverityCritical:
+		default:
+			return fmt.Errorf("%s: severity #%d: неизвестный уровень %q", t.Name, i+1, rule.Level)
+		}
+		cond, err := compileCondition(rule.When)
+		if err != nil {
+			return fmt.Errorf("%s: severity #%d: %w", t.Name, i+1, err)
+		}
+		t.severityConds = append(t.severityConds, cond)
+	}
+
+	return nil
+}
+
+func (t Target) priorityLevel() int {
+	switch t.Priority {
+	case PriorityCritical:
+		return priorityCritical
+	case PriorityBulk:
+		return priorityBulk
+	default:
+		return priorityNormal
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runConfigCommand реализует подкоманды config: пока только diff
+func runConfigCommand(args []string) error {
+	if len(args) != 3 || args[0] != "diff" {
+		return fmt.Errorf("использование: config diff old.yaml new.yaml")
+	}
+
+	oldCfg, err := loadConfig(args[1])
+	if err != nil {
+		return err
+	}
+	newCfg, err := loadConfig(args[2])
+	if err != nil {
+		return err
+	}
+
+	lines := diffConfigs(oldCfg, newCfg)
+	if len(lines) == 0 {
+		fmt.Println("Конфигурации не различаются.")
+		return nil
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// diffConfigs возвращает читаемый список изменений между двумя конфигурациями:
+// добавленные (+), удалённые (-) и изменённые (~) цели, а также прочие настройки
+func diffConfigs(oldCfg, newCfg Config) []string {
+	var lines []string
+
+	oldTargets := make(map[string]Target)
+	for _, t := range oldCfg.Targets {
+		oldTargets[t.Name] = t
+	}
+	newNames := make(map[string]bool)
+
+	for _, t := range newCfg.Targets {
+		newNames[t.Name] = true
+		old, ok := oldTargets[t.Name]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("+ цель %s (%s %s)", t.Name, t.Method, t.URL))
+			continue
+		}
+		changes := diffValues(old, t)
+		if len(changes) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("~ цель %s:", t.Name))
+		for _, c := range changes {
+			lines = append(lines, "    "+c)
+		}
+	}
+
+	for _, t := range oldCfg.Targets {
+		if !newNames[t.Name] {
+			lines = append(lines, fmt.Sprintf("- цель %s (%s %s)", t.Name, t.Method, t.URL))
+		}
+	}
+
+	// Всё, кроме списка целей, сравнивается как плоский набор путей
+	oldRest, newRest := oldCfg, newCfg
+	oldRest.Targets, newRest.Targets = nil, nil
+	for _, c := range diffValues(oldRest, newRest) {
+		lines = append(lines, "~ "+c)
+	}
+
+	return lines
+}
+
+// diffValues сравнивает два значения по путям полей в YAML-представлении
+func diffValues(a, b interface{}) []string {
+	oldFlat := flattenYAML(a)
+	newFlat := flattenYAML(b)
+
+	keys := make(map[string]bool)
+	for k := range oldFlat {
+		keys[k] = true
+	}
+	for k := range newFlat {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, k := range sorted {
+		o, oldOK := oldFlat[k]
+		n, newOK := newFlat[k]
+		switch {
+		case !oldOK:
+			changes = append(changes, fmt.Sprintf("%s: добавлено %s", k, n))
+		case !newOK:
+			changes = append(changes, fmt.Sprintf("%s: удалено (было %s)", k, o))
+		case o != n:
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", k, o, n))
+		}
+	}
+	return changes
+}
+
+// flattenYAML превращает значение в набор "путь → значение" (например graphql.assertions[0].equals)
+func flattenYAML(v interface{}) map[string]string {
+	out := make(map[string]string)
+
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return out
+	}
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return out
+	}
+
+	var walk func(prefix string, node interface{})
+	walk = func(prefix string, node interface{}) {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			for k, child := range n {
+				key := k
+				if prefix != "" {
+					key = prefix + "." + k
+				}
+				walk(key, child)
+			}
+		case []interface{}:
+			for i, child := range n {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), child)
+			}
+		case nil:
+		default:
+			// Незаданные поля (нулевые значения) не считаются изменением
+			if reflect.ValueOf(n).IsZero() {
+				return
+			}
+			s := fmt.Sprint(n)
+			if strings.Contains(s, "\n") {
+				s = fmt.Sprintf("%q", s)
+			}
+			out[prefix] = s
+		}
+	}
+	walk("", tree)
+
+	return out
+}
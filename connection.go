@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"text/tabwriter"
+	"time"
+)
+
+// Значения connection цели
+const (
+	ConnectionWarm = "warm" // соединения переиспользуются (keep-alive), по умолчанию
+	ConnectionCold = "cold" // новое соединение для каждого запроса
+	// Новое соединение и разрешение имени без системного кэша DNS: запрос к серверам
+	// из /etc/resolv.conf встроенным резолвером Go
+	ConnectionColdDNS = "cold-dns"
+)
+
+// Вид соединения запроса в PhaseTimings.Connection
+const (
+	ConnectionReused = "reused"
+	ConnectionNew    = "new"
+)
+
+// checkConnection проверяет значение connection нормализуемой цели
+func (t Target) checkConnection() error {
+	switch t.Connection {
+	case "", ConnectionWarm, ConnectionCold, ConnectionColdDNS:
+		return nil
+	}
+	return fmt.Errorf("неизвестное значение connection %q, нужно warm, cold или cold-dns", t.Connection)
+}
+
+// cold сообщает, что цель проверяется без переиспользования соединений
+func (t Target) cold() bool {
+	return t.Connection == ConnectionCold || t.Connection == ConnectionColdDNS
+}
+
+// uncachedResolver разрешает имена встроенным резолвером Go напрямую у DNS-серверов
+var uncachedResolver = &net.Resolver{
+	PreferGo: true,
+	Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, address)
+	},
+}
+
+// connectionSummary — задержки цели отдельно для переиспользованных и новых соединений
+type connectionSummary struct {
+	Name        string
+	Reused, New latencyStats
+}
+
+// summarizeConnections разделяет результаты HTTP-проверок целей по виду соединения.
+// Цели без результатов по сети (например, с ошибкой до соединения) не выводятся.
+func summarizeConnections(results []CheckResult) []connectionSummary {
+	names, groups := groupByTarget(results)
+
+	var out []connectionSummary
+	for _, name := range names {
+		var reused, fresh []CheckResult
+		for _, r := range groups[name] {
+			if r.Phases == nil || r.Shed {
+				continue
+			}
+			switch r.Phases.Connection {
+			case ConnectionReused:
+				reused = append(reused, r)
+			case ConnectionNew:
+				fresh = append(fresh, r)
+			}
+		}
+		if len(reused)+len(fresh) == 0 {
+			continue
+		}
+		out = append(out, connectionSummary{Name: name, Reused: computeStats(reused), New: computeStats(fresh)})
+	}
+	return out
+}
+
+// printConnectionSummaries выводит задержки по видам соединений
+func printConnectionSummaries(out io.Writer, summaries []connectionSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nЗадержки по соединениям (тёплые — переиспользованные, холодные — новые):")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tтёплых\tp50\tp95\tхолодных\tp50\tp95")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%d\t%v\t%v\n", s.Name,
+			s.Reused.Checks, s.Reused.P50.Round(time.Millisecond), s.Reused.P95.Round(time.Millisecond),
+			s.New.Checks, s.New.P50.Round(time.Millisecond), s.New.P95.Round(time.Millisecond))
+	}
+	w.Flush()
+}
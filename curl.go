@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Флаги curl без аргумента, которые не влияют на запрос и пропускаются
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-k": true, "--insecure": true, "-L": true, "--location": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true,
+	"--compressed": true, "-f": true, "--fail": true, "-g": true, "--globoff": true,
+}
+
+// Флаги curl с аргументом, которые не переносятся в цель
+var curlIgnoredArgFlags = map[string]bool{
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"--connect-timeout": true, "--retry": true, "-x": true, "--proxy": true,
+}
+
+// parseCurlCommands разбирает одну или несколько команд curl; каждая команда
+// начинается со слова curl, перенос строки через «\» допускается
+func parseCurlCommands(src string) ([]Target, error) {
+	words, err := shellWords(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	var cmd []string
+	flush := func() error {
+		if len(cmd) == 0 {
+			return nil
+		}
+		t, err := parseCurl(cmd)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, t)
+		cmd = nil
+		return nil
+	}
+
+	for _, w := range words {
+		if w.text == "curl" && !w.quoted {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			cmd = []string{}
+			continue
+		}
+		if cmd == nil {
+			return nil, fmt.Errorf("ожидалась команда curl, получено %q", w.text)
+		}
+		cmd = append(cmd, w.text)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// parseCurl строит цель по аргументам одной команды curl
+func parseCurl(args []string) (Target, error) {
+	var t Target
+	var data []string
+	getQuery := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl: у флага %s нет значения", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		// Слитная форма длинного флага: --header=Value
+		if strings.HasPrefix(arg, "--") {
+			if name, value, ok := strings.Cut(arg, "="); ok {
+				arg = name
+				args = append(args[:i+1], append([]string{value}, args[i+1:]...)...)
+			}
+		}
+
+		switch {
+		case arg == "-X" || arg == "--request":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			t.Method = strings.ToUpper(v)
+		case arg == "-H" || arg == "--header":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			name, value, ok := strings.Cut(v, ":")
+			if !ok {
+				return t, fmt.Errorf("curl: некорректный заголовок %q", v)
+			}
+			setHeader(&t, strings.TrimSpace(name), strings.TrimSpace(value))
+		case arg == "-d" || arg == "--data" || arg == "--data-raw" || arg == "--data-binary" ||
+			arg == "--data-ascii" || arg == "--data-urlencode" || arg == "--json":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			if strings.HasPrefix(v, "@") && arg != "--data-raw" {
+				return t, fmt.Errorf("curl: тело из файла %s не поддерживается", v[1:])
+			}
+			if arg == "--data-urlencode" {
+				if name, value, ok := strings.Cut(v, "="); ok {
+					v = name + "=" + url.QueryEscape(value)
+				} else {
+					v = url.QueryEscape(v)
+				}
+			}
+			if arg == "--json" {
+				if !hasHeader(t, "Content-Type") {
+					setHeader(&t, "Content-Type", "application/json")
+				}
+				if !hasHeader(t, "Accept") {
+					setHeader(&t, "Accept", "application/json")
+				}
+			}
+			data = append(data, v)
+		case arg == "-u" || arg == "--user":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			setHeader(&t, "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		case arg == "-A" || arg == "--user-agent":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			setHeader(&t, "User-Agent", v)
+		case arg == "-e" || arg == "--referer":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			setHeader(&t, "Referer", v)
+		case arg == "-b" || arg == "--cookie":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			setHeader(&t, "Cookie", v)
+		case arg == "-m" || arg == "--max-time":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			seconds, err := time.ParseDuration(v + "s")
+			if err != nil {
+				return t, fmt.Errorf("curl: некорректный --max-time %q", v)
+			}
+			t.Timeout = seconds
+		case arg == "--url":
+			v, err := next()
+			if err != nil {
+				return t, err
+			}
+			t.URL = v
+		case arg == "-G" || arg == "--get":
+			getQuery = true
+		case arg == "-I" || arg == "--head":
+			t.Method = "HEAD"
+		case curlIgnoredFlags[arg]:
+		case curlIgnoredArgFlags[arg]:
+			if _, err := next(); err != nil {
+				return t, err
+			}
+		case strings.HasPrefix(arg, "-"):
+			return t, fmt.Errorf("curl: флаг %s не поддерживается", arg)
+		default:
+			t.URL = arg
+		}
+	}
+
+	if t.URL == "" {
+		return t, fmt.Errorf("curl: не указан URL")
+	}
+	if !strings.Contains(t.URL, "://") {
+		t.URL = "http://" + t.URL
+	}
+
+	if len(data) > 0 {
+		body := strings.Join(data, "&")
+		if getQuery {
+			sep := "?"
+			if strings.Contains(t.URL, "?") {
+				sep = "&"
+			}
+			t.URL += sep + body
+		} else {
+			t.Body = body
+			if t.Method == "" {
+				t.Method = "POST"
+			}
+			if !hasHeader(t, "Content-Type") {
+				setHeader(&t, "Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+	if t.Method == "" {
+		t.Method = "GET"
+	}
+
+	if u, err := url.Parse(t.URL); err == nil {
+		t.Name = t.Method + " " + u.Host + u.Path
+	}
+	return t, nil
+}
+
+type shellWord struct {
+	text   string
+	quoted bool
+}
+
+// shellWords разбивает текст на слова по правилам POSIX shell: одинарные и двойные
+// кавычки, экранирование обратной косой чертой и перенос строки через «\»
+func shellWords(src string) ([]shellWord, error) {
+	var words []shellWord
+	var cur strings.Builder
+	inWord, quoted := false, false
+
+	runes := []rune(src)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' {
+					cur.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("незакрытая одинарная кавычка")
+			}
+			cur.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord, quoted = true, true
+		case c == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("незакрытая двойная кавычка")
+			}
+			inWord, quoted = true, true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, shellWord{cur.String(), quoted})
+				cur.Reset()
+				inWord, quoted = false, false
+			}
+		default:
+			cur.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, shellWord{cur.String(), quoted})
+	}
+	return words, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCurlCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Target
+	}{
+		{
+			name: "GET по умолчанию, схема добавляется",
+			src:  `curl example.com/items`,
+			want: []Target{{Name: "GET example.com/items", Method: "GET", URL: "http://example.com/items"}},
+		},
+		{
+			name: "заголовки, метод и перенос строки",
+			src: `curl -X put 'https://api.example.com/v1/items/1' \
+  -H 'Content-Type: application/json' \
+  -H "X-Request-Id:  42 " \
+  --data-raw '{"name": "a b"}'`,
+			want: []Target{{
+				Name:    "PUT api.example.com/v1/items/1",
+				Method:  "PUT",
+				URL:     "https://api.example.com/v1/items/1",
+				Headers: map[string]string{"Content-Type": "application/json", "X-Request-Id": "42"},
+				Body:    `{"name": "a b"}`,
+			}},
+		},
+		{
+			name: "данные без метода — POST формы",
+			src:  `curl https://example.com/login -d user=a -d pass=b`,
+			want: []Target{{
+				Name:    "POST example.com/login",
+				Method:  "POST",
+				URL:     "https://example.com/login",
+				Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+				Body:    "user=a&pass=b",
+			}},
+		},
+		{
+			name: "-G переносит данные в строку запроса",
+			src:  `curl -G https://example.com/search?lang=ru --data-urlencode 'q=a b'`,
+			want: []Target{{
+				Name:   "GET example.com/search",
+				Method: "GET",
+				URL:    "https://example.com/search?lang=ru&q=a+b",
+			}},
+		},
+		{
+			name: "--json, --user, --max-time и слитная форма флага",
+			src:  `curl --json '{"a":1}' --user=u:p --max-time 2.5 --url https://example.com/x`,
+			want: []Target{{
+				Name:   "POST example.com/x",
+				Method: "POST",
+				URL:    "https://example.com/x",
+				Headers: map[string]string{
+					"Content-Type":  "application/json",
+					"Accept":        "application/json",
+					"Authorization": "Basic dTpw",
+				},
+				Body:    `{"a":1}`,
+				Timeout: 2500 * time.Millisecond,
+			}},
+		},
+		{
+			name: "-I, пропускаемые флаги и несколько команд",
+			src:  "curl -s -S -L -o /dev/null -I https://a.example.com/\ncurl -k -A agent -e https://ref/ -b 'sid=1' https://b.example.com/",
+			want: []Target{
+				{Name: "HEAD a.example.com/", Method: "HEAD", URL: "https://a.example.com/"},
+				{
+					Name:    "GET b.example.com/",
+					Method:  "GET",
+					URL:     "https://b.example.com/",
+					Headers: map[string]string{"User-Agent": "agent", "Referer": "https://ref/", "Cookie": "sid=1"},
+				},
+			},
+		},
+		{
+			name: "экранирование и кавычки внутри слова",
+			src:  `curl -H "Authorization: Bearer \"x\"" https://example.com/a\ b`,
+			want: []Target{{
+				Name:    "GET example.com/a b",
+				Method:  "GET",
+				URL:     "https://example.com/a b",
+				Headers: map[string]string{"Authorization": `Bearer "x"`},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCurlCommands(tt.src)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("получено\n%+v\nожидалось\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCurlCommandsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"не curl", `wget https://example.com`},
+		{"нет URL", `curl -H 'A: b'`},
+		{"флаг без значения", `curl https://example.com -H`},
+		{"некорректный заголовок", `curl -H 'nocolon' https://example.com`},
+		{"тело из файла", `curl -d @body.json https://example.com`},
+		{"неизвестный флаг", `curl --unknown https://example.com`},
+		{"некорректный max-time", `curl -m abc https://example.com`},
+		{"незакрытая кавычка", `curl 'https://example.com`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseCurlCommands(tt.src); err == nil {
+				t.Errorf("ожидалась ошибка, получено %+v", got)
+			}
+		})
+	}
+}
+
+func TestShellWords(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []shellWord
+	}{
+		{`a 'b c' "d\"e" f\ g`, []shellWord{{"a", false}, {"b c", true}, {`d"e`, true}, {"f g", false}}},
+		{"x \\\n  y", []shellWord{{"x", false}, {"y", false}}},
+		{`''`, []shellWord{{"", true}}},
+		{"  ", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := shellWords(tt.src)
+		if err != nil {
+			t.Fatalf("%q: ошибка: %v", tt.src, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: получено %+v, ожидалось %+v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dashboardTemplate = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>ApiChecker</title>
+<style>
+body { font-family: sans-serif; margin: 2em; background: #fafafa; color: #222; }
+.targets { display: flex; flex-wrap: wrap; gap: 1em; }
+.card { background: #fff; border: 1px solid #ddd; border-radius: 6px; padding: 1em; width: 280px; }
+.card h2 { font-size: 1.1em; margin: 0 0 .5em; word-break: break-all; }
+.ok { color: #2e7d32; } .fail { color: #c62828; }
+.health, .slo { font-size: .9em; } .healthy { color: #2e7d32; } .degraded, .recovering { color: #f9a825; } .down { color: #c62828; }
+.gauge circle { fill: none; stroke-width: 8; }
+.gauge .track { stroke: #eee; }
+.gauge text { font-size: 14px; text-anchor: middle; }
+.spark polyline { fill: none; stroke: #1565c0; stroke-width: 1.5; }
+.spark line { stroke: #8e24aa; stroke-width: 1; stroke-dasharray: 2 2; }
+table { border-collapse: collapse; margin-top: 1em; background: #fff; }
+td, th { border: 1px solid #ddd; padding: .3em .6em; text-align: left; font-size: .9em; }
+</style>
+</head>
+<body>
+<h1>ApiChecker</h1>
+<div class="targets">
+{{range .Targets}}
+<div class="card">
+<h2>{{.Name}}</h2>
+<div class="{{if .Last.Success}}ok{{else}}fail{{end}}">
+{{if .Last.Success}}● работает{{else}}● сбой{{end}} · {{formatTime .Last.Timestamp}}
+</div>
+{{with healthOf $.Health .Name}}<div class="health {{.State}}">состояние: {{.State}} с {{formatTime .Since}}</div>{{end}}
+{{with sloOf $.SLO .Name}}<div class="slo{{if lt .BudgetRemaining 0.0}} fail{{end}}">SLO {{.Objective}}% · бюджет {{printf "%.1f" .BudgetRemaining}}% · расход {{printf "%.1f" .BurnRate}}x</div>{{end}}
+<svg class="gauge" width="80" height="80" viewBox="0 0 80 80">
+<circle class="track" cx="40" cy="40" r="32"/>
+<circle cx="40" cy="40" r="32" stroke="{{gaugeColor .Stats.SuccessRate}}" stroke-dasharray="{{gaugeDash .Stats.SuccessRate}}" transform="rotate(-90 40 40)"/>
+<text x="40" y="45">{{printf "%.1f" .Stats.SuccessRate}}%</text>
+</svg>
+<svg class="spark" width="180" height="40" viewBox="0 0 180 40">
+<polyline points="{{sparkline .Recent 180 40}}"/>
+{{range annotationMarks .Recent $.Annotations .Name 180}}<line x1="{{.}}" y1="0" x2="{{.}}" y2="40"/>{{end}}
+</svg>
+<div>средняя {{ms .Stats.Avg}} · p95 {{ms .Stats.P95}} · проверок {{.Stats.Checks}}</div>
+</div>
+{{else}}
+<p>Результатов пока нет.</p>
+{{end}}
+</div>
+{{if .Missed}}
+<h2>Пропущенные периоды</h2>
+<table>
+<tr><th>цель</th><th>начало</th><th>конец</th><th>длительность</th></tr>
+{{range .Missed}}
+<tr><td>{{.Target}}</td><td>{{formatTime .Start}}</td><td>{{formatTime .End}}</td><td>{{missedDuration .}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Annotations}}
+<h2>Аннотации</h2>
+<table>
+<tr><th>время</th><th>цель</th><th>вид</th><th>текст</th></tr>
+{{range .Annotations}}
+<tr><td>{{period .}}</td><td>{{if .Target}}{{.Target}}{{else}}все{{end}}</td><td>{{.Kind}}</td><td>{{.Text}}</td></tr>
+{{end}}
+</table>
+{{end}}
+<h2>Последние сбои</h2>
+<table>
+<tr><th>время</th><th>цель</th><th>статус</th><th>ошибка</th></tr>
+{{range .Failures}}
+<tr><td>{{formatTime .Timestamp}}</td><td>{{.Target}}</td><td>{{if .Status}}{{.Status}}{{end}}</td><td>{{.Error}}</td></tr>
+{{else}}
+<tr><td colspan="4">Сбоев нет.</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`
+
+const gaugeRadius = 32
+
+var dashboardFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"ms":         func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"gaugeDash": func(rate float64) string {
+		c := 2 * math.Pi * gaugeRadius
+		return fmt.Sprintf("%.1f %.1f", c*rate/100, c)
+	},
+	"gaugeColor": func(rate float64) string {
+		switch {
+		case rate >= 99:
+			return "#2e7d32"
+		case rate >= 90:
+			return "#f9a825"
+		default:
+			return "#c62828"
+		}
+	},
+	"sparkline":       sparklinePoints,
+	"annotationMarks": annotationMarks,
+	"period":          Annotation.period,
+	"missedDuration":  func(w MissedWindow) string { return w.duration().Round(time.Second).String() },
+	"healthOf":        healthOf,
+	"sloOf":           sloOf,
+}
+
+var dashboardTmpl = template.Must(template.New("dashboard").Funcs(dashboardFuncs).Parse(dashboardTemplate))
+
+// sparklinePoints строит координаты ломаной задержек для SVG размером width×height
+func sparklinePoints(results []CheckResult, width, height int) string {
+	if len(results) == 0 {
+		return ""
+	}
+
+	var max time.Duration
+	for _, r := range results {
+		if r.Latency > max {
+			max = r.Latency
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	step := float64(width)
+	if len(results) > 1 {
+		step = float64(width) / float64(len(results)-1)
+	}
+
+	points := make([]string, 0, len(results))
+	for i, r := range results {
+		x := float64(i) * step
+		y := float64(height) - float64(r.Latency)/float64(max)*float64(height-2) - 1
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return strings.Join(points, " ")
+}
+
+// healthOf возвращает состояние цели из списка или nil, если его нет
+func healthOf(states []HealthState, target string) *HealthState {
+	for i := range states {
+		if states[i].Target == target {
+			return &states[i]
+		}
+	}
+	return nil
+}
+
+// sloOf возвращает расчёт SLO цели из списка или nil, если его нет
+func sloOf(statuses []SLOStatus, target string) *SLOStatus {
+	for i := range statuses {
+		if statuses[i].Target == target {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// annotationMarks возвращает координаты x отметок аннотаций цели на графике задержек:
+// отметка ставится у первого результата, полученного после начала аннотации
+func annotationMarks(results []CheckResult, annotations []Annotation, target string, width int) []string {
+	if len(results) < 2 {
+		return nil
+	}
+	step := float64(width) / float64(len(results)-1)
+	first, last := results[0].Timestamp, results[len(results)-1].Timestamp
+
+	var marks []string
+	for _, a := range annotations {
+		if !a.covers(target, first, last) {
+			continue
+		}
+		i := sort.Search(len(results), func(i int) bool { return !results[i].Timestamp.Before(a.Start) })
+		marks = append(marks, fmt.Sprintf("%.1f", float64(i)*step))
+	}
+	return marks
+}
+
+// dashboardView — данные дашборда: последние результаты, аннотации и пропущенные периоды за сутки
+type dashboardView struct {
+	liveSnapshot
+	Annotations []Annotation   `json:"annotations,omitempty"`
+	Missed      []MissedWindow `json:"missed,omitempty"`
+	Health      []HealthState  `json:"health"`
+	SLO         []SLOStatus    `json:"slo,omitempty"`
+}
+
+const dashboardAnnotationsWindow = 24 * time.Hour
+
+// registerDashboard регистрирует HTML-страницу и её данные в JSON (/api/status).
+// Аннотации и пропущенные периоды показываются, только если задана база результатов.
+// С заданным token страницы требуют токен API, как и само API.
+func registerDashboard(mux *http.ServeMux, live *liveStore, store *ResultStore, health *healthTracker, slo *sloMonitor, token string) {
+	view := func() dashboardView {
+		v := dashboardView{liveSnapshot: live.Snapshot(), Health: health.snapshot(), SLO: slo.snapshot()}
+		if store != nil {
+			now := time.Now()
+			annotations, err := store.Annotations("", now.Add(-dashboardAnnotationsWindow), now)
+			if err != nil {
+				slog.Error("Ошибка при загрузке аннотаций", "error", err)
+			}
+			v.Annotations = annotations
+			missed, err := store.MissedWindows("", now.Add(-dashboardAnnotationsWindow), now)
+			if err != nil {
+				slog.Error("Ошибка при загрузке пропущенных периодов", "error", err)
+			}
+			v.Missed = missed
+		}
+		return v
+	}
+
+	mux.HandleFunc("/", requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTmpl.Execute(w, view()); err != nil {
+			slog.Error("Ошибка при отрисовке дашборда", "error", err)
+		}
+	}))
+
+	mux.HandleFunc("/api/status", requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(view())
+	}))
+}
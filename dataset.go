@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DatasetConfig — файл с набором данных цели. Цель проверяется отдельно для каждой
+// строки набора, значения столбцов доступны в шаблонах url, заголовков и тела как {{.столбец}}.
+type DatasetConfig struct {
+	File string `yaml:"file"` // CSV с заголовком или JSON-массив объектов
+	// Столбец, значение которого добавляется к имени цели; по умолчанию номер строки
+	Name string `yaml:"name,omitempty"`
+}
+
+// DatasetRow — строка набора данных, для которой проверяется цель: имя исходной
+// цели и значения столбцов. У агентов нет файла набора данных, поэтому строка
+// передаётся им в самой цели
+type DatasetRow struct {
+	Dataset string            `yaml:"dataset"`
+	Values  map[string]string `yaml:"values"`
+}
+
+// loadDataset читает строки набора данных; формат определяется по расширению файла
+func loadDataset(path string) ([]map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = parseCSVDataset(string(data))
+	case ".json":
+		rows, err = parseJSONDataset(data)
+	default:
+		return nil, fmt.Errorf("%s: неизвестный формат набора данных, нужен .csv или .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: набор данных пуст", path)
+	}
+	return rows, nil
+}
+
+func parseCSVDataset(src string) ([]map[string]string, error) {
+	r := csv.NewReader(strings.NewReader(src))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseJSONDataset разбирает массив объектов; вложенные значения подставляются как JSON
+func parseJSONDataset(data []byte) ([]map[string]string, error) {
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]string, len(item))
+		for k, v := range item {
+			switch v := v.(type) {
+			case string:
+				row[k] = v
+			case nil:
+				row[k] = ""
+			default:
+				b, _ := json.Marshal(v)
+				row[k] = string(b)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// expandDataset возвращает по цели на каждую строку набора данных;
+// цель без набора данных возвращается как есть
+func (t Target) expandDataset() ([]Target, error) {
+	if t.Dataset == nil {
+		return []Target{t}, nil
+	}
+	if t.Dataset.File == "" {
+		return nil, fmt.Errorf("%s: не указан dataset.file", t.Name)
+	}
+	rows, err := loadDataset(t.Dataset.File)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	name := t.Name
+	if name == "" {
+		name = t.URL
+	}
+
+	out := make([]Target, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		key := strconv.Itoa(i + 1)
+		if t.Dataset.Name != "" {
+			v, ok := row[t.Dataset.Name]
+			if !ok {
+				return nil, fmt.Errorf("%s: строка %d набора данных: нет столбца %q", name, i+1, t.Dataset.Name)
+			}
+			key = v
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s: повторяющееся имя строки набора данных %q", name, key)
+		}
+		seen[key] = true
+
+		rt := t
+		rt.Name = fmt.Sprintf("%s [%s]", name, key)
+		rt.Dataset = nil
+		rt.DatasetRow = &DatasetRow{Dataset: name, Values: row}
+		out = append(out, rt)
+	}
+	return out, nil
+}
+
+// datasetSummary — сводка проверок одного набора данных
+type datasetSummary struct {
+	Name  string
+	Rows  []string // имена целей-строк в порядке набора
+	Stats map[string]latencyStats
+	Total latencyStats
+}
+
+// summarizeDatasets группирует результаты целей с наборами данных по набору и строке;
+// наборы и строки идут в порядке целей
+func summarizeDatasets(targets []Target, results []CheckResult) []datasetSummary {
+	_, groups := groupByTarget(results)
+
+	var summaries []datasetSummary
+	index := make(map[string]int)
+	for _, t := range targets {
+		if t.DatasetRow == nil {
+			continue
+		}
+		name := t.DatasetRow.Dataset
+		i, ok := index[name]
+		if !ok {
+			i = len(summaries)
+			index[name] = i
+			summaries = append(summaries, datasetSummary{Name: name, Stats: make(map[string]latencyStats)})
+		}
+		s := &summaries[i]
+		s.Rows = append(s.Rows, t.Name)
+		s.Stats[t.Name] = computeStats(groups[t.Name])
+	}
+
+	for i := range summaries {
+		var all []CheckResult
+		for _, row := range summaries[i].Rows {
+			all = append(all, groups[row]...)
+		}
+		summaries[i].Total = computeStats(all)
+	}
+	return summaries
+}
+
+// printDatasetSummaries выводит итоги по строкам каждого набора данных и по набору в целом
+func printDatasetSummaries(out io.Writer, summaries []datasetSummary) {
+	for _, s := range summaries {
+		failedRows := 0
+		for _, row := range s.Rows {
+			if st := s.Stats[row]; st.Successful < st.Checks {
+				failedRows++
+			}
+		}
+		fmt.Fprintf(out, "\nНабор данных %s: строк %d, с ошибками %d, успешных проверок %.2f%%, p95 %v\n",
+			s.Name, len(s.Rows), failedRows, s.Total.SuccessRate(), s.Total.P95.Round(time.Millisecond))
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "строка\tпроверок\tуспешных\tp50\tp95")
+		for _, row := range s.Rows {
+			st := s.Stats[row]
+			fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%v\t%v\n", row, st.Checks, st.SuccessRate(),
+				st.P50.Round(time.Millisecond), st.P95.Round(time.Millisecond))
+		}
+		w.Flush()
+	}
+}
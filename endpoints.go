@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// endpointFlags — повторяемый флаг -url [имя=]адрес
+type endpointFlags []namedEndpoint
+
+type namedEndpoint struct {
+	Name string
+	URL  string
+}
+
+func (e *endpointFlags) String() string {
+	parts := make([]string, len(*e))
+	for i, ep := range *e {
+		parts[i] = ep.Name + "=" + ep.URL
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Set принимает "имя=адрес" или только адрес: тогда имя — хост адреса
+func (e *endpointFlags) Set(s string) error {
+	name, raw, ok := strings.Cut(s, "=")
+	if !ok || strings.Contains(name, "/") || strings.Contains(name, ":") {
+		name, raw = "", s
+	}
+	if name == "" {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("некорректный адрес %q", raw)
+		}
+		name = u.Host
+	}
+	for _, ep := range *e {
+		if ep.Name == name {
+			return fmt.Errorf("адрес с именем %s уже задан", name)
+		}
+	}
+	*e = append(*e, namedEndpoint{Name: name, URL: raw})
+	return nil
+}
+
+// endpointComparison — результаты адреса рядом с эталоном (первым адресом)
+type endpointComparison struct {
+	Name  string       `json:"name"`
+	URL   string       `json:"url"`
+	Stats latencyStats `json:"stats"`
+	// Для эталона поля сравнения не заполняются
+	Reference bool    `json:"reference,omitempty"`
+	P95Change float64 `json:"p95_change,omitempty"`
+	// Изменение доли успешных относительно эталона, п.п.
+	SuccessDelta float64  `json:"success_delta,omitempty"`
+	LatencyP     float64  `json:"latency_p,omitempty"`
+	SuccessP     float64  `json:"success_p,omitempty"`
+	Regressed    bool     `json:"regressed,omitempty"`
+	Notes        []string `json:"notes,omitempty"`
+}
+
+// compareEndpoints проверяет равнозначные адреса одинаковой нагрузкой одновременно и
+// сравнивает каждый с первым. Возвращает true, если какой-либо адрес значимо хуже эталона.
+func compareEndpoints(out io.Writer, template Target, endpoints endpointFlags, duration, interval time.Duration, th regressionThresholds, alpha float64, jsonOut bool) (bool, error) {
+	if len(endpoints) < 2 {
+		return false, fmt.Errorf("для сравнения нужно не меньше двух адресов -url")
+	}
+	if interval <= 0 || duration < interval {
+		return false, fmt.Errorf("-duration должен быть не меньше интервала -t")
+	}
+
+	targets := make([]Target, len(endpoints))
+	for i, ep := range endpoints {
+		t, err := derivedTarget(template, ep.Name, ep.URL)
+		if err != nil {
+			return false, err
+		}
+		targets[i] = t
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, duration+time.Minute)
+	defer cancel()
+
+	slog.Info("Сравнение адресов", "endpoints", len(endpoints), "reference", endpoints[0].Name, "duration", duration)
+	result := runTests(ctx, newTargetRegistry(targets, TargetDefaults{}), runOptions{
+		Interval:  interval,
+		NumChecks: int(duration / interval),
+	})
+	_, groups := groupByTarget(result.Results)
+
+	comparisons := judgeEndpoints(endpoints, groups, th, alpha)
+	regressed := false
+	for _, c := range comparisons {
+		regressed = regressed || c.Regressed
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return regressed, enc.Encode(comparisons)
+	}
+	printEndpointComparison(out, comparisons, alpha)
+	return regressed, nil
+}
+
+// judgeEndpoints сравнивает адреса с эталоном; отличия учитываются, только если они
+// статистически значимы на уровне alpha
+func judgeEndpoints(endpoints endpointFlags, groups map[string][]CheckResult, th regressionThresholds, alpha float64) []endpointComparison {
+	ref := groups[endpoints[0].Name]
+	out := []endpointComparison{{Name: endpoints[0].Name, URL: endpoints[0].URL, Stats: computeStats(ref), Reference: true}}
+
+	for _, ep := range endpoints[1:] {
+		c := compareTarget(ep.Name, ref, groups[ep.Name], th)
+		e := endpointComparison{
+			Name:         ep.Name,
+			URL:          ep.URL,
+			Stats:        c.Current,
+			P95Change:    c.P95Change,
+			SuccessDelta: c.Current.SuccessRate() - c.Baseline.SuccessRate(),
+			LatencyP:     c.LatencyP,
+			SuccessP:     c.SuccessP,
+		}
+		if c.LatencyP < alpha {
+			if c.P95Change > 0 {
+				e.Notes = append(e.Notes, fmt.Sprintf("медленнее: p95 %+.1f%%", c.P95Change))
+			} else {
+				e.Notes = append(e.Notes, fmt.Sprintf("быстрее: p95 %+.1f%%", c.P95Change))
+			}
+			if c.P95Change > th.MaxP95Regression {
+				e.Regressed = true
+			}
+		}
+		if c.SuccessP < alpha && e.SuccessDelta != 0 {
+			if e.SuccessDelta < 0 {
+				e.Notes = append(e.Notes, fmt.Sprintf("менее надёжен: успешных %+.2f п.п.", e.SuccessDelta))
+			} else {
+				e.Notes = append(e.Notes, fmt.Sprintf("надёжнее: успешных %+.2f п.п.", e.SuccessDelta))
+			}
+			if -e.SuccessDelta > th.MaxSuccessDrop {
+				e.Regressed = true
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func printEndpointComparison(out io.Writer, comparisons []endpointComparison, alpha float64) {
+	fmt.Fprintf(out, "Сравнение с эталоном %s (значимые отличия при p < %g):\n", comparisons[0].Name, alpha)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "адрес\tпроверок\tуспешных\tp50\tp95\tp99\tизменение p95\tp (задержки)\tp (успешные)\tитог")
+	for _, c := range comparisons {
+		st := c.Stats
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%v\t%v\t%v\t", c.Name, st.Checks, st.SuccessRate(),
+			st.P50.Round(time.Millisecond), st.P95.Round(time.Millisecond), st.P99.Round(time.Millisecond))
+		if c.Reference {
+			fmt.Fprintln(w, "-\t-\t-\tэталон")
+			continue
+		}
+		verdict := "без значимых отличий"
+		if len(c.Notes) > 0 {
+			verdict = strings.Join(c.Notes, "; ")
+		}
+		if c.Regressed {
+			verdict = "регрессия: " + verdict
+		}
+		fmt.Fprintf(w, "%+.1f%%\t%.3f\t%.3f\t%s\n", c.P95Change, c.LatencyP, c.SuccessP, verdict)
+	}
+	w.Flush()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// TLSDetails — параметры TLS-соединения, по которому выполнен запрос
+type TLSDetails struct {
+	Version string `json:"version"`
+	Cipher  string `json:"cipher"`
+	// Серийный номер сертификата сервера в шестнадцатеричном виде
+	CertSerial string `json:"cert_serial,omitempty"`
+}
+
+// enriched сообщает, записываются ли в результаты цели IP-адрес сервера и параметры TLS
+func (t Target) enriched() bool {
+	return t.Enrich == nil || *t.Enrich
+}
+
+// connRecorder запоминает адрес сервера и параметры TLS соединения запроса.
+// Адрес записывается и при неудачном соединении, чтобы сбой можно было связать с сервером.
+type connRecorder struct {
+	mu     sync.Mutex
+	remote string
+	tls    *tls.ConnectionState
+}
+
+func (c *connRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		ConnectDone: func(_, addr string, _ error) {
+			c.mu.Lock()
+			c.remote = addr
+			c.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			c.remote = info.Conn.RemoteAddr().String()
+			c.mu.Unlock()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, _ error) {
+			c.mu.Lock()
+			c.tls = &state
+			c.mu.Unlock()
+		},
+	}
+}
+
+// apply записывает собранные сведения в результат; resp может быть nil, если запрос не удался
+func (c *connRecorder) apply(r *CheckResult, resp *http.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if host, _, err := net.SplitHostPort(c.remote); err == nil {
+		r.RemoteIP = host
+	}
+	state := c.tls
+	if resp != nil && resp.TLS != nil {
+		state = resp.TLS
+	}
+	if state != nil && state.Version != 0 {
+		r.TLS = tlsDetails(*state)
+	}
+}
+
+func tlsDetails(state tls.ConnectionState) *TLSDetails {
+	d := &TLSDetails{
+		Version: tls.VersionName(state.Version),
+		Cipher:  tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) > 0 {
+		d.CertSerial = strings.ToUpper(fmt.Sprintf("%x", state.PeerCertificates[0].SerialNumber))
+	}
+	return d
+}
+
+// endpointKey — сервер и соединение, по которым получены результаты
+type endpointKey struct {
+	RemoteIP, Protocol, TLSVersion, CertSerial string
+}
+
+func endpointOf(r CheckResult) endpointKey {
+	k := endpointKey{RemoteIP: r.RemoteIP, Protocol: r.Protocol}
+	if r.TLS != nil {
+		k.TLSVersion, k.CertSerial = r.TLS.Version, r.TLS.CertSerial
+	}
+	return k
+}
+
+// printEndpoints выводит доступность цели по серверам, версиям протоколов и сертификатам,
+// если результаты получены больше чем по одному их сочетанию
+func printEndpoints(out io.Writer, results []CheckResult) error {
+	var keys []endpointKey
+	groups := make(map[endpointKey][]CheckResult)
+	for _, r := range results {
+		if r.Shed || r.Warmup || r.RemoteIP == "" {
+			continue
+		}
+		k := endpointOf(r)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+	if len(keys) < 2 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  сервер\tпротокол\tTLS\tсертификат\tпроверок\tдоступность\tp95")
+	for _, k := range keys {
+		st := computeStats(groups[k])
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%d\t%.2f%%\t%v\n", k.RemoteIP, k.Protocol, dash(k.TLSVersion), dash(k.CertSerial),
+			st.Checks, st.SuccessRate(), st.P95.Round(time.Millisecond))
+	}
+	return w.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
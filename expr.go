@@ -0,0 +1,393 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Выражения условий успеха и уровней серьёзности, например:
+//
+//	status in [200, 204] && (latency < 500ms || assertions)
+//	!error && ttfb <= 1s
+//
+// Числовые сигналы: status, latency, dns, connect, tls, ttfb (длительности).
+// Логические сигналы: assertions (все утверждения по телу выполнены), error (ошибка соединения).
+type conditionExpr interface {
+	eval(env signalEnv) (exprValue, error)
+}
+
+// signalEnv — значения сигналов одной проверки
+type signalEnv map[string]exprValue
+
+type exprValue struct {
+	num    float64
+	b      bool
+	isBool bool
+}
+
+var knownSignals = map[string]bool{
+	"status": false, "latency": false, "dns": false, "connect": false, "tls": false, "ttfb": false, "redirects": false, "size": false,
+	"assertions": true, "error": true,
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// compiledCondition хранит исходный текст для сообщений об ошибках
+type compiledCondition struct {
+	source string
+	expr   conditionExpr
+}
+
+func compileCondition(source string) (*compiledCondition, error) {
+	p := &exprParser{src: source}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("выражение %q: %w", source, err)
+	}
+	e, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("лишний текст %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("выражение %q: %w", source, err)
+	}
+	return &compiledCondition{source: source, expr: e}, nil
+}
+
+func (c *compiledCondition) evalBool(env signalEnv) (bool, error) {
+	v, err := c.expr.eval(env)
+	if err != nil {
+		return false, err
+	}
+	if !v.isBool {
+		return false, fmt.Errorf("выражение %q не является логическим", c.source)
+	}
+	return v.b, nil
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	num  float64
+}
+
+type exprParser struct {
+	src    string
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) tokenize() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, exprToken{kind: tokLParen, text: "("})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, exprToken{kind: tokRParen, text: ")"})
+			i++
+		case c == '[':
+			p.tokens = append(p.tokens, exprToken{kind: tokLBracket, text: "["})
+			i++
+		case c == ']':
+			p.tokens = append(p.tokens, exprToken{kind: tokRBracket, text: "]"})
+			i++
+		case c == ',':
+			p.tokens = append(p.tokens, exprToken{kind: tokComma, text: ","})
+			i++
+		case strings.ContainsRune("&|=!<>", c):
+			op := string(c)
+			if i+1 < len(s) {
+				two := s[i : i+2]
+				switch two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "&" || op == "|" || op == "=" {
+				return fmt.Errorf("неизвестный оператор %q", op)
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokOp, text: op})
+			i += len(op)
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			text := s[i:j]
+			num, err := parseExprNumber(text)
+			if err != nil {
+				return err
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokNumber, text: text, num: num})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokIdent, text: s[i:j]})
+			i = j
+		default:
+			return fmt.Errorf("неожиданный символ %q", c)
+		}
+	}
+	return nil
+}
+
+// parseExprNumber разбирает число или длительность (500ms, 2s); длительности — в наносекундах
+func parseExprNumber(text string) (float64, error) {
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return n, nil
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("некорректное число или длительность %q", text)
+	}
+	return float64(d), nil
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (conditionExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokOp && t.text == "||"; t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (conditionExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokOp && t.text == "&&"; t = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (conditionExpr, error) {
+	if t := p.peek(); t != nil && t.kind == tokOp && t.text == "!" {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (conditionExpr, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("неожиданный конец выражения")
+	}
+
+	switch t.kind {
+	case tokLParen:
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.kind != tokRParen {
+			return nil, fmt.Errorf("ожидалась ')'")
+		}
+		p.pos++
+		return e, nil
+
+	case tokIdent:
+		p.pos++
+		name := t.text
+		isBool, ok := knownSignals[name]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный сигнал %q", name)
+		}
+		if isBool {
+			return signalExpr(name), nil
+		}
+
+		op := p.peek()
+		if op == nil {
+			return nil, fmt.Errorf("после %s ожидалось сравнение", name)
+		}
+		if op.kind == tokIdent && op.text == "in" {
+			p.pos++
+			list, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			return inExpr{signal: name, values: list}, nil
+		}
+		if op.kind != tokOp || !comparisonOps[op.text] {
+			return nil, fmt.Errorf("после %s ожидалось сравнение", name)
+		}
+		p.pos++
+		value := p.peek()
+		if value == nil || value.kind != tokNumber {
+			return nil, fmt.Errorf("после %s %s ожидалось число", name, op.text)
+		}
+		p.pos++
+		return compareExpr{signal: name, op: op.text, value: value.num}, nil
+	}
+
+	return nil, fmt.Errorf("неожиданный токен %q", t.text)
+}
+
+func (p *exprParser) parseList() ([]float64, error) {
+	if t := p.peek(); t == nil || t.kind != tokLBracket {
+		return nil, fmt.Errorf("после in ожидался список [..]")
+	}
+	p.pos++
+
+	var values []float64
+	for {
+		t := p.peek()
+		if t == nil || t.kind != tokNumber {
+			return nil, fmt.Errorf("в списке ожидалось число")
+		}
+		values = append(values, t.num)
+		p.pos++
+
+		t = p.peek()
+		if t != nil && t.kind == tokComma {
+			p.pos++
+			continue
+		}
+		if t != nil && t.kind == tokRBracket {
+			p.pos++
+			return values, nil
+		}
+		return nil, fmt.Errorf("ожидалась ']'")
+	}
+}
+
+type logicalExpr struct {
+	op          string
+	left, right conditionExpr
+}
+
+func (e logicalExpr) eval(env signalEnv) (exprValue, error) {
+	l, err := evalBoolOperand(e.left, env)
+	if err != nil {
+		return exprValue{}, err
+	}
+	if e.op == "&&" && !l {
+		return exprValue{isBool: true}, nil
+	}
+	if e.op == "||" && l {
+		return exprValue{b: true, isBool: true}, nil
+	}
+	r, err := evalBoolOperand(e.right, env)
+	if err != nil {
+		return exprValue{}, err
+	}
+	return exprValue{b: r, isBool: true}, nil
+}
+
+type notExpr struct {
+	inner conditionExpr
+}
+
+func (e notExpr) eval(env signalEnv) (exprValue, error) {
+	v, err := evalBoolOperand(e.inner, env)
+	return exprValue{b: !v, isBool: true}, err
+}
+
+type signalExpr string
+
+func (e signalExpr) eval(env signalEnv) (exprValue, error) {
+	return env[string(e)], nil
+}
+
+type compareExpr struct {
+	signal string
+	op     string
+	value  float64
+}
+
+func (e compareExpr) eval(env signalEnv) (exprValue, error) {
+	v := env[e.signal].num
+	var b bool
+	switch e.op {
+	case "==":
+		b = v == e.value
+	case "!=":
+		b = v != e.value
+	case "<":
+		b = v < e.value
+	case "<=":
+		b = v <= e.value
+	case ">":
+		b = v > e.value
+	case ">=":
+		b = v >= e.value
+	}
+	return exprValue{b: b, isBool: true}, nil
+}
+
+type inExpr struct {
+	signal string
+	values []float64
+}
+
+func (e inExpr) eval(env signalEnv) (exprValue, error) {
+	v := env[e.signal].num
+	for _, candidate := range e.values {
+		if v == candidate {
+			return exprValue{b: true, isBool: true}, nil
+		}
+	}
+	return exprValue{isBool: true}, nil
+}
+
+func evalBoolOperand(e conditionExpr, env signalEnv) (bool, error) {
+	v, err := e.eval(env)
+	if err != nil {
+		return false, err
+	}
+	if !v.isBool {
+		return false, fmt.Errorf("ожидалось логическое значение")
+	}
+	return v.b, nil
+}
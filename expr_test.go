@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConditionEval(t *testing.T) {
+	env := signalsFor(CheckResult{
+		Status:  204,
+		Latency: 350 * time.Millisecond,
+		Size:    1024,
+		Phases:  &PhaseTimings{TTFB: 120 * time.Millisecond},
+	}, false)
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"status == 204", true},
+		{"status != 204", false},
+		{"status in [200, 204]", true},
+		{"status in [200,201]", false},
+		{"status >= 200 && status < 300", true},
+		{"latency < 500ms", true},
+		{"latency <= 0.3s", false},
+		{"latency > 350000000", false},
+		{"ttfb <= 1s && dns == 0", true},
+		{"size >= 1024", true},
+		{"assertions", false},
+		{"!assertions", true},
+		{"!!error", false},
+		{"assertions || status == 204", true},
+		{"status == 204 && (latency < 100ms || assertions)", false},
+		{"status == 500 || status == 204 && latency < 1s", true},
+		{"!(status == 204)", false},
+	}
+
+	for _, tt := range tests {
+		c, err := compileCondition(tt.src)
+		if err != nil {
+			t.Errorf("%q: ошибка компиляции: %v", tt.src, err)
+			continue
+		}
+		got, err := c.evalBool(env)
+		if err != nil {
+			t.Errorf("%q: ошибка вычисления: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %v, ожидалось %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestConditionShortCircuit(t *testing.T) {
+	// Правая часть не вычисляется, если результат известен по левой
+	env := signalEnv{"error": {b: true, isBool: true}}
+	for src, want := range map[string]bool{"error || status == 1": true, "!error && status == 0": false} {
+		c, err := compileCondition(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := c.evalBool(env); err != nil || got != want {
+			t.Errorf("%q = %v, %v; ожидалось %v", src, got, err, want)
+		}
+	}
+}
+
+func TestCompileConditionErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"status",
+		"status = 200",
+		"status & 1",
+		"status == ",
+		"status == abc",
+		"status == 10parsecs",
+		"unknown == 1",
+		"(status == 200",
+		"status == 200)",
+		"status in 200",
+		"status in [200,",
+		"status in [200 201]",
+		"status == 200 latency < 1s",
+		"assertions == 1",
+		"status == 200 $",
+	}
+
+	for _, src := range tests {
+		if _, err := compileCondition(src); err == nil {
+			t.Errorf("%q: ожидалась ошибка", src)
+		}
+	}
+}
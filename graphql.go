@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type GraphQLCheck struct {
+	Query         string                 `yaml:"query,omitempty"` // запрос или мутация
+	OperationName string                 `yaml:"operation_name,omitempty"`
+	Variables     map[string]interface{} `yaml:"variables,omitempty"`
+	// По умолчанию наличие массива errors в ответе считается неуспехом
+	AllowErrors bool               `yaml:"allow_errors,omitempty"`
+	Assertions  []GraphQLAssertion `yaml:"assertions,omitempty"`
+}
+
+// GraphQLAssertion проверяет значение по пути вида data.user.items.0.id
+type GraphQLAssertion struct {
+	Path   string      `yaml:"path"`
+	Equals interface{} `yaml:"equals"`
+	Exists *bool       `yaml:"exists"`
+}
+
+type graphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []graphQLError `json:"errors"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// requestBody формирует тело POST-запроса по спецификации GraphQL over HTTP
+func (g *GraphQLCheck) requestBody() ([]byte, error) {
+	payload := map[string]interface{}{"query": g.Query}
+	if g.OperationName != "" {
+		payload["operationName"] = g.OperationName
+	}
+	if len(g.Variables) > 0 {
+		payload["variables"] = g.Variables
+	}
+	return json.Marshal(payload)
+}
+
+// evaluate проверяет ответ сервера: отсутствие errors и утверждения по путям
+func (g *GraphQLCheck) evaluate(body []byte) error {
+	var resp graphQLResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("ответ не является JSON: %w", err)
+	}
+
+	if len(resp.Errors) > 0 && !g.AllowErrors {
+		return fmt.Errorf("ответ содержит errors: %s", resp.Errors[0].Message)
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return err
+	}
+
+	for _, a := range g.Assertions {
+		if err := a.check(document); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a GraphQLAssertion) check(document interface{}) error {
+	value, found := lookupPath(document, a.Path)
+
+	if a.Exists != nil {
+		if *a.Exists && !found {
+			return fmt.Errorf("%s: значение отсутствует", a.Path)
+		}
+		if !*a.Exists && found {
+			return fmt.Errorf("%s: значение присутствует", a.Path)
+		}
+	}
+
+	if a.Equals != nil {
+		if !found {
+			return fmt.Errorf("%s: значение отсутствует", a.Path)
+		}
+		expected, err := normalizeJSON(a.Equals)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(value, expected) {
+			return fmt.Errorf("%s: ожидалось %v, получено %v", a.Path, expected, value)
+		}
+	}
+
+	return nil
+}
+
+// lookupPath обходит JSON-документ по пути с точками; числа означают индексы массивов
+func lookupPath(document interface{}, path string) (interface{}, bool) {
+	current := document
+	if path == "" {
+		return current, true
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, current != nil
+}
+
+// normalizeJSON приводит значение из YAML к типам, которые даёт encoding/json
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(&out)
+	return out, err
+}
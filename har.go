@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// harLog — файл HAR 1.2, экспортированный браузером; описаны только нужные поля
+type harLog struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"` // мс
+	Request         struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+	Timings struct {
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"` // включает ssl
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+	} `json:"timings"`
+	// Chrome: тип ресурса (document, xhr, fetch, script, image...)
+	ResourceType string `json:"_resourceType"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Заголовки, которые выставляет HTTP-клиент или которые не имеют смысла при повторе
+var harSkippedHeaders = map[string]bool{
+	"host": true, "content-length": true, "connection": true,
+	"accept-encoding": true, "keep-alive": true, "transfer-encoding": true,
+}
+
+// replayedRequest — повтор одного запроса HAR в сравнении с исходным
+type replayedRequest struct {
+	Target         Target
+	OriginalStatus int
+	// Время исходного запроса до получения заголовков ответа
+	OriginalLatency time.Duration
+	Result          CheckResult
+	Slow            bool
+}
+
+// runReplay реализует подкоманду replay: запросы из HAR-файла выполняются по порядку,
+// статус и задержка каждого сравниваются с исходными. Возвращает true, если есть расхождения.
+func runReplay(args []string) (bool, error) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	domains := fs.String("domain", "", "Повторять только запросы к этим доменам (через запятую, поддомены включаются)")
+	apiOnly := fs.Bool("api", false, "Только запросы API: xhr/fetch или ответы в JSON")
+	maxSlowdown := fs.Float64("max-slowdown", 0, "Во сколько раз запрос может быть медленнее исходного (0 — не проверять)")
+	timeout := fs.Duration("timeout", 30*time.Second, "Таймаут каждого запроса")
+	output := fs.String("o", "", "Сохранить результаты в файл в формате test_results.json")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return false, fmt.Errorf("использование: replay [флаги] file.har")
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	var har harLog
+	if err := json.Unmarshal(data, &har); err != nil {
+		return false, fmt.Errorf("разбор %s: %w", fs.Arg(0), err)
+	}
+
+	var filter []string
+	for _, d := range strings.Split(*domains, ",") {
+		if d = strings.TrimSpace(strings.ToLower(d)); d != "" {
+			filter = append(filter, d)
+		}
+	}
+
+	var replays []replayedRequest
+	for i, e := range har.Log.Entries {
+		if !e.wanted(filter, *apiOnly) {
+			continue
+		}
+		t, err := e.target(fmt.Sprintf("#%d %s", i+1, e.Request.Method), *timeout)
+		if err != nil {
+			slog.Warn("Запрос пропущен", "entry", i+1, "error", err)
+			continue
+		}
+		replays = append(replays, replayedRequest{
+			Target:          t,
+			OriginalStatus:  e.Response.Status,
+			OriginalLatency: e.latency(),
+		})
+	}
+	if len(replays) == 0 {
+		return false, fmt.Errorf("%s: нет запросов для повтора", fs.Arg(0))
+	}
+
+	slog.Info("Повтор запросов", "requests", len(replays), "file", fs.Arg(0))
+
+	var result TestResult
+	failed := false
+	for i := range replays {
+		r := &replays[i]
+		r.Result = executeCheck(context.Background(), r.Target)
+		if *maxSlowdown > 0 && r.OriginalLatency > 0 &&
+			float64(r.Result.Latency) > float64(r.OriginalLatency)**maxSlowdown {
+			r.Slow = true
+			r.Result.Success = false
+			r.Result.Error = fmt.Sprintf("медленнее исходного в %.1f раза", float64(r.Result.Latency)/float64(r.OriginalLatency))
+		}
+		if !r.Result.Success {
+			failed = true
+		}
+		result.Results = append(result.Results, r.Result)
+	}
+
+	printReplay(replays)
+
+	if *output != "" {
+		jsonData, err := json.MarshalIndent(result, "", "    ")
+		if err != nil {
+			return failed, err
+		}
+		if err := ioutil.WriteFile(*output, jsonData, 0644); err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
+
+// wanted отбирает записи по домену и типу ресурса
+func (e harEntry) wanted(domains []string, apiOnly bool) bool {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	// Запросы, не получившие ответа в браузере, сравнивать не с чем
+	if e.Response.Status == 0 {
+		return false
+	}
+
+	if len(domains) > 0 {
+		host := strings.ToLower(u.Hostname())
+		matched := false
+		for _, d := range domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if apiOnly {
+		switch {
+		case e.ResourceType == "xhr" || e.ResourceType == "fetch":
+		case strings.Contains(e.Response.Content.MimeType, "json"):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// target строит цель, повторяющую запрос; успех — статус как у исходного запроса.
+// Клиент следует перенаправлениям, поэтому для исходного 3xx подходит и итоговый 2xx.
+func (e harEntry) target(name string, timeout time.Duration) (Target, error) {
+	t := Target{
+		Name:    name + " " + e.Request.URL,
+		Method:  e.Request.Method,
+		URL:     e.Request.URL,
+		Timeout: timeout,
+	}
+	for _, h := range e.Request.Headers {
+		if strings.HasPrefix(h.Name, ":") || harSkippedHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		setHeader(&t, h.Name, h.Value)
+	}
+	if p := e.Request.PostData; p != nil {
+		t.Body = p.Text
+		if p.MimeType != "" && !hasHeader(t, "Content-Type") {
+			setHeader(&t, "Content-Type", p.MimeType)
+		}
+	}
+
+	if s := e.Response.Status; s >= 300 && s < 400 {
+		t.Success = fmt.Sprintf("status == %d || (status >= 200 && status < 300)", s)
+	} else {
+		t.Success = fmt.Sprintf("status == %d", s)
+	}
+
+	if err := t.normalize(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// latency возвращает время исходного запроса без ожидания в очереди браузера и загрузки тела
+func (e harEntry) latency() time.Duration {
+	var ms float64
+	for _, v := range []float64{e.Timings.DNS, e.Timings.Connect, e.Timings.Send, e.Timings.Wait} {
+		// -1 означает, что фаза отсутствовала
+		if v > 0 {
+			ms += v
+		}
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func printReplay(replays []replayedRequest) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "запрос\tстатус было\tстатус стало\tвремя было\tвремя стало\tитог")
+
+	failed := 0
+	for _, r := range replays {
+		verdict := "ок"
+		if !r.Result.Success {
+			failed++
+			verdict = r.Result.Error
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%v\t%v\t%s\n",
+			truncate(r.Target.Name, 80), r.OriginalStatus, r.Result.Status,
+			r.OriginalLatency.Round(time.Millisecond), r.Result.Latency.Round(time.Millisecond), verdict)
+	}
+	w.Flush()
+
+	fmt.Printf("Повторено запросов: %d, с расхождениями: %d\n", len(replays), failed)
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
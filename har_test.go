@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func parseHAREntry(t *testing.T, src string) harEntry {
+	t.Helper()
+	var e harEntry
+	if err := json.Unmarshal([]byte(src), &e); err != nil {
+		t.Fatalf("разбор записи HAR: %v", err)
+	}
+	return e
+}
+
+func TestHAREntryWanted(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		domains []string
+		apiOnly bool
+		want    bool
+	}{
+		{"без фильтров", `{"request": {"url": "https://example.com/"}, "response": {"status": 200}}`, nil, false, true},
+		{"не http", `{"request": {"url": "data:text/plain,a"}, "response": {"status": 200}}`, nil, false, false},
+		{"без ответа", `{"request": {"url": "https://example.com/"}, "response": {"status": 0}}`, nil, false, false},
+		{"домен совпадает", `{"request": {"url": "https://Example.com/"}, "response": {"status": 200}}`, []string{"example.com"}, false, true},
+		{"поддомен", `{"request": {"url": "https://api.example.com/"}, "response": {"status": 200}}`, []string{"example.com"}, false, true},
+		{"похожий домен", `{"request": {"url": "https://badexample.com/"}, "response": {"status": 200}}`, []string{"example.com"}, false, false},
+		{"xhr", `{"request": {"url": "https://a/"}, "response": {"status": 200}, "_resourceType": "xhr"}`, nil, true, true},
+		{"ответ json", `{"request": {"url": "https://a/"}, "response": {"status": 200, "content": {"mimeType": "application/json; charset=utf-8"}}}`, nil, true, true},
+		{"картинка", `{"request": {"url": "https://a/"}, "response": {"status": 200, "content": {"mimeType": "image/png"}}, "_resourceType": "image"}`, nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parseHAREntry(t, tt.entry)
+			if got := e.wanted(tt.domains, tt.apiOnly); got != tt.want {
+				t.Errorf("wanted = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHAREntryTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		entry       string
+		wantHeaders map[string]string
+		wantBody    string
+		wantSuccess string
+	}{
+		{
+			name: "служебные заголовки отбрасываются",
+			entry: `{"request": {"method": "GET", "url": "https://a/x", "headers": [
+				{"name": ":authority", "value": "a"}, {"name": "Host", "value": "a"},
+				{"name": "Accept-Encoding", "value": "gzip"}, {"name": "Accept", "value": "*/*"}]},
+				"response": {"status": 200}}`,
+			wantHeaders: map[string]string{"Accept": "*/*"},
+			wantSuccess: "status == 200",
+		},
+		{
+			name: "тело и тип содержимого",
+			entry: `{"request": {"method": "POST", "url": "https://a/x", "postData": {"mimeType": "application/json", "text": "{}"}},
+				"response": {"status": 201}}`,
+			wantHeaders: map[string]string{"Content-Type": "application/json"},
+			wantBody:    "{}",
+			wantSuccess: "status == 201",
+		},
+		{
+			name: "заданный Content-Type не перезаписывается",
+			entry: `{"request": {"method": "POST", "url": "https://a/x", "headers": [{"name": "content-type", "value": "text/plain"}],
+				"postData": {"mimeType": "application/json", "text": "a"}}, "response": {"status": 200}}`,
+			wantHeaders: map[string]string{"content-type": "text/plain"},
+			wantBody:    "a",
+			wantSuccess: "status == 200",
+		},
+		{
+			name:        "перенаправление",
+			entry:       `{"request": {"method": "GET", "url": "https://a/old"}, "response": {"status": 302}}`,
+			wantSuccess: "status == 302 || (status >= 200 && status < 300)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parseHAREntry(t, tt.entry)
+			got, err := e.target("#1 "+e.Request.Method, 5*time.Second)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if got.Name != "#1 "+e.Request.Method+" "+e.Request.URL || got.URL != e.Request.URL || got.Timeout != 5*time.Second {
+				t.Errorf("имя, URL или таймаут: %q %q %v", got.Name, got.URL, got.Timeout)
+			}
+			if !reflect.DeepEqual(got.Headers, tt.wantHeaders) {
+				t.Errorf("заголовки %v, ожидалось %v", got.Headers, tt.wantHeaders)
+			}
+			if got.Body != tt.wantBody {
+				t.Errorf("тело %q, ожидалось %q", got.Body, tt.wantBody)
+			}
+			if got.Success != tt.wantSuccess {
+				t.Errorf("условие успеха %q, ожидалось %q", got.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestHAREntryLatency(t *testing.T) {
+	tests := []struct {
+		timings string
+		want    time.Duration
+	}{
+		{`{"dns": 1.5, "connect": 10, "send": 0.5, "wait": 88}`, 100 * time.Millisecond},
+		{`{"dns": -1, "connect": -1, "send": 1, "wait": 9}`, 10 * time.Millisecond},
+		{`{}`, 0},
+	}
+
+	for _, tt := range tests {
+		e := parseHAREntry(t, `{"timings": `+tt.timings+`}`)
+		if got := e.latency(); got != tt.want {
+			t.Errorf("%s: latency = %v, ожидалось %v", tt.timings, got, tt.want)
+		}
+	}
+}
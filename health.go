@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Состояния здоровья цели
+const (
+	HealthUnknown    = "unknown"
+	HealthHealthy    = "healthy"
+	HealthDegraded   = "degraded"
+	HealthDown       = "down"
+	HealthRecovering = "recovering"
+)
+
+// healthStates перечисляет состояния в порядке, в котором они выводятся в метриках
+var healthStates = []string{HealthUnknown, HealthHealthy, HealthDegraded, HealthDown, HealthRecovering}
+
+// HealthConfig — правила переходов между состояниями здоровья целей. Пороги подряд
+// идущих проверок со значением 0 и пороги процента успешных со значением 0 не действуют.
+type HealthConfig struct {
+	// Число последних проверок, по которым считается процент успешных
+	Window int `yaml:"window"`
+	// healthy → degraded: неуспешных проверок подряд или процент успешных в окне ниже порога
+	DegradedAfter int     `yaml:"degraded_after"`
+	DegradedBelow float64 `yaml:"degraded_below"`
+	// → down: неуспешных проверок подряд или процент успешных в окне ниже порога
+	DownAfter int     `yaml:"down_after"`
+	DownBelow float64 `yaml:"down_below"`
+	// down → recovering: успешных проверок подряд
+	RecoverAfter int `yaml:"recover_after"`
+	// degraded, recovering → healthy: успешных проверок подряд
+	HealthyAfter int `yaml:"healthy_after"`
+}
+
+// defaultHealthConfig используется, если не заданы ни health, ни пороги alerts
+func defaultHealthConfig() HealthConfig {
+	return HealthConfig{Window: 10, DegradedAfter: 1, DownAfter: 3, RecoverAfter: 1, HealthyAfter: 3}
+}
+
+// healthConfigFor возвращает правила состояний для конфигурации. Без раздела health
+// правила повторяют прежние условия оповещений: consecutive_failures и success_threshold
+// переводят цель в down, первая успешная проверка с достаточным процентом успешных — в healthy.
+func healthConfigFor(cfg Config) HealthConfig {
+	if cfg.Health != nil {
+		h := *cfg.Health
+		d := defaultHealthConfig()
+		if h.Window <= 0 {
+			h.Window = d.Window
+		}
+		if h.RecoverAfter <= 0 {
+			h.RecoverAfter = d.RecoverAfter
+		}
+		if h.HealthyAfter <= 0 {
+			h.HealthyAfter = d.HealthyAfter
+		}
+		return h
+	}
+	if a := cfg.Alerts; a != nil && (a.ConsecutiveFailures > 0 || a.SuccessThreshold > 0) {
+		window := a.Window
+		if window <= 0 {
+			window = 10
+		}
+		return HealthConfig{
+			Window:        window,
+			DegradedAfter: 1,
+			DownAfter:     a.ConsecutiveFailures,
+			DownBelow:     a.SuccessThreshold,
+			RecoverAfter:  1,
+			HealthyAfter:  1,
+		}
+	}
+	return defaultHealthConfig()
+}
+
+// HealthState — текущее состояние цели
+type HealthState struct {
+	Target               string    `json:"target"`
+	State                string    `json:"state"`
+	Since                time.Time `json:"since"`
+	SuccessRate          float64   `json:"success_rate"`
+	ConsecutiveFailures  int       `json:"consecutive_failures"`
+	ConsecutiveSuccesses int       `json:"consecutive_successes"`
+	LastError            string    `json:"last_error,omitempty"`
+}
+
+// HealthTransition — смена состояния цели
+type HealthTransition struct {
+	Target string
+	From   string
+	To     string
+	Reason string
+	Time   time.Time
+	// Состояние после перехода
+	State HealthState
+}
+
+type targetHealth struct {
+	HealthState
+	window []bool
+}
+
+// healthTracker ведёт состояния целей по результатам проверок и сообщает о переходах подписчикам
+type healthTracker struct {
+	cfg HealthConfig
+
+	mu        sync.Mutex
+	states    map[string]*targetHealth
+	listeners []func(HealthTransition)
+}
+
+func newHealthTracker(cfg HealthConfig) *healthTracker {
+	return &healthTracker{cfg: cfg, states: make(map[string]*targetHealth)}
+}
+
+// subscribe добавляет обработчик переходов; вызывается до начала проверок
+func (h *healthTracker) subscribe(fn func(HealthTransition)) {
+	h.listeners = append(h.listeners, fn)
+}
+
+// Observe учитывает результат проверки; отброшенные проверки и сбои во время
+// обслуживания не учитываются
+func (h *healthTracker) Observe(r CheckResult) {
+	if !r.countsAgainstSLA() {
+		return
+	}
+
+	h.mu.Lock()
+	st, ok := h.states[r.Target]
+	if !ok {
+		st = &targetHealth{HealthState: HealthState{Target: r.Target, State: HealthUnknown, Since: r.Timestamp}}
+		h.states[r.Target] = st
+	}
+
+	st.window = append(st.window, r.Success)
+	if len(st.window) > h.cfg.Window {
+		st.window = st.window[1:]
+	}
+	st.SuccessRate = successRate(st.window)
+	if r.Success {
+		st.ConsecutiveSuccesses++
+		st.ConsecutiveFailures = 0
+	} else {
+		st.ConsecutiveFailures++
+		st.ConsecutiveSuccesses = 0
+		st.LastError = r.Error
+	}
+
+	next, reason := h.next(st)
+	if next == st.State {
+		h.mu.Unlock()
+		return
+	}
+	tr := HealthTransition{Target: r.Target, From: st.State, To: next, Reason: reason, Time: time.Now()}
+	st.State, st.Since = next, tr.Time
+	tr.State = st.HealthState
+	h.mu.Unlock()
+
+	for _, fn := range h.listeners {
+		fn(tr)
+	}
+}
+
+// next возвращает состояние цели после очередного результата и причину перехода
+func (h *healthTracker) next(st *targetHealth) (string, string) {
+	c := h.cfg
+	full := len(st.window) >= c.Window
+
+	down := ""
+	switch {
+	case c.DownAfter > 0 && st.ConsecutiveFailures >= c.DownAfter:
+		down = fmt.Sprintf("%d неуспешных проверок подряд", st.ConsecutiveFailures)
+	case c.DownBelow > 0 && full && st.SuccessRate < c.DownBelow:
+		down = fmt.Sprintf("процент успешных ниже %.2f%%", c.DownBelow)
+	}
+	degraded := ""
+	switch {
+	case c.DegradedAfter > 0 && st.ConsecutiveFailures >= c.DegradedAfter:
+		degraded = fmt.Sprintf("%d неуспешных проверок подряд", st.ConsecutiveFailures)
+	case c.DegradedBelow > 0 && full && st.SuccessRate < c.DegradedBelow:
+		degraded = fmt.Sprintf("процент успешных ниже %.2f%%", c.DegradedBelow)
+	}
+	// Для возврата в healthy процент успешных должен быть не ниже порогов
+	rateOK := (c.DownBelow <= 0 || st.SuccessRate >= c.DownBelow) && (c.DegradedBelow <= 0 || st.SuccessRate >= c.DegradedBelow)
+	healthy := st.ConsecutiveSuccesses >= c.HealthyAfter && rateOK
+
+	switch st.State {
+	case HealthUnknown:
+		switch {
+		case down != "":
+			return HealthDown, down
+		case degraded != "":
+			return HealthDegraded, degraded
+		case st.ConsecutiveSuccesses > 0:
+			return HealthHealthy, "первая успешная проверка"
+		}
+	case HealthHealthy:
+		switch {
+		case down != "":
+			return HealthDown, down
+		case degraded != "":
+			return HealthDegraded, degraded
+		}
+	case HealthDegraded:
+		switch {
+		case down != "":
+			return HealthDown, down
+		case healthy:
+			return HealthHealthy, fmt.Sprintf("%d успешных проверок подряд", st.ConsecutiveSuccesses)
+		}
+	case HealthDown:
+		switch {
+		case healthy:
+			return HealthHealthy, fmt.Sprintf("%d успешных проверок подряд", st.ConsecutiveSuccesses)
+		case st.ConsecutiveSuccesses >= c.RecoverAfter:
+			return HealthRecovering, fmt.Sprintf("%d успешных проверок подряд", st.ConsecutiveSuccesses)
+		}
+	case HealthRecovering:
+		switch {
+		case st.ConsecutiveFailures > 0:
+			return HealthDown, "неуспешная проверка во время восстановления"
+		case healthy:
+			return HealthHealthy, fmt.Sprintf("%d успешных проверок подряд", st.ConsecutiveSuccesses)
+		}
+	}
+	return st.State, ""
+}
+
+// snapshot возвращает состояния всех целей с результатами по алфавиту
+func (h *healthTracker) snapshot() []HealthState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]HealthState, 0, len(h.states))
+	for _, st := range h.states {
+		out = append(out, st.HealthState)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Target < out[j].Target })
+	return out
+}
+
+// state возвращает состояние цели; для цели без результатов — unknown
+func (h *healthTracker) state(target string) HealthState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if st, ok := h.states[target]; ok {
+		return st.HealthState
+	}
+	return HealthState{Target: target, State: HealthUnknown}
+}
+
+// forget сбрасывает состояние удалённой цели
+func (h *healthTracker) forget(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.states, name)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// observeSequence передаёт трекеру результаты по строке: "+" — успешная проверка,
+// "-" — неуспешная, "s" — отброшенная, "m" и "M" — неуспешная и успешная во время обслуживания.
+// Возвращает состояния цели после каждого результата.
+func observeSequence(h *healthTracker, seq string) []string {
+	var states []string
+	for i, c := range seq {
+		r := CheckResult{Target: "api", Timestamp: time.Unix(int64(i), 0), Success: c == '+' || c == 'M'}
+		switch c {
+		case '-':
+			r.Error = "status 500"
+		case 's':
+			r.Shed = true
+		case 'm', 'M':
+			r.Maintenance = true
+		}
+		h.Observe(r)
+		states = append(states, h.state("api").State)
+	}
+	return states
+}
+
+func TestHealthTransitions(t *testing.T) {
+	const (
+		H = HealthHealthy
+		G = HealthDegraded
+		D = HealthDown
+		R = HealthRecovering
+	)
+	defaults := defaultHealthConfig()
+
+	tests := []struct {
+		name string
+		cfg  HealthConfig
+		seq  string
+		want []string
+	}{
+		{"первая успешная", defaults, "++", []string{H, H}},
+		{"первая неуспешная", defaults, "-", []string{G}},
+		{"degraded и возврат", defaults, "+-+++", []string{H, G, G, G, H}},
+		{"down подряд", defaults, "+---", []string{H, G, G, D}},
+		{"восстановление", defaults, "---+++", []string{G, G, D, R, R, H}},
+		{"сбой при восстановлении", defaults, "---++-", []string{G, G, D, R, R, D}},
+		{"неуспешные с перерывами не приводят к down", defaults, "+--+--+", []string{H, G, G, G, G, G, G}},
+		{"отброшенные и обслуживание не учитываются", defaults, "+smsm-", []string{H, H, H, H, H, G}},
+		{"успешная во время обслуживания учитывается", defaults, "---mM", []string{G, G, D, D, R}},
+		{
+			name: "процент успешных в полном окне",
+			cfg:  HealthConfig{Window: 4, DegradedBelow: 80, DownBelow: 50, RecoverAfter: 1, HealthyAfter: 2},
+			// Окно заполняется на четвёртой проверке: 75% < 80%, затем 50%, затем 25% < 50%
+			seq:  "+-+-+--",
+			want: []string{H, H, H, G, G, G, D},
+		},
+		{
+			name: "возврат в healthy ждёт процента успешных",
+			cfg:  HealthConfig{Window: 4, DownBelow: 75, RecoverAfter: 1, HealthyAfter: 1},
+			seq:  "+---+++",
+			want: []string{H, H, H, D, R, R, H},
+		},
+		{
+			name: "без порогов degraded цель сразу уходит в down",
+			cfg:  HealthConfig{Window: 10, DownAfter: 2, RecoverAfter: 2, HealthyAfter: 3},
+			seq:  "+--+++",
+			want: []string{H, H, D, D, R, H},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := observeSequence(newHealthTracker(tt.cfg), tt.seq)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: получено %v, ожидалось %v", tt.seq, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthTransitionEvents(t *testing.T) {
+	h := newHealthTracker(defaultHealthConfig())
+	var events []HealthTransition
+	h.subscribe(func(tr HealthTransition) { events = append(events, tr) })
+
+	observeSequence(h, "+---+")
+
+	var got []string
+	for _, tr := range events {
+		got = append(got, tr.From+">"+tr.To+": "+tr.Reason)
+	}
+	want := []string{
+		"unknown>healthy: первая успешная проверка",
+		"healthy>degraded: 1 неуспешных проверок подряд",
+		"degraded>down: 3 неуспешных проверок подряд",
+		"down>recovering: 1 успешных проверок подряд",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("переходы:\n%s\nожидалось:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	down := events[2].State
+	if down.State != HealthDown || down.ConsecutiveFailures != 3 || down.LastError != "status 500" || down.SuccessRate != 25 {
+		t.Errorf("состояние после перехода в down: %+v", down)
+	}
+}
+
+func TestHealthTrackerSnapshotAndForget(t *testing.T) {
+	h := newHealthTracker(defaultHealthConfig())
+	h.Observe(CheckResult{Target: "b", Success: true})
+	h.Observe(CheckResult{Target: "a", Success: false})
+
+	var got []string
+	for _, st := range h.snapshot() {
+		got = append(got, st.Target+"="+st.State)
+	}
+	if want := []string{"a=degraded", "b=healthy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot = %v, ожидалось %v", got, want)
+	}
+
+	h.forget("a")
+	if st := h.state("a"); st.State != HealthUnknown || len(h.snapshot()) != 1 {
+		t.Errorf("после forget: %+v", st)
+	}
+}
+
+func TestHealthConfigFor(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want HealthConfig
+	}{
+		{"по умолчанию", Config{}, defaultHealthConfig()},
+		{
+			name: "из порогов оповещений",
+			cfg:  Config{Alerts: &AlertConfig{ConsecutiveFailures: 5, SuccessThreshold: 90}},
+			want: HealthConfig{Window: 10, DegradedAfter: 1, DownAfter: 5, DownBelow: 90, RecoverAfter: 1, HealthyAfter: 1},
+		},
+		{
+			name: "раздел health дополняется значениями по умолчанию",
+			cfg:  Config{Health: &HealthConfig{DownAfter: 2}, Alerts: &AlertConfig{ConsecutiveFailures: 5}},
+			want: HealthConfig{Window: 10, DownAfter: 2, RecoverAfter: 1, HealthyAfter: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := healthConfigFor(tt.cfg); got != tt.want {
+			t.Errorf("%s: получено %+v, ожидалось %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyHistogram — гистограмма задержек в духе HDR Histogram: значения в микросекундах
+// до histogramSubBuckets записываются точно, большие — в логарифмически-линейные
+// корзины с относительной погрешностью не больше 1/2^(histogramSubBits-1) (0,8%).
+// Память ограничена числом корзин и не растёт с числом проверок.
+type latencyHistogram struct {
+	counts []uint64
+	total  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+const (
+	histogramSubBits    = 8
+	histogramSubBuckets = 1 << histogramSubBits
+	histogramHalf       = histogramSubBuckets / 2
+)
+
+// histogramIndex возвращает номер корзины значения v в микросекундах
+func histogramIndex(v uint64) int {
+	if v < histogramSubBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - histogramSubBits
+	mantissa := v >> shift
+	return histogramSubBuckets + (shift-1)*histogramHalf + int(mantissa-histogramHalf)
+}
+
+// histogramBounds возвращает наименьшее и наибольшее значения корзины в микросекундах
+func histogramBounds(i int) (lo, hi uint64) {
+	if i < histogramSubBuckets {
+		return uint64(i), uint64(i)
+	}
+	shift := (i-histogramSubBuckets)/histogramHalf + 1
+	mantissa := uint64((i-histogramSubBuckets)%histogramHalf + histogramHalf)
+	return mantissa << shift, (mantissa+1)<<shift - 1
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := histogramIndex(uint64(d / time.Microsecond))
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.total == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.total++
+	h.sum += d
+}
+
+// merge добавляет значения другой гистограммы
+func (h *latencyHistogram) merge(o *latencyHistogram) {
+	if o.total == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, len(o.counts)-len(h.counts))...)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.total == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	h.max = max(h.max, o.max)
+	h.total += o.total
+	h.sum += o.sum
+}
+
+func (h *latencyHistogram) mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.total)
+}
+
+// quantile возвращает p-й процентиль (метод nearest-rank) — верхнюю границу корзины,
+// в которую он попадает, в пределах наблюдавшихся min и max
+func (h *latencyHistogram) quantile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.total)))
+	rank = min(max(rank, 1), h.total)
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			_, hi := histogramBounds(i)
+			return min(max(time.Duration(hi)*time.Microsecond, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// countAtMost возвращает число значений не больше bound (с точностью до корзины)
+func (h *latencyHistogram) countAtMost(bound time.Duration) uint64 {
+	limit := uint64(bound / time.Microsecond)
+	var n uint64
+	for i, c := range h.counts {
+		if lo, _ := histogramBounds(i); lo > limit {
+			break
+		}
+		n += c
+	}
+	return n
+}
+
+// HistogramBucket — непустая корзина гистограммы: значения до LE включительно, нс
+type HistogramBucket struct {
+	LE    time.Duration `json:"le"`
+	Count uint64        `json:"count"`
+}
+
+// HistogramSnapshot — гистограмма задержек цели в отчёте JSON
+type HistogramSnapshot struct {
+	Count   uint64            `json:"count"`
+	Sum     time.Duration     `json:"sum"`
+	Min     time.Duration     `json:"min"`
+	Max     time.Duration     `json:"max"`
+	P50     time.Duration     `json:"p50"`
+	P90     time.Duration     `json:"p90"`
+	P95     time.Duration     `json:"p95"`
+	P99     time.Duration     `json:"p99"`
+	P999    time.Duration     `json:"p999"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+func (h *latencyHistogram) snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Count: h.total, Sum: h.sum, Min: h.min, Max: h.max,
+		P50: h.quantile(50), P90: h.quantile(90), P95: h.quantile(95), P99: h.quantile(99), P999: h.quantile(99.9),
+	}
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		_, hi := histogramBounds(i)
+		s.Buckets = append(s.Buckets, HistogramBucket{LE: time.Duration(hi) * time.Microsecond, Count: c})
+	}
+	return s
+}
+
+// targetHistograms — гистограммы задержек целей за время работы
+type targetHistograms struct {
+	mu       sync.Mutex
+	byTarget map[string]*latencyHistogram
+}
+
+func newTargetHistograms() *targetHistograms {
+	return &targetHistograms{byTarget: make(map[string]*latencyHistogram)}
+}
+
+// Observe записывает задержку успешной проверки; как и в computeStats, неуспешные,
+// отброшенные и прогревочные не учитываются
+func (t *targetHistograms) Observe(r CheckResult) {
+	if !r.Success || r.Shed || r.Warmup {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.byTarget[r.Target]
+	if !ok {
+		h = &latencyHistogram{}
+		t.byTarget[r.Target] = h
+	}
+	h.record(r.Latency)
+}
+
+// each вызывает fn для гистограмм целей по порядку имён
+func (t *targetHistograms) each(fn func(name string, h *latencyHistogram)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.byTarget))
+	for name := range t.byTarget {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn(name, t.byTarget[name])
+	}
+}
+
+func (t *targetHistograms) snapshot() map[string]HistogramSnapshot {
+	out := make(map[string]HistogramSnapshot)
+	t.each(func(name string, h *latencyHistogram) {
+		out[name] = h.snapshot()
+	})
+	return out
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHistogramIndex(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want int
+	}{
+		{0, 0},
+		{1, 1},
+		{histogramSubBuckets - 1, histogramSubBuckets - 1},
+		{histogramSubBuckets, histogramSubBuckets},
+		{histogramSubBuckets + 1, histogramSubBuckets},
+		{histogramSubBuckets + 2, histogramSubBuckets + 1},
+		{2*histogramSubBuckets - 1, histogramSubBuckets + histogramHalf - 1},
+		{2 * histogramSubBuckets, histogramSubBuckets + histogramHalf},
+		{2*histogramSubBuckets + 3, histogramSubBuckets + histogramHalf},
+		{2*histogramSubBuckets + 4, histogramSubBuckets + histogramHalf + 1},
+	}
+	for _, tt := range tests {
+		if got := histogramIndex(tt.v); got != tt.want {
+			t.Errorf("histogramIndex(%d) = %d, ожидалось %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramBounds(t *testing.T) {
+	// Корзины идут подряд без пропусков и перекрытий вплоть до часа в микросекундах
+	last := histogramIndex(uint64(time.Hour / time.Microsecond))
+	var next uint64
+	for i := 0; i <= last; i++ {
+		lo, hi := histogramBounds(i)
+		if lo != next || hi < lo {
+			t.Fatalf("корзина %d: [%d, %d], ожидалось начало %d", i, lo, hi, next)
+		}
+		if histogramIndex(lo) != i || histogramIndex(hi) != i {
+			t.Fatalf("корзина %d: границы [%d, %d] попадают в %d и %d", i, lo, hi, histogramIndex(lo), histogramIndex(hi))
+		}
+		// Относительная ширина корзины не больше заявленной погрешности
+		if lo >= histogramSubBuckets && float64(hi-lo+1)/float64(lo) > 1.0/histogramHalf {
+			t.Fatalf("корзина %d: [%d, %d] шире 1/%d", i, lo, hi, histogramHalf)
+		}
+		next = hi + 1
+	}
+}
+
+func TestHistogramIndexBoundsRoundTrip(t *testing.T) {
+	values := []uint64{0, 7, 255, 256, 511, 512, 1000, 65535, 65536, 1e6, 1e9, math.MaxUint32, 1 << 50}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		values = append(values, uint64(rng.Int63n(1<<40)))
+	}
+	for _, v := range values {
+		lo, hi := histogramBounds(histogramIndex(v))
+		if v < lo || v > hi {
+			t.Errorf("%d не попадает в границы своей корзины [%d, %d]", v, lo, hi)
+		}
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	var h latencyHistogram
+	var exact []time.Duration
+	for i := 0; i < 10000; i++ {
+		// Логнормальное распределение с медианой около 50 мс
+		d := time.Duration(math.Exp(rng.NormFloat64()*0.8+math.Log(50e3))) * time.Microsecond
+		h.record(d)
+		exact = append(exact, d)
+	}
+	sort.Slice(exact, func(i, j int) bool { return exact[i] < exact[j] })
+
+	for _, p := range []float64{1, 50, 90, 95, 99, 99.9, 100} {
+		rank := int(math.Ceil(p / 100 * float64(len(exact))))
+		want := exact[rank-1]
+		got := h.quantile(p)
+		if got < want || float64(got-want) > float64(want)/histogramHalf+float64(time.Microsecond) {
+			t.Errorf("p%v = %v, точное значение %v", p, got, want)
+		}
+	}
+
+	if h.quantile(100) != exact[len(exact)-1] || h.quantile(0) < exact[0] {
+		t.Errorf("крайние процентили %v и %v вне [%v, %v]", h.quantile(0), h.quantile(100), exact[0], exact[len(exact)-1])
+	}
+	if h.min != exact[0] || h.max != exact[len(exact)-1] || h.total != uint64(len(exact)) {
+		t.Errorf("min %v, max %v, total %d", h.min, h.max, h.total)
+	}
+}
+
+func TestHistogramSmallValuesExact(t *testing.T) {
+	var h latencyHistogram
+	for _, us := range []int{10, 20, 30, 40} {
+		h.record(time.Duration(us) * time.Microsecond)
+	}
+	h.record(-time.Second)
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{20, 0},
+		{40, 10 * time.Microsecond},
+		{50, 20 * time.Microsecond},
+		{100, 40 * time.Microsecond},
+	}
+	for _, tt := range tests {
+		if got := h.quantile(tt.p); got != tt.want {
+			t.Errorf("p%v = %v, ожидалось %v", tt.p, got, tt.want)
+		}
+	}
+	if got := h.countAtMost(25 * time.Microsecond); got != 3 {
+		t.Errorf("countAtMost(25µs) = %d, ожидалось 3", got)
+	}
+	if got := h.mean(); got != 20*time.Microsecond {
+		t.Errorf("mean = %v", got)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	var a, b, all latencyHistogram
+	for i := 1; i <= 200; i++ {
+		d := time.Duration(i*i) * time.Millisecond
+		all.record(d)
+		if i%2 == 0 {
+			a.record(d)
+		} else {
+			b.record(d)
+		}
+	}
+	a.merge(&b)
+	a.merge(&latencyHistogram{})
+
+	if !reflect.DeepEqual(a.snapshot(), all.snapshot()) {
+		t.Errorf("объединение\n%+v\nотличается от общей гистограммы\n%+v", a.snapshot(), all.snapshot())
+	}
+
+	var empty latencyHistogram
+	if s := empty.snapshot(); s.Count != 0 || s.P99 != 0 || s.Buckets != nil {
+		t.Errorf("пустая гистограмма: %+v", s)
+	}
+}
+
+func TestTargetHistogramsObserve(t *testing.T) {
+	h := newTargetHistograms()
+	h.Observe(CheckResult{Target: "a", Success: true, Latency: time.Millisecond})
+	h.Observe(CheckResult{Target: "a", Success: false, Latency: time.Second})
+	h.Observe(CheckResult{Target: "a", Success: true, Shed: true, Latency: time.Second})
+	h.Observe(CheckResult{Target: "a", Success: true, Warmup: true, Latency: time.Second})
+	h.Observe(CheckResult{Target: "b", Success: false})
+
+	s := h.snapshot()
+	if len(s) != 1 || s["a"].Count != 1 || s["a"].Max != time.Millisecond {
+		t.Errorf("снимок %+v", s)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runHistory реализует подкоманду history: тренды доступности и задержек из SQLite
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dbPath := fs.String("db", "results.db", "Путь к базе результатов")
+	target := fs.String("target", "", "Имя цели (по умолчанию все цели)")
+	since := fs.Duration("since", 24*time.Hour, "Период от текущего момента, если не заданы -from/-to")
+	fromStr := fs.String("from", "", "Начало периода (RFC3339)")
+	toStr := fs.String("to", "", "Конец периода (RFC3339)")
+	bucket := fs.Duration("bucket", time.Hour, "Шаг группировки тренда")
+	fs.Parse(args)
+
+	from, to, err := parsePeriod(*since, *fromStr, *toStr)
+	if err != nil {
+		return err
+	}
+	if *bucket <= 0 {
+		return fmt.Errorf("-bucket должен быть положительным")
+	}
+
+	store, err := openStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	results, err := store.Query(*target, from, to)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println("За выбранный период результатов нет.")
+		return nil
+	}
+
+	missed, err := store.MissedWindows(*target, from, to)
+	if err != nil {
+		return err
+	}
+
+	names, groups := groupByTarget(results)
+	for _, name := range names {
+		total := computeStats(groups[name])
+		fmt.Printf("\n%s: проверок %d, доступность %.2f%%, средняя задержка %v, p95 %v\n",
+			name, total.Checks, total.SuccessRate(), total.Avg.Round(time.Millisecond), total.P95.Round(time.Millisecond))
+		if s := computeStreaks(name, groups[name]); s.Failures > 0 {
+			fmt.Printf("  сбоев %d, подряд максимум %d, нестабильность %.2f — %s\n",
+				s.Failures, s.LongestFailures, s.Flakiness, s.verdict())
+		}
+		if d := missedWithin(missed, name, from, to); d > 0 {
+			fmt.Printf("  не проверялась (утилита не работала): %v\n", d.Round(time.Second))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  период\tпроверок\tдоступность\tсредняя\tp95")
+		for _, b := range splitBuckets(groups[name], from, *bucket) {
+			st := computeStats(b.results)
+			if st.Checks == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "  %s\t%d\t%.2f%%\t%v\t%v\n",
+				b.start.Format("2006-01-02 15:04"), st.Checks, st.SuccessRate(),
+				st.Avg.Round(time.Millisecond), st.P95.Round(time.Millisecond))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := printEndpoints(os.Stdout, groups[name]); err != nil {
+			return err
+		}
+	}
+
+	if len(missed) > 0 {
+		fmt.Println("\nПропущенные периоды:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "цель\tначало\tконец\tдлительность")
+		for _, m := range missed {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\n", m.Target, m.Start.Format("2006-01-02 15:04:05"),
+				m.End.Format("2006-01-02 15:04:05"), m.duration().Round(time.Second))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	annotations, err := store.Annotations(*target, from, to)
+	if err != nil {
+		return err
+	}
+	if len(annotations) > 0 {
+		fmt.Println("\nАннотации:")
+		return printAnnotations(os.Stdout, annotations)
+	}
+
+	return nil
+}
+
+type resultBucket struct {
+	start   time.Time
+	results []CheckResult
+}
+
+// splitBuckets делит отсортированные по времени результаты на интервалы длиной step
+func splitBuckets(results []CheckResult, from time.Time, step time.Duration) []resultBucket {
+	var buckets []resultBucket
+	for _, r := range results {
+		start := from.Add(r.Timestamp.Sub(from) / step * step)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].start.Equal(start) {
+			buckets = append(buckets, resultBucket{start: start})
+		}
+		last := &buckets[len(buckets)-1]
+		last.results = append(last.results, r)
+	}
+	return buckets
+}
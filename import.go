@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runImport обрабатывает подкоманду import: генерирует конфигурацию целей из внешних описаний API
+//
+//	import openapi [-base-url url] [-o file] spec.yaml
+//	import postman [-o file] collection.json
+//	import curl [-o file] commands.txt|-
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("использование: import openapi|postman|curl [флаги] <файл>")
+	}
+
+	format := args[0]
+	fs := flag.NewFlagSet("import "+format, flag.ExitOnError)
+	output := fs.String("o", "", "Файл для сохранения конфигурации (по умолчанию stdout)")
+	var baseURL, methods *string
+	if format == "openapi" {
+		baseURL = fs.String("base-url", "", "Базовый URL API вместо указанного в спецификации")
+		methods = fs.String("methods", defaultImportMethods, "Методы операций, для которых создаются проверки, через запятую; post, put, delete и другие изменяют данные API")
+	}
+	fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("использование: import %s [флаги] <файл>", format)
+	}
+	source := fs.Arg(0)
+
+	var targets []Target
+	var err error
+	switch format {
+	case "openapi":
+		var allowed map[string]bool
+		if allowed, err = parseImportMethods(*methods); err != nil {
+			return fmt.Errorf("-methods: %w", err)
+		}
+		var spec *openAPISpec
+		if spec, err = loadOpenAPISpec(source); err == nil {
+			targets, err = spec.targets(*baseURL, allowed)
+		}
+	case "postman":
+		var c postmanCollection
+		if c, err = loadPostmanCollection(source); err == nil {
+			targets, err = c.targets()
+		}
+	case "curl":
+		// «-» читает команды со стандартного ввода, чтобы их можно было вставить
+		var data []byte
+		if source == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(source)
+		}
+		if err == nil {
+			targets, err = parseCurlCommands(string(data))
+		}
+	default:
+		return fmt.Errorf("неизвестный формат импорта %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return writeImportedTargets(*output, source, targets)
+}
+
+// writeImportedTargets проверяет сгенерированные цели и сохраняет их в формате конфигурации
+func writeImportedTargets(path, source string, targets []Target) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("%s: не найдено ни одного запроса", source)
+	}
+	// Имена целей должны быть уникальны
+	seen := make(map[string]int)
+	for i := range targets {
+		name := targets[i].Name
+		if seen[name]++; seen[name] > 1 {
+			targets[i].Name = fmt.Sprintf("%s (%d)", name, seen[name])
+		}
+	}
+	for _, t := range targets {
+		if err := t.normalize(); err != nil {
+			return fmt.Errorf("сгенерирована некорректная цель: %w", err)
+		}
+	}
+
+	data, err := yaml.Marshal(struct {
+		Targets []Target `yaml:"targets"`
+	}{targets})
+	if err != nil {
+		return err
+	}
+	data = append([]byte(fmt.Sprintf("# Сгенерировано из %s\n", source)), data...)
+
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	slog.Info("Конфигурация сохранена", "targets", len(targets), "path", path)
+	return nil
+}
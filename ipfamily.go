@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Значения ip_version цели
+const (
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+	IPVersionDual = "dual" // цель проверяется по IPv4 и по IPv6 отдельно
+)
+
+// expandDualStack заменяет цель с ip_version: dual двумя целями — по IPv4 и по IPv6
+func (t Target) expandDualStack() []Target {
+	if t.IPVersion != IPVersionDual {
+		return []Target{t}
+	}
+	name := t.Name
+	if name == "" {
+		name = t.URL
+	}
+
+	out := make([]Target, 0, 2)
+	for _, v := range []string{IPVersion4, IPVersion6} {
+		ft := t
+		ft.Name = fmt.Sprintf("%s [ipv%s]", name, v)
+		ft.IPVersion = v
+		ft.dualStackName = name
+		out = append(out, ft)
+	}
+	return out
+}
+
+// familyNetwork уточняет сеть соединения (tcp, udp) по ip_version
+func familyNetwork(network, ipVersion string) string {
+	switch ipVersion {
+	case IPVersion4:
+		return network + "4"
+	case IPVersion6:
+		return network + "6"
+	}
+	return network
+}
+
+// ipNetwork возвращает сеть для net.Resolver.LookupIP
+func (t Target) ipNetwork() string {
+	switch t.IPVersion {
+	case IPVersion4:
+		return "ip4"
+	case IPVersion6:
+		return "ip6"
+	}
+	return "ip"
+}
+
+// dualStackComparison — результаты одной цели по IPv4 и IPv6
+type dualStackComparison struct {
+	Name       string
+	IPv4, IPv6 latencyStats
+	// Вывод о неравенстве семейств адресов, пусто — различий нет
+	Problem string
+}
+
+// compareDualStack сопоставляет результаты целей, проверяемых по обоим семействам адресов
+func compareDualStack(targets []Target, results []CheckResult) []dualStackComparison {
+	_, groups := groupByTarget(results)
+
+	var out []dualStackComparison
+	index := make(map[string]int)
+	for _, t := range targets {
+		if t.dualStackName == "" {
+			continue
+		}
+		i, ok := index[t.dualStackName]
+		if !ok {
+			i = len(out)
+			index[t.dualStackName] = i
+			out = append(out, dualStackComparison{Name: t.dualStackName})
+		}
+		st := computeStats(groups[t.Name])
+		if t.IPVersion == IPVersion4 {
+			out[i].IPv4 = st
+		} else {
+			out[i].IPv6 = st
+		}
+	}
+
+	for i := range out {
+		c := &out[i]
+		v4, v6 := c.IPv4.SuccessRate(), c.IPv6.SuccessRate()
+		switch {
+		case c.IPv4.Checks == 0 || c.IPv6.Checks == 0:
+		case v6 == 0 && v4 > 0:
+			c.Problem = "IPv6 не работает"
+		case v4 == 0 && v6 > 0:
+			c.Problem = "IPv4 не работает"
+		case v4-v6 > defaultMaxSuccessDrop:
+			c.Problem = fmt.Sprintf("по IPv6 успешных меньше на %.2f п.п.", v4-v6)
+		case v6-v4 > defaultMaxSuccessDrop:
+			c.Problem = fmt.Sprintf("по IPv4 успешных меньше на %.2f п.п.", v6-v4)
+		}
+	}
+	return out
+}
+
+// printDualStack выводит сравнение семейств адресов
+func printDualStack(out io.Writer, comparisons []dualStackComparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nСравнение IPv4 и IPv6:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tIPv4 успешных\tIPv4 p95\tIPv6 успешных\tIPv6 p95\tвывод")
+	for _, c := range comparisons {
+		problem := c.Problem
+		if problem == "" {
+			problem = "ок"
+		}
+		fmt.Fprintf(w, "%s\t%.2f%%\t%v\t%.2f%%\t%v\t%s\n", c.Name,
+			c.IPv4.SuccessRate(), c.IPv4.P95.Round(time.Millisecond),
+			c.IPv6.SuccessRate(), c.IPv6.P95.Round(time.Millisecond), problem)
+	}
+	w.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	liveRecentSize   = 100 // последних результатов на цель
+	liveFailuresSize = 20  // последних сбоев по всем целям
+)
+
+// liveStore хранит в памяти последние результаты по каждой цели для дашборда
+type liveStore struct {
+	mu       sync.RWMutex
+	order    []string
+	recent   map[string][]CheckResult
+	failures []CheckResult
+}
+
+func newLiveStore() *liveStore {
+	return &liveStore{recent: make(map[string][]CheckResult)}
+}
+
+func (l *liveStore) Observe(r CheckResult) {
+	if r.Shed {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.recent[r.Target]; !ok {
+		l.order = append(l.order, r.Target)
+	}
+	recent := append(l.recent[r.Target], r)
+	if len(recent) > liveRecentSize {
+		recent = recent[len(recent)-liveRecentSize:]
+	}
+	l.recent[r.Target] = recent
+
+	if !r.Success {
+		l.failures = append(l.failures, r)
+		if len(l.failures) > liveFailuresSize {
+			l.failures = l.failures[len(l.failures)-liveFailuresSize:]
+		}
+	}
+}
+
+// forget удаляет результаты цели, например после её удаления через API
+func (l *liveStore) forget(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.recent, name)
+	for i, n := range l.order {
+		if n == name {
+			l.order = append(l.order[:i], l.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// seed заполняет хранилище историей из базы, чтобы дашборд не пустовал после перезапуска
+func (l *liveStore) seed(store *ResultStore, since time.Duration) error {
+	now := time.Now()
+	results, err := store.Query("", now.Add(-since), now)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		l.Observe(r)
+	}
+	return nil
+}
+
+type targetSnapshot struct {
+	Name   string        `json:"name"`
+	Last   CheckResult   `json:"last"`
+	Stats  latencyStats  `json:"stats"`
+	Recent []CheckResult `json:"recent"`
+}
+
+type liveSnapshot struct {
+	Targets  []targetSnapshot `json:"targets"`
+	Failures []CheckResult    `json:"failures"` // от новых к старым
+}
+
+func (l *liveStore) Snapshot() liveSnapshot {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var snap liveSnapshot
+	for _, name := range l.order {
+		recent := append([]CheckResult(nil), l.recent[name]...)
+		snap.Targets = append(snap.Targets, targetSnapshot{
+			Name:   name,
+			Last:   recent[len(recent)-1],
+			Stats:  computeStats(recent),
+			Recent: recent,
+		})
+	}
+	for i := len(l.failures) - 1; i >= 0; i-- {
+		snap.Failures = append(snap.Failures, l.failures[i])
+	}
+	return snap
+}
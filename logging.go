@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Форматы журнала
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// logOutput — назначение журнала; на время замера bench журнал отключается,
+// а в терминале его строки выводятся через строку прогресса
+var logOutput = &switchWriter{w: os.Stderr}
+
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *switchWriter) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(b)
+}
+
+// set подменяет назначение и возвращает прежнее
+func (s *switchWriter) set(w io.Writer) io.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.w
+	s.w = w
+	return prev
+}
+
+// setupLogging настраивает журнал по умолчанию: уровень debug, info, warn или error
+// и формат text или json. Пакет log тоже пишет через него на уровне info.
+func setupLogging(level, format string) error {
+	var lv slog.Level
+	if level == "" {
+		level = "info"
+	}
+	if err := lv.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("неизвестный уровень журнала %q (debug, info, warn или error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lv}
+	var h slog.Handler
+	switch format {
+	case "", LogFormatText:
+		h = slog.NewTextHandler(logOutput, opts)
+	case LogFormatJSON:
+		h = slog.NewJSONHandler(logOutput, opts)
+	default:
+		return fmt.Errorf("неизвестный формат журнала %q (text или json)", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// fatal записывает ошибку в журнал и завершает программу
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Части имён заголовков, значения которых не выводятся в журнал
+var sensitiveHeaderParts = []string{"authorization", "cookie", "token", "secret", "password", "api-key", "apikey", "signature"}
+
+// redactHeaders возвращает заголовки для журнала со скрытыми значениями секретов
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if isSensitiveName(name) {
+			value = "[скрыто]"
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// redactURL возвращает адрес без пароля и значений параметров запроса: в них часто
+// передаются ключи API и токены
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	c := *u
+	params := strings.Split(c.RawQuery, "&")
+	for i, p := range params {
+		if name, _, ok := strings.Cut(p, "="); ok {
+			params[i] = name + "=REDACTED"
+		}
+	}
+	c.RawQuery = strings.Join(params, "&")
+	return c.Redacted()
+}
+
+// isSensitiveName сообщает, что по имени заголовка или флага его значение — секрет
+func isSensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range sensitiveHeaderParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func debugEnabled() bool {
+	return slog.Default().Enabled(context.Background(), slog.LevelDebug)
+}
+
+// logRequest записывает запрос проверки на уровне debug
+func logRequest(target Target, req *http.Request) {
+	if !debugEnabled() {
+		return
+	}
+	slog.Debug("Запрос", "target", target.Name, "method", req.Method, "url", redactURL(req.URL),
+		"headers", redactHeaders(req.Header))
+}
+
+// logResponse записывает ответ проверки на уровне debug
+func logResponse(target Target, resp *http.Response, latency time.Duration) {
+	if !debugEnabled() {
+		return
+	}
+	slog.Debug("Ответ", "target", target.Name, "status", resp.StatusCode, "protocol", resp.Proto,
+		"latency", latency, "headers", redactHeaders(resp.Header))
+}
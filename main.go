@@ -1,142 +1,591 @@
-package main
-
-import (
-	"context"
-	"encoding/json"
-	"flag"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
-)
-
-type CheckResult struct {
-	Success bool `json:"success"`
-	// дополнительные поля, если нужно
-}
-
-type TestResult struct {
-	Results []CheckResult `json:"results"`
-	// дополнительные поля, если нужно
-}
-
-func performCheck(ctx context.Context, wg *sync.WaitGroup, results chan<- CheckResult) {
-	defer wg.Done()
-
-	// Здесь выполняется одна проверка
-	// Пример:
-	resp, err := http.Get("https://thecatapi.com")
-	if err != nil {
-		log.Println("Ошибка при выполнении запроса:", err)
-		results <- CheckResult{Success: false}
-		return
-	}
-
-	// Проверяем успешность запроса и выполняем дополнительные проверки, если нужно
-	success := resp.StatusCode == http.StatusOK
-
-	select {
-	case <-ctx.Done(): // Проверка на сигнал остановки
-		log.Println("Получен сигнал остановки. Прерывание проверки.")
-		resp.Body.Close()
-		return
-	default:
-		result := CheckResult{Success: success}
-		results <- result
-	}
-}
-
-func runTests(ctx context.Context, interval time.Duration, numChecks int) TestResult {
-	// Создаем каналы для результатов и сигналов для остановки
-	results := make(chan CheckResult, numChecks)
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := sync.WaitGroup{}
-
-	for i := 0; i < numChecks; i++ {
-		wg.Add(1)
-		go performCheck(ctx, &wg, results)
-
-		select {
-		case <-ctx.Done(): // Проверка на сигнал остановки
-			log.Println("Получен сигнал остановки. Прерывание тестов.")
-			wg.Wait()
-			close(results)
-			return collectResults(results)
-		default:
-			time.Sleep(interval)
-		}
-	}
-
-	wg.Wait()
-	close(results)
-
-	return collectResults(results)
-}
-
-func collectResults(results <-chan CheckResult) TestResult {
-	testResult := TestResult{
-		Results: make([]CheckResult, 0),
-	}
-
-	for result := range results {
-		testResult.Results = append(testResult.Results, result)
-	}
-
-	return testResult
-}
-
-func main() {
-	interval := flag.Duration("t", 3*time.Second, "Интервал между запусками проверок")
-	numChecks := flag.Int("n", 3, "Количество проверок")
-	flag.Parse()
-
-	log.Println("Запуск утилиты для измерения производительности и оценки отказоустойчивости API...")
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	go func() {
-		stop := make(chan os.Signal, 1)
-		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-		<-stop
-		log.Println("Получен сигнал остановки. Сохранение результатов в файл...")
-
-		cancel() // Отменяем контекст после получения сигнала
-	}()
-
-	testResult := runTests(ctx, *interval, *numChecks)
-
-	// Выводим и анализируем результаты
-	successfulCount := 0
-	for _, result := range testResult.Results {
-		if result.Success {
-			successfulCount++
-		}
-	}
-
-	successfulPercentage := float64(successfulCount) / float64(len(testResult.Results)) * 100
-	fmt.Printf("Процент успешных запросов: %.2f%%\n", successfulPercentage)
-
-	// Сохраняем результаты в файл
-	jsonData, err := json.MarshalIndent(testResult, "", "    ")
-	if err != nil {
-		log.Println("Ошибка при сериализации результатов в JSON:", err)
-		return
-	}
-
-	err = ioutil.WriteFile("test_results.json", jsonData, 0644)
-	if err != nil {
-		log.Println("Ошибка при сохранении результатов в файл:", err)
-		return
-	}
-
-	log.Println("Результаты успешно сохранены в файл test_results.json.")
-	log.Println("Работа программы завершена.")
-}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	// Журнал подкоманд настраивается переменными окружения, основного режима — ещё и флагами
+	if err := setupLogging(os.Getenv("APICHECKER_LOG_LEVEL"), os.Getenv("APICHECKER_LOG_FORMAT")); err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка:", err)
+		os.Exit(2)
+	}
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			// Явная форма запуска проверок, флаги те же
+			args = args[1:]
+		case "history":
+			if err := runHistory(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "report":
+			if err := runReport(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "config":
+			if err := runConfigCommand(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "import":
+			if err := runImport(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "canary":
+			rollback, err := runCanary(os.Args[2:])
+			if err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			if rollback {
+				os.Exit(1)
+			}
+			return
+		case "replay":
+			failed, err := runReplay(os.Args[2:])
+			if err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return
+		case "bench":
+			if err := runBench(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "annotate":
+			if err := runAnnotate(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "probe":
+			failed, err := runProbe(os.Args[2:])
+			if err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return
+		case "agent":
+			if err := runAgent(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "chaos":
+			if err := runChaos(os.Args[2:]); err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			return
+		case "version":
+			printVersion()
+			return
+		case "compare":
+			regressed, err := runCompare(os.Args[2:])
+			if err != nil {
+				fatal("Ошибка", "error", err)
+			}
+			if regressed {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	interval := flag.Duration("t", 3*time.Second, "Интервал между запусками проверок")
+	numChecks := flag.Int("n", 3, "Количество проверок")
+	configPath := flag.String("config", "", "Путь к YAML-файлу с описанием целей")
+	fromStdin := flag.Bool("stdin", false, "Читать список URL из стандартного ввода, по одному в строке; настройки целей берутся из defaults конфигурации")
+	daemon := flag.Bool("daemon", false, "Режим мониторинга: проверки выполняются до остановки")
+	concurrency := flag.Int("concurrency", 0, "Максимум одновременных проверок (0 — без ограничения)")
+	dbPath := flag.String("db", "", "Путь к базе SQLite для хранения истории результатов")
+	startupCheck := flag.String("startup-check", StartupCheckOff, "Стартовая проверка целей в режиме мониторинга: warn или fail")
+	baselinePath := flag.String("baseline", "", "Файл результатов базового запуска для поиска регрессий")
+	maxP95 := flag.Float64("max-p95-regression", defaultMaxP95Regression, "Допустимый рост p95 задержки относительно базового запуска, %")
+	maxDrop := flag.Float64("max-success-drop", defaultMaxSuccessDrop, "Допустимое падение процента успешных относительно базового запуска, п.п.")
+	httpAddr := flag.String("http", "", "Адрес веб-дашборда в режиме мониторинга, например :8080")
+	apiToken := flag.String("api-token", "", "Токен API управления; без него API доступен только на локальном адресе")
+	region := flag.String("region", "", "Регион, из которого выполняются проверки; в режиме координатора агентов — регион самого координатора")
+	recordMissed := flag.Bool("record-missed", false, "При запуске мониторинга с -db записывать периоды без проверок, пока утилита не работала")
+	network := networkFlags{Resolve: resolveFlags{}}
+	flag.StringVar(&network.Proxy, "proxy", "", "Прокси для целей без proxy в конфигурации (http://, https://, socks5://; direct — без прокси из окружения)")
+	flag.StringVar(&network.DNSServer, "dns-server", "", "DNS-сервер для целей без dns_server в конфигурации, например 1.1.1.1:53")
+	flag.BoolVar(&network.IPv4, "4", false, "Соединяться только по IPv4")
+	flag.BoolVar(&network.IPv6, "6", false, "Соединяться только по IPv6")
+	flag.BoolVar(&network.DualStack, "dual-stack", false, "Проверять каждую цель по IPv4 и по IPv6 отдельно и сравнить результаты")
+	flag.StringVar(&network.HTTPVersion, "http-version", "", "Версия HTTP для целей без http_version в конфигурации: 1.1, 2 или compare — сравнить HTTP/1.1 и HTTP/2")
+	flag.StringVar(&network.Connection, "connection", "", "Соединения для целей без connection в конфигурации: warm, cold или cold-dns")
+	flag.Var(network.Resolve, "resolve", "Статический адрес хоста host:port:addr (порт можно опустить: host::addr), можно повторять")
+	statsdAddr := flag.String("statsd", "", "Отправлять метрики проверок в StatsD по указанному адресу, например 127.0.0.1:8125")
+	influxURL := flag.String("influx", "", "Отправлять результаты в InfluxDB по адресу записи, например http://localhost:8086/write?db=checks")
+	graphiteAddr := flag.String("graphite", "", "Отправлять метрики проверок в Graphite (Carbon) по указанному адресу, например 127.0.0.1:2003")
+	artifactsDir := flag.String("artifacts", "", "Каталог, в который сохраняются запрос и ответ неуспешных проверок (в подкаталог запуска)")
+	artifactBody := flag.Int("artifacts-body", defaultArtifactBody, "Сколько байт тела запроса и ответа сохранять в артефакт")
+	otlpEndpoint := flag.String("otlp", "", "Отправлять трассы проверок по OTLP/HTTP, например http://localhost:4318/v1/traces")
+	var warmup warmupFlag
+	flag.Var(&warmup, "warmup", "Прогрев перед проверками: число проверок каждой цели или длительность; результаты прогрева не учитываются в статистике")
+	grace := flag.Duration("grace", 0, "Время на завершение текущих проверок после сигнала остановки")
+	quiet := flag.Bool("quiet", false, "Не выводить прогресс и журнал во время проверок, только итоговый отчёт")
+	noColor := flag.Bool("no-color", false, "Не использовать цвета в выводе")
+	logLevel := flag.String("log-level", os.Getenv("APICHECKER_LOG_LEVEL"), "Уровень журнала: debug, info (по умолчанию), warn или error")
+	logFormat := flag.String("log-format", os.Getenv("APICHECKER_LOG_FORMAT"), "Формат журнала: text (по умолчанию) или json")
+	verbose := flag.Bool("v", false, "Подробный журнал: уровень debug, включая запросы и ответы проверок")
+	tags := tagFlags{}
+	rotate := flag.Duration("rotate", 0, "Длительный тест: каждые N сырые результаты записываются в results-ГГГГММДД-ЧЧММ.json и не накапливаются в памяти")
+	flag.Var(tags, "tag", "Метка запуска ключ=значение, записывается в результаты; можно повторять")
+	flag.CommandLine.Parse(args)
+	run := newRunMetadata(args, tags)
+
+	if *verbose {
+		*logLevel = "debug"
+	}
+	if err := setupLogging(*logLevel, *logFormat); err != nil {
+		fatal("Ошибка в настройках журнала", "error", err)
+	}
+
+	// Код выхода выставляется при найденной регрессии; os.Exit вызывается после остальных defer
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	var baseline *TestResult
+	if *baselinePath != "" {
+		b, err := loadTestResult(*baselinePath)
+		if err != nil {
+			fatal("Ошибка при загрузке базового запуска", "error", err)
+		}
+		baseline = &b
+	}
+
+	cfg := Config{Targets: []Target{defaultTarget()}}
+	if *configPath != "" {
+		var err error
+		cfg, err = readConfig(*configPath, !*fromStdin)
+		if err != nil {
+			fatal("Ошибка при загрузке конфигурации", "error", err)
+		}
+	}
+	if *fromStdin {
+		var err error
+		cfg.Targets, err = readTargetList(os.Stdin, cfg.Defaults)
+		if err != nil {
+			fatal("Ошибка при чтении списка URL", "error", err)
+		}
+	}
+	if err := network.apply(&cfg); err != nil {
+		fatal("Ошибка в сетевых настройках", "error", err)
+	}
+	// Конфигурация из файла до флагов приёмников и трассировки — для перечитывания
+	loaded := cfg
+	targets := cfg.Targets
+	registry := newTargetRegistry(targets, cfg.Defaults)
+	triggers := make(chan checkTrigger)
+
+	checks := *numChecks
+	var handlers []func(CheckResult)
+	var alerter *Alerter
+	var regions *regionCollector
+	var self *checkerState
+
+	histograms := newTargetHistograms()
+	handlers = append(handlers, histograms.Observe)
+
+	var soak *soakRecorder
+	if *rotate > 0 {
+		soak = newSoakRecorder(*rotate, ".", run, histograms)
+		handlers = append(handlers, soak.Observe)
+		slog.Info("Длительный тест: сырые результаты записываются в отдельные файлы", "every", *rotate)
+	}
+
+	var store *ResultStore
+	if *dbPath != "" {
+		var err error
+		store, err = openStore(*dbPath)
+		if err != nil {
+			fatal("Ошибка при открытии базы результатов", "error", err)
+		}
+		defer store.Close()
+
+		if err := seedAdaptiveTimeouts(store, targets); err != nil {
+			slog.Warn("Ошибка при загрузке задержек для адаптивных таймаутов", "error", err)
+		}
+
+		handlers = append(handlers, func(r CheckResult) {
+			if err := store.Save(r); err != nil {
+				slog.Error("Ошибка при сохранении результата в базу", "error", err)
+			}
+		})
+	}
+
+	cfg.Sinks = append(cfg.Sinks, metricSinkFlags(*statsdAddr, *influxURL, *graphiteAddr)...)
+	if len(cfg.Sinks) > 0 {
+		sinks, err := newSinkDispatcher(cfg.Sinks)
+		if err != nil {
+			fatal("Ошибка в настройках приёмников", "error", err)
+		}
+		defer sinks.Close()
+		handlers = append(handlers, sinks.Observe)
+	}
+
+	uploaders, err := newUploaders(cfg.Uploads)
+	if err != nil {
+		fatal("Ошибка в настройках выгрузки результатов", "error", err)
+	}
+
+	if *artifactsDir != "" {
+		var err error
+		if artifacts, err = newArtifactWriter(*artifactsDir, *artifactBody); err != nil {
+			fatal("Ошибка при создании каталога артефактов", "error", err)
+		}
+		slog.Info("Запросы и ответы неуспешных проверок сохраняются", "dir", artifacts.dir)
+	}
+
+	if *otlpEndpoint != "" {
+		tracing := TracingConfig{}
+		if cfg.Tracing != nil {
+			tracing = *cfg.Tracing
+		}
+		tracing.Endpoint = *otlpEndpoint
+		cfg.Tracing = &tracing
+	}
+	if cfg.Tracing != nil {
+		var err error
+		tracer, err = newCheckTracer(*cfg.Tracing)
+		if err != nil {
+			fatal("Ошибка в настройках трассировки", "error", err)
+		}
+		defer tracer.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	maxResults := 0
+	if *daemon {
+		checks = 0
+		maxResults = daemonMaxResults
+
+		switch *startupCheck {
+		case StartupCheckOff:
+		case StartupCheckWarn, StartupCheckFail:
+			if err := runStartupCheck(targets, *startupCheck); err != nil {
+				fatal("Запуск отменён", "error", err)
+			}
+		default:
+			fatal("Неизвестный режим стартовой проверки", "mode", *startupCheck)
+		}
+
+		if *recordMissed {
+			if store == nil {
+				slog.Warn("Флаг -record-missed требует -db и игнорируется.")
+			} else if _, err := recordMissedWindows(store, targets, *interval, time.Now()); err != nil {
+				slog.Error("Ошибка при записи пропущенных периодов", "error", err)
+			}
+		}
+
+		// Результаты агентов сохраняются и отправляются в приёмники, но не влияют
+		// на здоровье целей и оповещения координатора
+		remote := append([]func(CheckResult){}, handlers...)
+
+		health := newHealthTracker(healthConfigFor(cfg))
+		health.subscribe(func(tr HealthTransition) {
+			slog.Info("Состояние цели изменилось", "target", tr.Target, "from", tr.From, "to", tr.To, "reason", tr.Reason)
+		})
+		handlers = append(handlers, health.Observe)
+
+		if cfg.Alerts != nil {
+			var err error
+			alerter, err = newAlerter(*cfg.Alerts, cfg.Health != nil, targets)
+			if err != nil {
+				fatal("Ошибка в настройках оповещений", "error", err)
+			}
+			health.subscribe(alerter.onHealthTransition)
+		}
+
+		// Цели и defaults перечитываются по SIGHUP и при изменении файла конфигурации
+		var reloader *configReloader
+		if *configPath != "" && !*fromStdin {
+			reloader = newConfigReloader(*configPath, network, loaded, registry)
+			reloader.alerter = alerter
+			reloader.forget = append(reloader.forget, health.forget)
+			if alerter != nil {
+				reloader.forget = append(reloader.forget, alerter.forget)
+			}
+		}
+
+		// Бюджет ошибок считается по сохранённым результатам
+		var slo *sloMonitor
+		if hasSLO(targets) || (cfg.Alerts != nil && cfg.Alerts.BurnRate != nil) {
+			if store == nil {
+				slog.Warn("Расчёт бюджета ошибок SLO и оповещения о нём требуют флага -db и отключены.")
+			} else {
+				var burn *BurnRateConfig
+				if cfg.Alerts != nil {
+					burn = cfg.Alerts.BurnRate
+				}
+				slo = newSLOMonitor(burn, store, alerter, registry)
+				go slo.run(ctx)
+			}
+		}
+
+		if *httpAddr != "" {
+			if err := checkAPIAddr(*httpAddr, *apiToken); err != nil {
+				fatal("Ошибка в настройках API управления", "error", err)
+			}
+			live := newLiveStore()
+			if store != nil {
+				if err := live.seed(store, 24*time.Hour); err != nil {
+					slog.Warn("Ошибка при загрузке истории для дашборда", "error", err)
+				}
+			}
+			handlers = append(handlers, live.Observe)
+			regions = newRegionCollector(*region)
+			handlers = append(handlers, regions.Observe)
+			self = newCheckerState(store)
+			handlers = append(handlers, self.Observe)
+			if reloader != nil {
+				reloader.forget = append(reloader.forget, live.forget, regions.forget)
+			}
+
+			mux := http.NewServeMux()
+			registerDashboard(mux, live, store, health, slo, *apiToken)
+			registerMetrics(mux, health, slo, histograms, *apiToken)
+			registerSelfChecks(mux, self)
+			registerControlAPI(mux, &controlAPI{
+				registry: registry,
+				live:     live,
+				health:   health,
+				alerter:  alerter,
+				store:    store,
+				triggers: triggers,
+				token:    *apiToken,
+				regions:  regions,
+				interval: *interval,
+				onRemote: func(r CheckResult) {
+					for _, h := range remote {
+						h(r)
+					}
+				},
+			})
+
+			server := &http.Server{Addr: *httpAddr, Handler: mux}
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					slog.Error("Ошибка веб-сервера", "error", err)
+				}
+			}()
+			defer server.Close()
+			slog.Info("Дашборд доступен", "address", *httpAddr)
+		}
+
+		if reloader != nil {
+			go reloader.run(ctx)
+		}
+	}
+
+	// Прогресс показывается, только если журнал выводится в терминал
+	colorOutput = !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	var progress *progressBar
+	switch {
+	case *quiet:
+		logOutput.set(ioutil.Discard)
+	case isTerminal(os.Stderr):
+		progress = newProgressBar(os.Stderr, expectedChecks(targets, checks))
+		handlers = append(handlers, progress.Observe)
+		logOutput.set(progress)
+	}
+
+	slog.Info("Запуск утилиты для измерения производительности и оценки отказоустойчивости API...", "version", version)
+
+	draining := make(chan struct{})
+
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		<-stop
+		slog.Info("Получен сигнал остановки. Сохранение результатов в файл...")
+		if self != nil {
+			self.drain()
+		}
+
+		if *grace > 0 {
+			close(draining)
+			// Повторный сигнал прерывает ожидание
+			select {
+			case <-time.After(*grace):
+			case <-stop:
+			}
+		}
+
+		cancel() // Отменяем контекст после получения сигнала
+	}()
+
+	var warmupResults []CheckResult
+	if warmup.enabled() {
+		slog.Info("Прогрев целей", "warmup", warmup.String())
+		warmupResults = runWarmup(ctx, targets, warmup)
+	}
+	if soak != nil {
+		for _, r := range warmupResults {
+			soak.Observe(r)
+		}
+		warmupResults = nil
+	}
+
+	testResult := runTests(ctx, registry, runOptions{
+		Interval:    *interval,
+		NumChecks:   checks,
+		Concurrency: *concurrency,
+		Draining:    draining,
+		Triggers:    triggers,
+		// В длительном тесте результаты хранит soakRecorder
+		DiscardResults: soak != nil,
+		// Без ограничения числа проверок в памяти остаются только последние результаты
+		MaxResults: maxResults,
+		OnWait: func(next time.Time) {
+			if self != nil {
+				self.scheduled(next)
+			}
+		},
+		OnResult: func(r CheckResult) {
+			r.Region = *region
+			for _, h := range handlers {
+				h(r)
+			}
+		},
+	})
+	if progress != nil {
+		progress.Close()
+	}
+	logOutput.set(os.Stderr)
+	if alerter != nil {
+		alerter.Wait()
+	}
+	// Прогревочные результаты сохраняются в файл с пометкой warmup
+	if len(warmupResults) > 0 {
+		testResult.Results = append(warmupResults, testResult.Results...)
+	}
+
+	// Выводим и анализируем результаты
+	if soak != nil {
+		testResult.Files = soak.Close()
+		soak.printSummary(os.Stdout)
+	} else {
+		if testResult.Dropped > 0 {
+			fmt.Printf("Итоги по последним %d проверкам (ранние не сохраняются: %d)\n", len(testResult.Results), testResult.Dropped)
+		}
+		printRunSummary(os.Stdout, targets, testResult.Results, len(warmupResults))
+	}
+	if regions != nil {
+		printRegions(os.Stdout, regions.summaries())
+	}
+
+	// Сохраняем результаты в файл
+	testResult.Histograms = histograms.snapshot()
+	run.Finished = time.Now()
+	testResult.Run = run
+	jsonData, err := json.MarshalIndent(testResult, "", "    ")
+	if err != nil {
+		slog.Error("Ошибка при сериализации результатов в JSON", "error", err)
+		return
+	}
+
+	err = ioutil.WriteFile("test_results.json", jsonData, 0644)
+	if err != nil {
+		slog.Error("Ошибка при сохранении результатов в файл", "error", err)
+		return
+	}
+
+	slog.Info("Результаты успешно сохранены в файл test_results.json.")
+	uploadResults(uploaders, jsonData, newUploadData(run, targets))
+
+	if baseline != nil && soak != nil {
+		slog.Warn("Сравнение с базовым запуском недоступно в длительном тесте (-rotate)")
+	} else if baseline != nil {
+		comparisons := compareRuns(*baseline, testResult, regressionThresholds{*maxP95, *maxDrop})
+		if printComparison(os.Stdout, comparisons) {
+			slog.Warn("Обнаружена регрессия относительно базового запуска.")
+			exitCode = 1
+		}
+		if store != nil {
+			if err := printRunAnnotations(os.Stdout, store, *baseline, testResult); err != nil {
+				slog.Error("Ошибка при загрузке аннотаций", "error", err)
+			}
+		}
+	}
+
+	slog.Info("Работа программы завершена.")
+}
+
+// printRunSummary выводит итоги запуска по сырым результатам проверок
+func printRunSummary(out io.Writer, targets []Target, results []CheckResult, warmupCount int) {
+	successfulCount := 0
+	shedCount := 0
+	abortedCount := 0
+	maintenanceCount := 0
+	for _, result := range results {
+		if result.Aborted {
+			abortedCount++
+		}
+		if result.Shed {
+			shedCount++
+			continue
+		}
+		if result.Warmup {
+			continue
+		}
+		if !result.countsAgainstSLA() {
+			maintenanceCount++
+			continue
+		}
+		if result.Success {
+			successfulCount++
+		}
+	}
+
+	// Без учитываемых проверок (все прерваны или пришлись на обслуживание) процент не определён
+	successfulPercentage := 0.0
+	if counted := len(results) - shedCount - maintenanceCount - warmupCount; counted > 0 {
+		successfulPercentage = float64(successfulCount) / float64(counted) * 100
+	}
+	printTargetSummaries(out, results)
+	fmt.Fprintln(out, colorize(fmt.Sprintf("Процент успешных запросов: %.2f%%", successfulPercentage), rateColor(successfulPercentage)))
+	if shedCount > abortedCount {
+		fmt.Fprintf(out, "Отброшено проверок: %d\n", shedCount-abortedCount)
+	}
+	if abortedCount > 0 {
+		fmt.Fprintf(out, "Прервано при остановке (не учитываются): %d\n", abortedCount)
+	}
+	if warmupCount > 0 {
+		fmt.Fprintf(out, "Прогревочных проверок (не учитываются): %d\n", warmupCount)
+	}
+	if maintenanceCount > 0 {
+		fmt.Fprintf(out, "Сбоев во время обслуживания (не учитываются): %d\n", maintenanceCount)
+	}
+	printStreaks(out, results)
+	printDatasetSummaries(out, summarizeDatasets(targets, results))
+	printDualStack(out, compareDualStack(targets, results))
+	printProtocolComparison(out, compareProtocols(targets, results))
+	printConnectionSummaries(out, summarizeConnections(results))
+	printRateLimits(out, summarizeRateLimits(results))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaintenanceWindow — период обслуживания цели: разовый (from и to) или повторяющийся
+// (days и hours, как в schedule). Проверки в это время выполняются и сохраняются,
+// но их сбои не учитываются в доступности и не вызывают оповещений.
+type MaintenanceWindow struct {
+	From     time.Time `yaml:"from,omitempty"`
+	To       time.Time `yaml:"to,omitempty"`
+	Days     string    `yaml:"days,omitempty"`
+	Hours    string    `yaml:"hours,omitempty"`
+	Timezone string    `yaml:"timezone,omitempty"`
+	Reason   string    `yaml:"reason,omitempty"`
+
+	recurring ScheduleRule
+}
+
+func (w *MaintenanceWindow) compile() error {
+	once := !w.From.IsZero() || !w.To.IsZero()
+	if once {
+		if w.Days != "" || w.Hours != "" {
+			return fmt.Errorf("нужно задать либо from и to, либо days и hours")
+		}
+		if w.From.IsZero() || w.To.IsZero() || !w.To.After(w.From) {
+			return fmt.Errorf("to должен быть позже from")
+		}
+		return nil
+	}
+	if w.Days == "" && w.Hours == "" {
+		return fmt.Errorf("нужно задать from и to или days и hours")
+	}
+	w.recurring = ScheduleRule{Days: w.Days, Hours: w.Hours, Timezone: w.Timezone}
+	return w.recurring.compileWindow()
+}
+
+func (w *MaintenanceWindow) matches(now time.Time) bool {
+	if !w.From.IsZero() {
+		return !now.Before(w.From) && now.Before(w.To)
+	}
+	return w.recurring.matches(now)
+}
+
+// compileMaintenance проверяет периоды обслуживания цели
+func (t *Target) compileMaintenance() error {
+	// Периоды копируются, чтобы не разделять разобранные значения с defaults
+	t.Maintenance = append([]MaintenanceWindow(nil), t.Maintenance...)
+	for i := range t.Maintenance {
+		if err := t.Maintenance[i].compile(); err != nil {
+			return fmt.Errorf("maintenance #%d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// inMaintenance сообщает, идёт ли у цели обслуживание в момент now
+func (t Target) inMaintenance(now time.Time) bool {
+	for i := range t.Maintenance {
+		if t.Maintenance[i].matches(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// countsAgainstSLA сообщает, учитывается ли результат в доступности и оповещениях:
+// сбои во время обслуживания не учитываются
+func (r CheckResult) countsAgainstSLA() bool {
+	return !r.Shed && !(r.Maintenance && !r.Success)
+}
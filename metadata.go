@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Версия и коммит утилиты задаются при сборке:
+// go build -ldflags "-X main.version=1.2.0 -X main.gitSHA=$(git rev-parse HEAD)"
+var (
+	version = "dev"
+	gitSHA  = ""
+)
+
+// RunMetadata описывает запуск, чтобы сохранённый файл результатов был самодостаточным
+type RunMetadata struct {
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Version  string            `json:"version"`
+	GitSHA   string            `json:"git_sha,omitempty"`
+	Hostname string            `json:"hostname,omitempty"`
+	Args     []string          `json:"args"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+func newRunMetadata(args []string, tags tagFlags) *RunMetadata {
+	hostname, _ := os.Hostname()
+	m := &RunMetadata{
+		Started:  time.Now(),
+		Version:  version,
+		GitSHA:   buildRevision(),
+		Hostname: hostname,
+		Args:     redactArgs(args),
+	}
+	if len(tags) > 0 {
+		m.Tags = tags
+	}
+	return m
+}
+
+// buildRevision возвращает коммит из -ldflags, а без него — записанный Go при сборке из репозитория
+func buildRevision() string {
+	if gitSHA != "" {
+		return gitSHA
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return ""
+}
+
+// redactArgs скрывает значения флагов с секретами (например -api-token) и заголовков -H
+// Authorization, Cookie и подобных
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	var pending string // флаг, значение которого — следующий аргумент
+	for i, arg := range args {
+		out[i] = arg
+		if pending != "" {
+			out[i] = redactFlagValue(pending, arg)
+			pending = ""
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case hasValue:
+			out[i] = arg[:len(arg)-len(value)] + redactFlagValue(name, value)
+		case isSensitiveName(name) || name == "H":
+			pending = name
+		}
+	}
+	return out
+}
+
+func redactFlagValue(flagName, value string) string {
+	if isSensitiveName(flagName) {
+		return "[скрыто]"
+	}
+	if flagName == "H" {
+		if header, _, ok := strings.Cut(value, ":"); ok && isSensitiveName(header) {
+			return header + ": [скрыто]"
+		}
+	}
+	return value
+}
+
+// tagFlags — повторяемый флаг -tag ключ=значение
+type tagFlags map[string]string
+
+func (t tagFlags) String() string {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + t[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t tagFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("метка должна иметь вид ключ=значение")
+	}
+	t[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	return nil
+}
+
+// matches сообщает, что у запуска есть все метки фильтра
+func (t tagFlags) matches(run *RunMetadata) bool {
+	for k, v := range t {
+		if run == nil || run.Tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func printVersion() {
+	if sha := buildRevision(); sha != "" {
+		fmt.Printf("apichecker %s (%s)\n", version, sha)
+		return
+	}
+	fmt.Printf("apichecker %s\n", version)
+}
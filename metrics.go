@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// registerMetrics регистрирует /metrics — состояния целей в текстовом формате Prometheus.
+// Для каждой цели выводится по метрике на состояние: 1 для текущего, 0 для остальных.
+// Задержки проверок выводятся гистограммой, для целей с SLO — остаток бюджета ошибок
+// и скорость его расхода. С заданным token метрики требуют токен API.
+func registerMetrics(mux *http.ServeMux, health *healthTracker, slo *sloMonitor, histograms *targetHistograms, token string) {
+	mux.HandleFunc("/metrics", requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprintln(w, "# HELP apichecker_target_health_state Текущее состояние здоровья цели.")
+		fmt.Fprintln(w, "# TYPE apichecker_target_health_state gauge")
+		for _, st := range health.snapshot() {
+			for _, state := range healthStates {
+				v := 0
+				if st.State == state {
+					v = 1
+				}
+				fmt.Fprintf(w, "apichecker_target_health_state{target=\"%s\",state=\"%s\"} %d\n", metricLabel(st.Target), state, v)
+			}
+		}
+
+		fmt.Fprintln(w, "# HELP apichecker_check_latency_seconds Задержки проверок цели.")
+		fmt.Fprintln(w, "# TYPE apichecker_check_latency_seconds histogram")
+		histograms.each(func(name string, h *latencyHistogram) {
+			label := metricLabel(name)
+			for _, bound := range metricLatencyBuckets {
+				fmt.Fprintf(w, "apichecker_check_latency_seconds_bucket{target=\"%s\",le=\"%g\"} %d\n", label, bound.Seconds(), h.countAtMost(bound))
+			}
+			fmt.Fprintf(w, "apichecker_check_latency_seconds_bucket{target=\"%s\",le=\"+Inf\"} %d\n", label, h.total)
+			fmt.Fprintf(w, "apichecker_check_latency_seconds_sum{target=\"%s\"} %g\n", label, h.sum.Seconds())
+			fmt.Fprintf(w, "apichecker_check_latency_seconds_count{target=\"%s\"} %d\n", label, h.total)
+		})
+
+		statuses := slo.snapshot()
+		if len(statuses) == 0 {
+			return
+		}
+		fmt.Fprintln(w, "# HELP apichecker_slo_error_budget_remaining_percent Неизрасходованный остаток бюджета ошибок за период SLO.")
+		fmt.Fprintln(w, "# TYPE apichecker_slo_error_budget_remaining_percent gauge")
+		for _, st := range statuses {
+			fmt.Fprintf(w, "apichecker_slo_error_budget_remaining_percent{target=\"%s\"} %g\n", metricLabel(st.Target), st.BudgetRemaining)
+		}
+		fmt.Fprintln(w, "# HELP apichecker_slo_burn_rate Скорость расхода бюджета ошибок за последний час.")
+		fmt.Fprintln(w, "# TYPE apichecker_slo_burn_rate gauge")
+		for _, st := range statuses {
+			fmt.Fprintf(w, "apichecker_slo_burn_rate{target=\"%s\"} %g\n", metricLabel(st.Target), st.BurnRate)
+		}
+	}))
+}
+
+// Границы корзин гистограммы задержек в /metrics
+var metricLatencyBuckets = []time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+var metricLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricLabel экранирует значение метки Prometheus
+func metricLabel(s string) string {
+	return metricLabelReplacer.Replace(s)
+}
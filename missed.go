@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// MissedWindow — период, в который цель не проверялась, потому что утилита не работала.
+// Такие периоды не считаются ни доступностью, ни сбоем цели.
+type MissedWindow struct {
+	Target string    `json:"target"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// Перерыв считается пропуском, если с последней проверки прошло больше стольких интервалов цели
+const missedWindowFactor = 2
+
+// recordMissedWindows при запуске мониторинга сравнивает время последней сохранённой
+// проверки каждой цели с текущим и записывает пропущенный период, если перерыв
+// заметно длиннее интервала цели
+func recordMissedWindows(store *ResultStore, targets []Target, interval time.Duration, now time.Time) ([]MissedWindow, error) {
+	var recorded []MissedWindow
+	for _, t := range targets {
+		last, err := store.LastCheck(t.Name)
+		if err != nil {
+			return recorded, err
+		}
+		if last.IsZero() {
+			continue
+		}
+
+		step := t.intervalAt(last, interval)
+		if now.Sub(last) <= missedWindowFactor*step {
+			continue
+		}
+
+		w := MissedWindow{Target: t.Name, Start: last.Add(step), End: now}
+		if err := store.AddMissedWindow(w); err != nil {
+			return recorded, err
+		}
+		slog.Info("Цель не проверялась, период записан как пропущенный", "target", t.Name,
+			"from", w.Start.Format("2006-01-02 15:04:05"), "to", w.End.Format("2006-01-02 15:04:05"), "duration", w.duration().Round(time.Second))
+		recorded = append(recorded, w)
+	}
+	return recorded, nil
+}
+
+func (w MissedWindow) duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// missedWithin возвращает суммарную длительность пропущенных периодов цели внутри [from, to)
+func missedWithin(windows []MissedWindow, target string, from, to time.Time) time.Duration {
+	var total time.Duration
+	for _, w := range windows {
+		if w.Target != target {
+			continue
+		}
+		start, end := w.Start, w.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
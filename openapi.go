@@ -0,0 +1,587 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec — спецификация OpenAPI 3.x или Swagger 2.0 в виде разобранного документа
+type openAPISpec struct {
+	doc map[string]interface{}
+}
+
+// Методы операций в порядке вывода
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Методы, для которых по умолчанию создаются проверки: периодические запросы остальных
+// изменяли бы данные API
+const defaultImportMethods = "get,head"
+
+// Ограничение длины цепочки ссылок $ref
+const maxRefChain = 8
+
+func loadOpenAPISpec(path string) (*openAPISpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	// Приводим числа и вложенные объекты к типам encoding/json;
+	// коды ответов без кавычек YAML разбирает как числовые ключи
+	normalized, err := normalizeJSON(stringKeys(doc))
+	if err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	doc, _ = normalized.(map[string]interface{})
+
+	if doc["openapi"] == nil && doc["swagger"] == nil {
+		return nil, fmt.Errorf("%s: не найдено поле openapi или swagger", path)
+	}
+	return &openAPISpec{doc: doc}, nil
+}
+
+func (s *openAPISpec) isSwagger2() bool {
+	return s.doc["swagger"] != nil
+}
+
+// baseURL возвращает адрес сервера из спецификации
+func (s *openAPISpec) baseURL() string {
+	if s.isSwagger2() {
+		host, _ := s.doc["host"].(string)
+		if host == "" {
+			return ""
+		}
+		scheme := "https"
+		if schemes, ok := s.doc["schemes"].([]interface{}); ok && len(schemes) > 0 {
+			scheme = fmt.Sprint(schemes[0])
+		}
+		basePath, _ := s.doc["basePath"].(string)
+		return scheme + "://" + host + basePath
+	}
+
+	servers, _ := s.doc["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server := asSchema(servers[0])
+	u, _ := server["url"].(string)
+	for name, v := range asSchema(server["variables"]) {
+		u = strings.ReplaceAll(u, "{"+name+"}", fmt.Sprint(asSchema(v)["default"]))
+	}
+	return u
+}
+
+// parseImportMethods разбирает список методов через запятую
+func parseImportMethods(s string) (map[string]bool, error) {
+	methods := make(map[string]bool)
+	for _, m := range strings.Split(s, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+		known := false
+		for _, k := range openAPIMethods {
+			known = known || k == m
+		}
+		if !known {
+			return nil, fmt.Errorf("неизвестный метод %q", m)
+		}
+		methods[m] = true
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("не указано ни одного метода")
+	}
+	return methods, nil
+}
+
+// targets строит по цели на каждую операцию спецификации с методом из methods
+func (s *openAPISpec) targets(baseURL string, methods map[string]bool) ([]Target, error) {
+	if baseURL == "" {
+		baseURL = s.baseURL()
+	}
+	if u, err := url.Parse(baseURL); err != nil || u.Host == "" {
+		return nil, fmt.Errorf("в спецификации нет абсолютного адреса сервера, укажите -base-url")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	paths := asSchema(s.doc["paths"])
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	var targets []Target
+	skipped := 0
+	for _, p := range names {
+		item := s.resolve(asSchema(paths[p]))
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if !methods[method] {
+				skipped++
+				continue
+			}
+			t, err := s.operationTarget(baseURL, p, method, item, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), p, err)
+			}
+			targets = append(targets, t)
+		}
+	}
+	if skipped > 0 {
+		slog.Info("Пропущены операции с методами не из -methods", "operations", skipped)
+	}
+	return targets, nil
+}
+
+func (s *openAPISpec) operationTarget(baseURL, path, method string, item, op map[string]interface{}) (Target, error) {
+	t := Target{
+		Name:   strings.ToUpper(method) + " " + path,
+		Method: strings.ToUpper(method),
+	}
+	if id, ok := op["operationId"].(string); ok && id != "" {
+		t.Name = id
+	}
+
+	query := url.Values{}
+	for _, param := range s.parameters(item, op) {
+		name, _ := param["name"].(string)
+		required, _ := param["required"].(bool)
+		value := s.parameterExample(param)
+
+		switch param["in"] {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(scalarString(value)))
+		case "query":
+			if required || param["example"] != nil {
+				query.Set(name, scalarString(value))
+			}
+		case "header":
+			if required {
+				setHeader(&t, name, scalarString(value))
+			}
+		case "body":
+			// Swagger 2.0: тело запроса описано параметром
+			body, err := json.Marshal(value)
+			if err != nil {
+				return t, err
+			}
+			t.Body = string(body)
+			setHeader(&t, "Content-Type", "application/json")
+		}
+	}
+
+	t.URL = baseURL + path
+	if len(query) > 0 {
+		t.URL += "?" + query.Encode()
+	}
+
+	if rb := s.resolve(asSchema(op["requestBody"])); rb != nil {
+		if contentType, media := jsonMedia(asSchema(rb["content"])); media != nil {
+			body, err := json.Marshal(s.mediaExample(media))
+			if err != nil {
+				return t, err
+			}
+			t.Body = string(body)
+			setHeader(&t, "Content-Type", contentType)
+		}
+	}
+
+	status, schema := s.expectedResponse(asSchema(op["responses"]))
+	if schema != nil {
+		t.Schema = s.inline(schema, map[string]bool{})
+	}
+	switch {
+	case status == "":
+		t.Success = "status >= 200 && status < 300 && assertions"
+	case status != "200":
+		t.Success = fmt.Sprintf("status == %s && assertions", status)
+	}
+
+	return t, nil
+}
+
+// responseSchema находит схему ответа операции для проверки целей с полем openapi
+func (s *openAPISpec) responseSchema(ref OpenAPIResponse) (map[string]interface{}, error) {
+	var op map[string]interface{}
+	var desc string
+	paths := asSchema(s.doc["paths"])
+
+	switch {
+	case ref.Operation != "":
+		desc = ref.Operation
+		for _, item := range paths {
+			item := s.resolve(asSchema(item))
+			for _, method := range openAPIMethods {
+				if candidate := asSchema(item[method]); candidate != nil && candidate["operationId"] == ref.Operation {
+					op = candidate
+				}
+			}
+		}
+	case ref.Path != "":
+		method := strings.ToLower(ref.Method)
+		if method == "" {
+			method = "get"
+		}
+		desc = strings.ToUpper(method) + " " + ref.Path
+		op = asSchema(s.resolve(asSchema(paths[ref.Path]))[method])
+	default:
+		return nil, fmt.Errorf("нужно указать operation или path")
+	}
+	if op == nil {
+		return nil, fmt.Errorf("операция %s не найдена в %s", desc, ref.Spec)
+	}
+
+	responses := asSchema(op["responses"])
+	if ref.Status != "" {
+		responses = map[string]interface{}{ref.Status: responses[ref.Status]}
+		if responses[ref.Status] == nil {
+			return nil, fmt.Errorf("%s: нет ответа %s", desc, ref.Status)
+		}
+	}
+	_, schema := s.expectedResponse(responses)
+	if schema == nil && ref.Status != "" {
+		schema = s.responseBodySchema(asSchema(responses[ref.Status]))
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("%s: у ответа нет JSON-схемы", desc)
+	}
+	return schema, nil
+}
+
+// parameters объединяет параметры пути и операции; параметры операции переопределяют общие
+func (s *openAPISpec) parameters(item, op map[string]interface{}) []map[string]interface{} {
+	var out []map[string]interface{}
+	index := make(map[string]int)
+
+	for _, list := range []interface{}{item["parameters"], op["parameters"]} {
+		params, _ := list.([]interface{})
+		for _, p := range params {
+			param := s.resolve(asSchema(p))
+			if param == nil {
+				continue
+			}
+			key := fmt.Sprint(param["in"], "/", param["name"])
+			if i, ok := index[key]; ok {
+				out[i] = param
+				continue
+			}
+			index[key] = len(out)
+			out = append(out, param)
+		}
+	}
+	return out
+}
+
+func (s *openAPISpec) parameterExample(param map[string]interface{}) interface{} {
+	if v, ok := param["example"]; ok {
+		return v
+	}
+	if v, ok := firstExample(asSchema(param["examples"])); ok {
+		return v
+	}
+	if schema := asSchema(param["schema"]); schema != nil {
+		return s.example(schema, map[string]bool{})
+	}
+	// Swagger 2.0: тип описан прямо в параметре
+	return s.example(param, map[string]bool{})
+}
+
+func (s *openAPISpec) mediaExample(media map[string]interface{}) interface{} {
+	if v, ok := media["example"]; ok {
+		return v
+	}
+	if v, ok := firstExample(asSchema(media["examples"])); ok {
+		return v
+	}
+	return s.example(asSchema(media["schema"]), map[string]bool{})
+}
+
+// expectedResponse выбирает наименьший успешный код ответа и схему его тела.
+// Пустой код означает диапазон 2XX.
+func (s *openAPISpec) expectedResponse(responses map[string]interface{}) (string, map[string]interface{}) {
+	var codes []string
+	for code := range responses {
+		if n, err := strconv.Atoi(code); err == nil && n >= 200 && n < 300 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	var code string
+	var resp map[string]interface{}
+	switch {
+	case len(codes) > 0:
+		code, resp = codes[0], asSchema(responses[codes[0]])
+	case responses["2XX"] != nil:
+		resp = asSchema(responses["2XX"])
+	default:
+		return "200", nil
+	}
+
+	return code, s.responseBodySchema(resp)
+}
+
+// responseBodySchema возвращает схему JSON-тела ответа
+func (s *openAPISpec) responseBodySchema(resp map[string]interface{}) map[string]interface{} {
+	resp = s.resolve(resp)
+	if s.isSwagger2() {
+		return asSchema(resp["schema"])
+	}
+	_, media := jsonMedia(asSchema(resp["content"]))
+	return asSchema(media["schema"])
+}
+
+// example строит пример значения по схеме; seen содержит раскрываемые сейчас ссылки,
+// чтобы рекурсивные схемы не разворачивались бесконечно
+func (s *openAPISpec) example(schema map[string]interface{}, seen map[string]bool) interface{} {
+	schema, ref := s.enter(schema, seen)
+	if schema == nil {
+		return nil
+	}
+	if ref != "" {
+		defer delete(seen, ref)
+	}
+
+	for _, key := range []string{"example", "default"} {
+		if v, ok := schema[key]; ok {
+			return v
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		merged := make(map[string]interface{})
+		for _, sub := range all {
+			if obj, ok := s.example(asSchema(sub), seen).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if variants, ok := schema[key].([]interface{}); ok && len(variants) > 0 {
+			return s.example(asSchema(variants[0]), seen)
+		}
+	}
+
+	t, _ := schema["type"].(string)
+	if types, ok := schema["type"].([]interface{}); ok && len(types) > 0 {
+		t = fmt.Sprint(types[0])
+	}
+	if t == "" && schema["properties"] != nil {
+		t = "object"
+	}
+
+	switch t {
+	case "object":
+		obj := make(map[string]interface{})
+		for name, prop := range asSchema(schema["properties"]) {
+			if v := s.example(asSchema(prop), seen); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		if item := s.example(asSchema(schema["items"]), seen); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "integer", "number":
+		if min, ok := schemaNumber(schema, "minimum"); ok {
+			return min
+		}
+		return 1
+	case "boolean":
+		return true
+	case "string":
+		switch schema["format"] {
+		case "date":
+			return "2024-01-01"
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "email":
+			return "user@example.com"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	}
+	return nil
+}
+
+// inline раскрывает локальные ссылки, чтобы схема цели не зависела от спецификации.
+// Повторное вхождение рекурсивной схемы заменяется пустой схемой без ограничений.
+func (s *openAPISpec) inline(schema map[string]interface{}, seen map[string]bool) map[string]interface{} {
+	schema, ref := s.enter(schema, seen)
+	if schema == nil {
+		return map[string]interface{}{}
+	}
+	if ref != "" {
+		defer delete(seen, ref)
+	}
+
+	out := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		switch k {
+		case "example", "examples", "description", "title", "xml", "externalDocs", "readOnly", "writeOnly", "deprecated", "discriminator":
+			continue
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if k == "properties" {
+				props := make(map[string]interface{}, len(val))
+				for name, prop := range val {
+					props[name] = s.inline(asSchema(prop), seen)
+				}
+				out[k] = props
+			} else {
+				out[k] = s.inline(val, seen)
+			}
+		case []interface{}:
+			if k == "allOf" || k == "anyOf" || k == "oneOf" {
+				subs := make([]interface{}, len(val))
+				for i, sub := range val {
+					subs[i] = s.inline(asSchema(sub), seen)
+				}
+				out[k] = subs
+			} else {
+				out[k] = val
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// enter разворачивает ссылку схемы и отмечает её в seen; для уже раскрываемой
+// ссылки возвращает nil. Вызывающий удаляет возвращённую ссылку из seen по завершении.
+func (s *openAPISpec) enter(schema map[string]interface{}, seen map[string]bool) (map[string]interface{}, string) {
+	ref, _ := schema["$ref"].(string)
+	if ref == "" {
+		return schema, ""
+	}
+	if seen[ref] {
+		return nil, ""
+	}
+	seen[ref] = true
+	return s.resolve(schema), ref
+}
+
+// resolve разворачивает ссылку $ref, если она задана
+func (s *openAPISpec) resolve(node map[string]interface{}) map[string]interface{} {
+	for i := 0; i < maxRefChain && node != nil; i++ {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		resolved, err := resolveSchemaRef(s.doc, ref)
+		if err != nil {
+			return nil
+		}
+		node = resolved
+	}
+	return node
+}
+
+// jsonMedia выбирает JSON-представление из content
+func jsonMedia(content map[string]interface{}) (string, map[string]interface{}) {
+	types := make([]string, 0, len(content))
+	for ct := range content {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+
+	for _, ct := range types {
+		if ct == "application/json" || strings.HasSuffix(ct, "+json") {
+			return ct, asSchema(content[ct])
+		}
+	}
+	return "", nil
+}
+
+func firstExample(examples map[string]interface{}) (interface{}, bool) {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if v, ok := asSchema(examples[name])["value"]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func scalarString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = scalarString(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+func setHeader(t *Target, name, value string) {
+	if t.Headers == nil {
+		t.Headers = make(map[string]string)
+	}
+	t.Headers[name] = value
+}
+
+// stringKeys приводит ключи вложенных YAML-объектов к строкам
+func stringKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = stringKeys(item)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = stringKeys(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = stringKeys(item)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeOpenAPISpec(t *testing.T, src string) *openAPISpec {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := loadOpenAPISpec(path)
+	if err != nil {
+		t.Fatalf("загрузка спецификации: %v", err)
+	}
+	return spec
+}
+
+const testOpenAPI3 = `
+openapi: 3.0.3
+servers:
+  - url: https://{env}.example.com/v1
+    variables:
+      env: {default: api}
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer, minimum: 5}}
+    get:
+      operationId: getUser
+      parameters:
+        - {name: expand, in: query, example: roles}
+        - {name: page, in: query, schema: {type: integer}}
+        - {name: X-Tenant, in: header, required: true, schema: {type: string, enum: [acme, other]}}
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+    delete:
+      responses:
+        "204": {description: deleted}
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        "201":
+          description: created
+    head:
+      responses:
+        2XX: {description: ok}
+components:
+  schemas:
+    User:
+      type: object
+      description: пользователь
+      properties:
+        id: {type: integer, example: 7}
+        email: {type: string, format: email}
+        manager: {$ref: '#/components/schemas/User'}
+`
+
+func TestOpenAPITargets(t *testing.T) {
+	spec := writeOpenAPISpec(t, testOpenAPI3)
+	if got := spec.baseURL(); got != "https://api.example.com/v1" {
+		t.Errorf("baseURL = %q", got)
+	}
+
+	tests := []struct {
+		methods string
+		want    []string
+	}{
+		{defaultImportMethods, []string{"HEAD https://api.example.com/v1/users", "GET https://api.example.com/v1/users/5?expand=roles"}},
+		{"post, DELETE", []string{"POST https://api.example.com/v1/users", "DELETE https://api.example.com/v1/users/5"}},
+	}
+	for _, tt := range tests {
+		methods, err := parseImportMethods(tt.methods)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.methods, err)
+		}
+		targets, err := spec.targets("", methods)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.methods, err)
+		}
+		var got []string
+		for _, target := range targets {
+			got = append(got, target.Method+" "+target.URL)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: получено %v, ожидалось %v", tt.methods, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAPIOperationTarget(t *testing.T) {
+	spec := writeOpenAPISpec(t, testOpenAPI3)
+	methods, _ := parseImportMethods("get,post,delete,head")
+	targets, err := spec.targets("http://localhost:8080/", methods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]Target)
+	for _, target := range targets {
+		byName[target.Name] = target
+	}
+
+	get := byName["getUser"]
+	if get.URL != "http://localhost:8080/users/5?expand=roles" {
+		t.Errorf("URL getUser = %q", get.URL)
+	}
+	if !reflect.DeepEqual(get.Headers, map[string]string{"X-Tenant": "acme"}) {
+		t.Errorf("заголовки getUser = %v", get.Headers)
+	}
+	if get.Success != "" {
+		t.Errorf("условие успеха getUser = %q, для 200 ожидалось пустое", get.Success)
+	}
+	// Рекурсивная ссылка раскрывается один раз, описание отбрасывается
+	wantSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":      map[string]interface{}{"type": "integer"},
+			"email":   map[string]interface{}{"type": "string", "format": "email"},
+			"manager": map[string]interface{}{},
+		},
+	}
+	if !reflect.DeepEqual(get.Schema, wantSchema) {
+		t.Errorf("схема getUser = %v", get.Schema)
+	}
+
+	post := byName["POST /users"]
+	if post.Body != `{"email":"user@example.com","id":7}` {
+		t.Errorf("тело POST = %s", post.Body)
+	}
+	if post.Headers["Content-Type"] != "application/json" || post.Success != "status == 201 && assertions" {
+		t.Errorf("POST: заголовки %v, условие %q", post.Headers, post.Success)
+	}
+	if s := byName["DELETE /users/{id}"].Success; s != "status == 204 && assertions" {
+		t.Errorf("условие DELETE = %q", s)
+	}
+	if s := byName["HEAD /users"].Success; s != "status >= 200 && status < 300 && assertions" {
+		t.Errorf("условие HEAD = %q", s)
+	}
+}
+
+func TestSwagger2Targets(t *testing.T) {
+	spec := writeOpenAPISpec(t, `
+swagger: "2.0"
+host: api.example.com
+basePath: /v2
+schemes: [http]
+paths:
+  /pets:
+    post:
+      operationId: addPet
+      parameters:
+        - in: body
+          name: pet
+          schema:
+            type: object
+            properties:
+              name: {type: string, default: rex}
+              tags: {type: array, items: {type: string}}
+      responses:
+        200:
+          description: ok
+          schema: {type: object, properties: {id: {type: integer}}}
+`)
+	methods, _ := parseImportMethods("post")
+	targets, err := spec.targets("", methods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("получено целей: %d", len(targets))
+	}
+	got := targets[0]
+	if got.URL != "http://api.example.com/v2/pets" || got.Body != `{"name":"rex","tags":["string"]}` {
+		t.Errorf("URL %q, тело %s", got.URL, got.Body)
+	}
+	if got.Schema == nil || got.Schema["type"] != "object" {
+		t.Errorf("схема ответа = %v", got.Schema)
+	}
+}
+
+func TestOpenAPITargetsNoServer(t *testing.T) {
+	spec := writeOpenAPISpec(t, "openapi: 3.0.0\npaths: {}\n")
+	methods, _ := parseImportMethods(defaultImportMethods)
+	if _, err := spec.targets("", methods); err == nil {
+		t.Error("ожидалась ошибка без адреса сервера")
+	}
+}
+
+func TestParseImportMethods(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{"get,head", map[string]bool{"get": true, "head": true}, false},
+		{" GET , ,Patch", map[string]bool{"get": true, "patch": true}, false},
+		{"", nil, true},
+		{"get,fetch", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseImportMethods(tt.src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: ошибка %v", tt.src, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: получено %v, ожидалось %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"plugin"
+	"strings"
+	"sync"
+	"time"
+
+	"test/checker"
+	"test/sink"
+)
+
+// PluginConfig — внешняя реализация проверок или приёмников: плагин Go или программа
+type PluginConfig struct {
+	// Плагин Go (go build -buildmode=plugin), регистрирующий проверки и приёмники в init
+	Path string `yaml:"path,omitempty"`
+	// Программа, которая обменивается с утилитой JSON через stdin и stdout:
+	// name — имя типа проверки или приёмника, kind — check или sink
+	Name    string   `yaml:"name,omitempty"`
+	Kind    string   `yaml:"kind,omitempty"`
+	Command []string `yaml:"command,omitempty"`
+}
+
+// Виды внешних программ
+const (
+	PluginKindCheck = "check"
+	PluginKindSink  = "sink"
+)
+
+// Подключённые плагины: конфигурация может загружаться несколько раз, повторно
+// подключается только то же самое
+var (
+	pluginsMu sync.Mutex
+	loaded    = make(map[string]string)
+)
+
+// loadPlugins подключает плагины до проверки целей и приёмников, которые их используют
+func loadPlugins(plugins []PluginConfig) error {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	for i, p := range plugins {
+		if err := p.load(); err != nil {
+			return fmt.Errorf("plugins #%d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (p PluginConfig) load() error {
+	if p.Path != "" {
+		if _, ok := loaded["path:"+p.Path]; ok {
+			return nil
+		}
+		// Открытие плагина выполняет его init, где он регистрирует свои реализации
+		if _, err := plugin.Open(p.Path); err != nil {
+			return err
+		}
+		loaded["path:"+p.Path] = p.Path
+		return nil
+	}
+
+	if p.Name == "" || len(p.Command) == 0 {
+		return fmt.Errorf("нужен path или name и command")
+	}
+	key, command := p.Kind+":"+p.Name, strings.Join(p.Command, " ")
+	if prev, ok := loaded[key]; ok {
+		if prev != command {
+			return fmt.Errorf("%s %s уже подключён с другой командой", p.Kind, p.Name)
+		}
+		return nil
+	}
+
+	switch p.Kind {
+	case PluginKindCheck:
+		if _, dup := checker.Lookup(p.Name); dup || isBuiltinCheckType(p.Name) {
+			return fmt.Errorf("тип проверки %s уже существует", p.Name)
+		}
+		checker.Register(p.Name, execChecker{command: p.Command})
+	case PluginKindSink:
+		for _, name := range sink.Names() {
+			if name == p.Name {
+				return fmt.Errorf("приёмник %s уже существует", p.Name)
+			}
+		}
+		command := p.Command
+		sink.Register(p.Name, func(options map[string]interface{}) (sink.Sink, error) {
+			return newExecSink(command, options)
+		})
+	default:
+		return fmt.Errorf("%s: неизвестный kind %q (check или sink)", p.Name, p.Kind)
+	}
+	loaded[key] = command
+	return nil
+}
+
+func isBuiltinCheckType(name string) bool {
+	return name == CheckTypeHTTP || name == CheckTypeGraphQL || name == CheckTypeComposite || name == CheckTypeScenario
+}
+
+// execChecker запускает программу на каждую проверку: в stdin передаётся checker.Request
+// в JSON, из stdout читается checker.Result
+type execChecker struct {
+	command []string
+}
+
+func (c execChecker) Check(ctx context.Context, req checker.Request) (checker.Result, error) {
+	var res checker.Result
+	input, err := json.Marshal(req)
+	if err != nil {
+		return res, err
+	}
+
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	runErr := cmd.Run()
+
+	// Программа может завершиться с ненулевым кодом и всё равно сообщить результат
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		if runErr != nil {
+			return res, fmt.Errorf("%s: %v: %s", c.command[0], runErr, strings.TrimSpace(stderr.String()))
+		}
+		return res, fmt.Errorf("%s: ответ не в формате JSON: %v", c.command[0], err)
+	}
+	return res, nil
+}
+
+// executePlugin выполняет проверку типа, зарегистрированного плагином
+func executePlugin(ctx context.Context, target Target) CheckResult {
+	result := CheckResult{Target: target.Name, Timestamp: time.Now()}
+
+	timeout := target.effectiveTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	res, err := target.checker.Check(ctx, checker.Request{
+		Target:  target.Name,
+		URL:     target.URL,
+		Timeout: timeout,
+		Options: target.Options,
+	})
+	result.Latency = time.Since(result.Timestamp)
+	if err != nil {
+		slog.Warn("Ошибка при выполнении проверки из плагина", "target", target.Name, "type", target.Type, "error", err)
+		result.Error = err.Error()
+		target.applySeverity(&result, signalsFor(result, false))
+		return result
+	}
+	if res.Latency > 0 {
+		result.Latency = res.Latency
+	}
+	result.Status = res.Status
+	result.Metrics = res.Metrics
+
+	signals := signalsFor(result, res.Success)
+	if target.successCond != nil {
+		ok, err := target.successCond.evalBool(signals)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case !ok:
+			result.Error = "не выполнено условие успеха: " + target.successCond.source
+		default:
+			result.Success = true
+		}
+	} else {
+		result.Success = res.Success
+		result.Error = res.Error
+		if !res.Success && res.Error == "" {
+			result.Error = "проверка не прошла"
+		}
+	}
+	target.applySeverity(&result, signals)
+	return result
+}
+
+// execSink передаёт результаты программе по строке JSON (sink.Result) в stdin;
+// options приёмника передаются программе в переменной окружения APICHECKER_SINK_OPTIONS.
+// Если программа завершилась, она запускается заново при следующем результате.
+type execSink struct {
+	command []string
+	options []byte
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	w       *bufio.Writer
+}
+
+func newExecSink(command []string, options map[string]interface{}) (sink.Sink, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	return &execSink{command: command, options: data}, nil
+}
+
+func (s *execSink) start() error {
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	cmd.Env = append(os.Environ(), "APICHECKER_SINK_OPTIONS="+string(s.options))
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.cmd, s.stdin, s.w = cmd, stdin, bufio.NewWriter(stdin)
+	return nil
+}
+
+func (s *execSink) Write(ctx context.Context, r sink.Result) error {
+	if s.cmd == nil {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		s.stop()
+		return err
+	}
+	return nil
+}
+
+func (s *execSink) Flush(ctx context.Context) error {
+	if s.cmd == nil {
+		return nil
+	}
+	if err := s.w.Flush(); err != nil {
+		s.stop()
+		return err
+	}
+	return nil
+}
+
+func (s *execSink) Close() error {
+	if s.cmd == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if werr := s.stop(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// stop закрывает stdin программы и дожидается её завершения
+func (s *execSink) stop() error {
+	s.stdin.Close()
+	err := s.cmd.Wait()
+	s.cmd = nil
+	return err
+}
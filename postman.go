@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// postmanCollection — коллекция Postman v2.1; описаны только поля, нужные для импорта
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanItem — запрос или папка с вложенными элементами
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *postmanBody      `json:"body"`
+	URL    postmanURL        `json:"url"`
+	Auth   *postmanAuth      `json:"auth"`
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"` // raw, urlencoded, formdata, graphql
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// postmanURL задаётся строкой или объектом с полем raw
+type postmanURL struct {
+	Raw string
+}
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &u.Raw); err == nil {
+		return nil
+	}
+	var obj struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	u.Raw = obj.Raw
+	return nil
+}
+
+// postmanAuth — параметры авторизации; значения каждого типа заданы списком key/value
+type postmanAuth struct {
+	Type   string            `json:"type"` // bearer, basic, apikey, noauth
+	Bearer []postmanKeyValue `json:"bearer"`
+	Basic  []postmanKeyValue `json:"basic"`
+	APIKey []postmanKeyValue `json:"apikey"`
+}
+
+var postmanVariable = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+func loadPostmanCollection(path string) (postmanCollection, error) {
+	var c postmanCollection
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// targets превращает запросы коллекции в цели; имена содержат путь по папкам.
+// Переменные коллекции подставляются, неизвестные {{переменные}} остаются как есть.
+func (c postmanCollection) targets() ([]Target, error) {
+	vars := make(map[string]string)
+	for _, v := range c.Variable {
+		vars[v.Key] = v.Value
+	}
+	expand := func(s string) string {
+		return postmanVariable.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := vars[postmanVariable.FindStringSubmatch(m)[1]]; ok {
+				return v
+			}
+			return m
+		})
+	}
+
+	var targets []Target
+	var walk func(items []postmanItem, prefix string, auth *postmanAuth) error
+	walk = func(items []postmanItem, prefix string, auth *postmanAuth) error {
+		for _, item := range items {
+			name := item.Name
+			if prefix != "" {
+				name = prefix + " / " + name
+			}
+			itemAuth := auth
+			if item.Auth != nil {
+				itemAuth = item.Auth
+			}
+
+			if item.Request == nil {
+				if err := walk(item.Item, name, itemAuth); err != nil {
+					return err
+				}
+				continue
+			}
+			if item.Request.Auth != nil {
+				itemAuth = item.Request.Auth
+			}
+			t, err := item.Request.target(name, itemAuth, expand)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			targets = append(targets, t)
+		}
+		return nil
+	}
+
+	if err := walk(c.Item, "", c.Auth); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *postmanRequest) target(name string, auth *postmanAuth, expand func(string) string) (Target, error) {
+	t := Target{Name: name, Method: strings.ToUpper(r.Method), URL: expand(r.URL.Raw)}
+	if t.Method == "" {
+		t.Method = "GET"
+	}
+
+	for _, h := range r.Header {
+		if !h.Disabled {
+			setHeader(&t, h.Key, expand(h.Value))
+		}
+	}
+
+	if b := r.Body; b != nil {
+		switch b.Mode {
+		case "raw":
+			t.Body = expand(b.Raw)
+			if b.Options.Raw.Language == "json" && !hasHeader(t, "Content-Type") {
+				setHeader(&t, "Content-Type", "application/json")
+			}
+		case "urlencoded", "formdata":
+			// Файловые поля formdata не переносятся; остальные отправляются как форма
+			form := url.Values{}
+			fields := b.URLEncoded
+			if b.Mode == "formdata" {
+				fields = b.FormData
+			}
+			for _, f := range fields {
+				if !f.Disabled {
+					form.Add(f.Key, expand(f.Value))
+				}
+			}
+			t.Body = form.Encode()
+			setHeader(&t, "Content-Type", "application/x-www-form-urlencoded")
+		case "graphql":
+			if b.GraphQL == nil {
+				break
+			}
+			t.Type = CheckTypeGraphQL
+			t.GraphQL = &GraphQLCheck{Query: b.GraphQL.Query}
+			if vars := strings.TrimSpace(expand(b.GraphQL.Variables)); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &t.GraphQL.Variables); err != nil {
+					return t, fmt.Errorf("переменные graphql: %w", err)
+				}
+			}
+		}
+	}
+
+	if err := applyPostmanAuth(&t, auth, expand); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+func applyPostmanAuth(t *Target, auth *postmanAuth, expand func(string) string) error {
+	if auth == nil {
+		return nil
+	}
+	value := func(list []postmanKeyValue, key string) string {
+		for _, kv := range list {
+			if kv.Key == key {
+				return expand(kv.Value)
+			}
+		}
+		return ""
+	}
+
+	switch auth.Type {
+	case "", "noauth":
+	case "bearer":
+		setHeader(t, "Authorization", "Bearer "+value(auth.Bearer, "token"))
+	case "basic":
+		creds := value(auth.Basic, "username") + ":" + value(auth.Basic, "password")
+		setHeader(t, "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+	case "apikey":
+		key, val := value(auth.APIKey, "key"), value(auth.APIKey, "value")
+		if value(auth.APIKey, "in") == "query" {
+			sep := "?"
+			if strings.Contains(t.URL, "?") {
+				sep = "&"
+			}
+			t.URL += sep + url.QueryEscape(key) + "=" + url.QueryEscape(val)
+		} else {
+			setHeader(t, key, val)
+		}
+	default:
+		return fmt.Errorf("тип авторизации %q не поддерживается", auth.Type)
+	}
+	return nil
+}
+
+func hasHeader(t Target, name string) bool {
+	for k := range t.Headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
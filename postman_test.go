@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPostmanCollectionTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		want       []Target
+	}{
+		{
+			name: "URL строкой и объектом, переменные коллекции",
+			collection: `{
+				"variable": [{"key": "base", "value": "https://api.example.com"}],
+				"item": [
+					{"name": "list", "request": {"url": "{{base}}/items?page={{page}}"}},
+					{"name": "one", "request": {"method": "delete", "url": {"raw": "{{ base }}/items/1"}}}
+				]
+			}`,
+			want: []Target{
+				{Name: "list", Method: "GET", URL: "https://api.example.com/items?page={{page}}"},
+				{Name: "one", Method: "DELETE", URL: "https://api.example.com/items/1"},
+			},
+		},
+		{
+			name: "папки и наследование авторизации",
+			collection: `{
+				"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "t0"}]},
+				"item": [{
+					"name": "users",
+					"auth": {"type": "basic", "basic": [{"key": "username", "value": "u"}, {"key": "password", "value": "p"}]},
+					"item": [
+						{"name": "get", "request": {"url": "https://a/u"}},
+						{"name": "open", "request": {"url": "https://a/o", "auth": {"type": "noauth"}}}
+					]
+				}, {
+					"name": "root", "request": {"url": "https://a/r"}
+				}]
+			}`,
+			want: []Target{
+				{Name: "users / get", Method: "GET", URL: "https://a/u", Headers: map[string]string{"Authorization": "Basic dTpw"}},
+				{Name: "users / open", Method: "GET", URL: "https://a/o"},
+				{Name: "root", Method: "GET", URL: "https://a/r", Headers: map[string]string{"Authorization": "Bearer t0"}},
+			},
+		},
+		{
+			name: "API-ключ в заголовке и в строке запроса",
+			collection: `{
+				"item": [
+					{"name": "h", "request": {"url": "https://a/h", "auth": {"type": "apikey", "apikey": [
+						{"key": "key", "value": "X-Key"}, {"key": "value", "value": "s"}]}}},
+					{"name": "q", "request": {"url": "https://a/q?x=1", "auth": {"type": "apikey", "apikey": [
+						{"key": "key", "value": "api key"}, {"key": "value", "value": "s&1"}, {"key": "in", "value": "query"}]}}}
+				]
+			}`,
+			want: []Target{
+				{Name: "h", Method: "GET", URL: "https://a/h", Headers: map[string]string{"X-Key": "s"}},
+				{Name: "q", Method: "GET", URL: "https://a/q?x=1&api+key=s%261"},
+			},
+		},
+		{
+			name: "тела raw, urlencoded и formdata, отключённые поля",
+			collection: `{
+				"item": [
+					{"name": "raw", "request": {"method": "POST", "url": "https://a/",
+						"header": [{"key": "X-Off", "value": "1", "disabled": true}],
+						"body": {"mode": "raw", "raw": "{\"a\":1}", "options": {"raw": {"language": "json"}}}}},
+					{"name": "raw-ct", "request": {"method": "POST", "url": "https://a/",
+						"header": [{"key": "content-type", "value": "text/plain"}],
+						"body": {"mode": "raw", "raw": "x", "options": {"raw": {"language": "json"}}}}},
+					{"name": "form", "request": {"method": "POST", "url": "https://a/",
+						"body": {"mode": "urlencoded", "urlencoded": [{"key": "b", "value": "2"}, {"key": "a", "value": "1 2"}, {"key": "c", "disabled": true}]}}},
+					{"name": "multipart", "request": {"method": "POST", "url": "https://a/",
+						"body": {"mode": "formdata", "formdata": [{"key": "f", "value": "v"}]}}}
+				]
+			}`,
+			want: []Target{
+				{Name: "raw", Method: "POST", URL: "https://a/", Body: `{"a":1}`, Headers: map[string]string{"Content-Type": "application/json"}},
+				{Name: "raw-ct", Method: "POST", URL: "https://a/", Body: "x", Headers: map[string]string{"content-type": "text/plain"}},
+				{Name: "form", Method: "POST", URL: "https://a/", Body: "a=1+2&b=2", Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}},
+				{Name: "multipart", Method: "POST", URL: "https://a/", Body: "f=v", Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}},
+			},
+		},
+		{
+			name: "тело graphql",
+			collection: `{
+				"variable": [{"key": "id", "value": "7"}],
+				"item": [{"name": "gql", "request": {"method": "POST", "url": "https://a/graphql",
+					"body": {"mode": "graphql", "graphql": {"query": "query($id: ID!) { user(id: $id) { name } }", "variables": "{\"id\": \"{{id}}\"}"}}}}]
+			}`,
+			want: []Target{{
+				Name:   "gql",
+				Method: "POST",
+				URL:    "https://a/graphql",
+				Type:   CheckTypeGraphQL,
+				GraphQL: &GraphQLCheck{
+					Query:     "query($id: ID!) { user(id: $id) { name } }",
+					Variables: map[string]interface{}{"id": "7"},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c postmanCollection
+			if err := json.Unmarshal([]byte(tt.collection), &c); err != nil {
+				t.Fatalf("разбор коллекции: %v", err)
+			}
+			got, err := c.targets()
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("получено\n%+v\nожидалось\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostmanCollectionTargetsErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+	}{
+		{"неизвестный тип авторизации", `{"item": [{"name": "a", "request": {"url": "https://a/", "auth": {"type": "oauth2"}}}]}`},
+		{"некорректные переменные graphql", `{"item": [{"name": "a", "request": {"url": "https://a/",
+			"body": {"mode": "graphql", "graphql": {"query": "{ a }", "variables": "{"}}}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c postmanCollection
+			if err := json.Unmarshal([]byte(tt.collection), &c); err != nil {
+				t.Fatalf("разбор коллекции: %v", err)
+			}
+			if got, err := c.targets(); err == nil {
+				t.Errorf("ожидалась ошибка, получено %+v", got)
+			}
+		})
+	}
+}
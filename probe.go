@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runProbe реализует подкоманду probe: каждая цель проверяется один раз, результат выводится
+// по строке на цель. Возвращает true, если хотя бы одна цель не прошла проверку или не
+// успела за -timeout — код выхода подходит для exec-проб Kubernetes и CronJob.
+func runProbe(args []string) (bool, error) {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	configPath := fs.String("config", "", "Конфигурация с целями")
+	rawURL := fs.String("url", "", "Проверить один URL вместо целей из -config")
+	names := fs.String("target", "", "Проверять только перечисленные через запятую цели из -config")
+	timeout := fs.Duration("timeout", 0, "Общий предел времени на все проверки, 0 — без предела")
+	quiet := fs.Bool("quiet", false, "Выводить только не прошедшие проверку цели")
+	fs.Parse(args)
+
+	var targets []Target
+	switch {
+	case *rawURL != "":
+		t, err := derivedTarget(Target{Method: "GET", Timeout: 10 * time.Second}, "probe", *rawURL)
+		if err != nil {
+			return false, err
+		}
+		targets = []Target{t}
+	case *configPath != "":
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return false, err
+		}
+		targets, err = selectTargets(cfg.Targets, *names)
+		if err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("использование: probe -config файл [-target имя,...] | probe -url url")
+	}
+
+	// Проверки, не завершившиеся за -timeout, прерываются при выходе
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan CheckResult, len(targets))
+	for _, t := range targets {
+		go func(t Target) { results <- executeCheck(ctx, t) }(t)
+	}
+
+	var deadline <-chan time.Time
+	if *timeout > 0 {
+		deadline = time.After(*timeout)
+	}
+	byName := make(map[string]CheckResult, len(targets))
+collect:
+	for range targets {
+		select {
+		case r := <-results:
+			byName[r.Target] = r
+		case <-deadline:
+			break collect
+		}
+	}
+
+	failed := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range targets {
+		r, ok := byName[t.Name]
+		switch {
+		case !ok:
+			failed = true
+			fmt.Fprintf(w, "FAIL\t%s\t-\t-\tне завершена за %v\n", t.Name, *timeout)
+		case !r.Success:
+			failed = true
+			fmt.Fprintf(w, "FAIL\t%s\t%d\t%v\t%s\n", t.Name, r.Status, roundLatency(r.Latency), truncate(r.Error, 120))
+		case !*quiet:
+			fmt.Fprintf(w, "ok\t%s\t%d\t%v\n", t.Name, r.Status, roundLatency(r.Latency))
+		}
+	}
+	w.Flush()
+	return failed, nil
+}
+
+// selectTargets оставляет цели с перечисленными через запятую именами; пустой список — все цели
+func selectTargets(targets []Target, names string) ([]Target, error) {
+	if names == "" {
+		return targets, nil
+	}
+	var out []Target
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, t := range targets {
+			if t.Name == name {
+				out = append(out, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("цель %s не найдена", name)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Цвета вывода в терминал
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+// colorOutput включает цвета в итоговых таблицах; выставляется в main,
+// если вывод идёт в терминал и не задан -no-color или NO_COLOR
+var colorOutput bool
+
+// isTerminal сообщает, подключён ли файл к терминалу
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize окрашивает строку, если цвета включены
+func colorize(s, color string) string {
+	if !colorOutput || color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// rateColor выбирает цвет по проценту успешных проверок: пороги те же, что у шкалы дашборда
+func rateColor(rate float64) string {
+	switch {
+	case rate >= 99:
+		return colorGreen
+	case rate >= 90:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// printColoredTable выводит таблицу, выровненную tabwriter, окрашивая строки целиком:
+// коды цвета внутри ячеек сбили бы выравнивание. colors — цвета строк после заголовка.
+func printColoredTable(out io.Writer, table *bytes.Buffer, colors []string) {
+	lines := strings.Split(strings.TrimRight(table.String(), "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			line = colorize(line, colorBold)
+		case i-1 < len(colors):
+			line = colorize(line, colors[i-1])
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+const (
+	progressBarWidth = 30
+	// Число последних задержек, по которым считается текущий p95
+	progressLatencies = 1000
+	progressRedraw    = 100 * time.Millisecond
+)
+
+// progressBar показывает в терминале ход проверок: долю выполненных, процент успешных
+// и p95 последних задержек. Журнал выводится через progressBar, чтобы строки
+// журнала не перемешивались со строкой прогресса.
+type progressBar struct {
+	out   io.Writer
+	total int // -1 — число проверок заранее неизвестно
+
+	mu         sync.Mutex
+	done       int
+	successful int
+	latencies  []time.Duration
+	drawn      time.Time
+	visible    bool
+}
+
+func newProgressBar(out io.Writer, total int) *progressBar {
+	return &progressBar{out: out, total: total}
+}
+
+// expectedChecks возвращает общее число проверок запуска или -1, если оно не ограничено
+func expectedChecks(targets []Target, defaultChecks int) int {
+	total := 0
+	for _, t := range targets {
+		if t.Duration > 0 {
+			return -1
+		}
+		n := t.Checks
+		if n == 0 {
+			n = defaultChecks
+		}
+		if n <= 0 {
+			return -1
+		}
+		total += n
+	}
+	return total
+}
+
+func (p *progressBar) Observe(r CheckResult) {
+	if r.Shed {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if r.Success {
+		p.successful++
+	}
+	p.latencies = append(p.latencies, r.Latency)
+	if len(p.latencies) > progressLatencies {
+		p.latencies = p.latencies[len(p.latencies)-progressLatencies:]
+	}
+	if time.Since(p.drawn) >= progressRedraw || p.done == p.total {
+		p.draw()
+	}
+}
+
+// Write выводит строки журнала над строкой прогресса
+func (p *progressBar) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	n, err := p.out.Write(b)
+	if p.done > 0 {
+		p.draw()
+	}
+	return n, err
+}
+
+// Close убирает строку прогресса перед итоговым отчётом
+func (p *progressBar) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+func (p *progressBar) clear() {
+	if p.visible {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.visible = false
+	}
+}
+
+func (p *progressBar) draw() {
+	sorted := append([]time.Duration(nil), p.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rate := float64(p.successful) / float64(p.done) * 100
+
+	var line string
+	if p.total > 0 {
+		filled := min(p.done*progressBarWidth/p.total, progressBarWidth)
+		line = fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled), p.done, p.total)
+	} else {
+		line = fmt.Sprintf("проверок %d", p.done)
+	}
+	line += fmt.Sprintf("  успешных %s  p95 %v", colorize(fmt.Sprintf("%.2f%%", rate), rateColor(rate)),
+		percentile(sorted, 95).Round(time.Millisecond))
+
+	fmt.Fprint(p.out, "\r\x1b[K"+line)
+	p.visible = true
+	p.drawn = time.Now()
+}
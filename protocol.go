@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Значения http_version цели
+const (
+	HTTPVersion1       = "1.1"
+	HTTPVersion2       = "2"
+	HTTPVersion3       = "3"
+	HTTPVersionCompare = "compare" // цель проверяется по HTTP/1.1 и HTTP/2 отдельно
+)
+
+// comparedHTTPVersions — версии, по которым проверяется цель с http_version: compare
+var comparedHTTPVersions = []string{HTTPVersion1, HTTPVersion2}
+
+// expandProtocols заменяет цель с http_version: compare целями по каждой версии HTTP
+func (t Target) expandProtocols() []Target {
+	if t.HTTPVersion != HTTPVersionCompare {
+		return []Target{t}
+	}
+	name := t.Name
+	if name == "" {
+		name = t.URL
+	}
+
+	out := make([]Target, 0, len(comparedHTTPVersions))
+	for _, v := range comparedHTTPVersions {
+		vt := t
+		vt.Name = fmt.Sprintf("%s [%s]", name, protocolLabel(v))
+		vt.HTTPVersion = v
+		vt.protocolName = name
+		out = append(out, vt)
+	}
+	return out
+}
+
+// protocolLabel возвращает краткое имя версии HTTP: h1, h2, h3
+func protocolLabel(version string) string {
+	return "h" + strings.TrimSuffix(version, ".1")
+}
+
+// checkHTTPVersion проверяет значение http_version нормализуемой цели
+func (t Target) checkHTTPVersion() error {
+	switch t.HTTPVersion {
+	case "", HTTPVersion1:
+	case HTTPVersion2:
+		if strings.HasPrefix(t.URL, "http://") {
+			return fmt.Errorf("http_version: 2 без TLS (h2c) не поддерживается, нужен https")
+		}
+	case HTTPVersion3:
+		return fmt.Errorf("http_version: 3 не поддерживается: в сборке нет реализации QUIC")
+	case HTTPVersionCompare:
+		return fmt.Errorf("http_version: compare поддерживается только в файле конфигурации и флагом -http-version")
+	default:
+		return fmt.Errorf("неизвестное значение http_version %q", t.HTTPVersion)
+	}
+	return nil
+}
+
+// applyHTTPVersion настраивает транспорт на версию HTTP цели
+func applyHTTPVersion(tr *http.Transport, version string) {
+	switch version {
+	case HTTPVersion1:
+		// Непустой TLSNextProto без h2 отключает HTTP/2
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+		tr.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	case HTTPVersion2:
+		tr.ForceAttemptHTTP2 = true
+	}
+}
+
+// negotiatedVersionError сообщает, что сервер ответил не по требуемой версии HTTP
+func (t Target) negotiatedVersionError(resp *http.Response) error {
+	want := 0
+	switch t.HTTPVersion {
+	case HTTPVersion1:
+		want = 1
+	case HTTPVersion2:
+		want = 2
+	}
+	if want == 0 || resp.ProtoMajor == want {
+		return nil
+	}
+	return fmt.Errorf("сервер ответил по %s вместо HTTP/%s", resp.Proto, t.HTTPVersion)
+}
+
+// protocolComparison — результаты одной цели по разным версиям HTTP
+type protocolComparison struct {
+	Name     string
+	Versions []string
+	Stats    map[string]latencyStats
+}
+
+// compareProtocols сопоставляет результаты целей, проверяемых по нескольким версиям HTTP
+func compareProtocols(targets []Target, results []CheckResult) []protocolComparison {
+	_, groups := groupByTarget(results)
+
+	var out []protocolComparison
+	index := make(map[string]int)
+	merged := make(map[string]map[string][]CheckResult)
+	for _, t := range targets {
+		if t.protocolName == "" {
+			continue
+		}
+		i, ok := index[t.protocolName]
+		if !ok {
+			i = len(out)
+			index[t.protocolName] = i
+			out = append(out, protocolComparison{Name: t.protocolName, Stats: make(map[string]latencyStats)})
+			merged[t.protocolName] = make(map[string][]CheckResult)
+		}
+		// Варианты по IPv4 и IPv6 одной версии HTTP объединяются
+		m := merged[t.protocolName]
+		if _, ok := m[t.HTTPVersion]; !ok {
+			out[i].Versions = append(out[i].Versions, t.HTTPVersion)
+		}
+		m[t.HTTPVersion] = append(m[t.HTTPVersion], groups[t.Name]...)
+	}
+
+	for i := range out {
+		c := &out[i]
+		for v, rs := range merged[c.Name] {
+			c.Stats[v] = computeStats(rs)
+		}
+	}
+	return out
+}
+
+// printProtocolComparison выводит задержки целей по версиям HTTP и разницу с HTTP/1.1
+func printProtocolComparison(out io.Writer, comparisons []protocolComparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nСравнение версий HTTP:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tверсия\tпроверок\tуспешных\tp50\tp95\tp50 к h1")
+	for _, c := range comparisons {
+		base := c.Stats[HTTPVersion1]
+		for _, v := range c.Versions {
+			st := c.Stats[v]
+			diff := "-"
+			if v != HTTPVersion1 && base.Successful > 0 && st.Successful > 0 {
+				d := (st.P50 - base.P50).Round(time.Millisecond)
+				diff = d.String()
+				if d > 0 {
+					diff = "+" + diff
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.2f%%\t%v\t%v\t%s\n", c.Name, protocolLabel(v), st.Checks,
+				st.SuccessRate(), st.P50.Round(time.Millisecond), st.P95.Round(time.Millisecond), diff)
+		}
+	}
+	w.Flush()
+}
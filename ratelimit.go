@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// RateLimitPolicy — реакция цели на ограничение частоты запросов: ответы 429
+// и 503 с заголовком Retry-After
+type RateLimitPolicy struct {
+	// Приостанавливать проверки цели на время из Retry-After и не повторять такие проверки
+	HonorRetryAfter bool `yaml:"honor_retry_after,omitempty"`
+	// Наибольшая пауза, по умолчанию 5m
+	MaxPause time.Duration `yaml:"max_pause,omitempty"`
+}
+
+const defaultRateLimitMaxPause = 5 * time.Minute
+
+// rateLimitState — время, до которого проверки цели приостановлены; общее для копий цели
+type rateLimitState struct {
+	maxPause time.Duration
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (t *Target) compileRateLimit() error {
+	t.rateLimit = nil
+	if t.RateLimit == nil || !t.RateLimit.HonorRetryAfter {
+		return nil
+	}
+	if t.RateLimit.MaxPause < 0 {
+		return fmt.Errorf("rate_limit.max_pause не может быть отрицательным")
+	}
+	maxPause := t.RateLimit.MaxPause
+	if maxPause == 0 {
+		maxPause = defaultRateLimitMaxPause
+	}
+	t.rateLimit = &rateLimitState{maxPause: maxPause}
+	return nil
+}
+
+// honorsRetryAfter сообщает, что результат ограничен по частоте и цель ждёт Retry-After
+func (t Target) honorsRetryAfter(r CheckResult) bool {
+	return t.rateLimit != nil && r.RateLimited
+}
+
+// observeRateLimit приостанавливает цель на время Retry-After ограниченного ответа
+func (t Target) observeRateLimit(r CheckResult) {
+	if !t.honorsRetryAfter(r) || r.RetryAfter <= 0 {
+		return
+	}
+	pause := min(r.RetryAfter, t.rateLimit.maxPause)
+	until := time.Now().Add(pause)
+
+	s := t.rateLimit
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until.After(s.until) {
+		s.until = until
+		slog.Warn("Проверки цели приостановлены по Retry-After", "target", t.Name, "pause", pause)
+	}
+}
+
+// pausedUntil возвращает время, до которого проверки цели приостановлены
+func (t Target) pausedUntil() time.Time {
+	if t.rateLimit == nil {
+		return time.Time{}
+	}
+	t.rateLimit.mu.Lock()
+	defer t.rateLimit.mu.Unlock()
+	return t.rateLimit.until
+}
+
+// applyRateLimit отмечает ответ 429 или 503 с Retry-After как ограниченный по частоте
+// и сохраняет заголовки лимитов (X-RateLimit-*, RateLimit-*)
+func applyRateLimit(resp *http.Response, r *CheckResult) {
+	retryAfter := resp.Header.Get("Retry-After")
+	if resp.StatusCode != http.StatusTooManyRequests && (resp.StatusCode != http.StatusServiceUnavailable || retryAfter == "") {
+		return
+	}
+	r.RateLimited = true
+	r.RetryAfter = parseRetryAfter(retryAfter, time.Now())
+	for name, values := range resp.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-ratelimit-") || strings.HasPrefix(lower, "ratelimit") {
+			if r.RateLimit == nil {
+				r.RateLimit = make(map[string]string)
+			}
+			r.RateLimit[name] = strings.Join(values, ", ")
+		}
+	}
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в виде HTTP-даты; 0 — заголовок
+// не задан или не разобран
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now).Round(time.Second)
+	}
+	return 0
+}
+
+// rateLimitError — ошибка ограниченного по частоте ответа
+func rateLimitError(r CheckResult, status string) error {
+	if r.RetryAfter > 0 {
+		return fmt.Errorf("ограничение частоты запросов: %s, Retry-After %v", status, r.RetryAfter)
+	}
+	return fmt.Errorf("ограничение частоты запросов: %s", status)
+}
+
+// rateLimitSummary — ограниченные по частоте ответы цели
+type rateLimitSummary struct {
+	Name       string
+	Count      int
+	RetryAfter time.Duration     // наибольший Retry-After
+	Headers    map[string]string // заголовки лимитов последнего такого ответа
+}
+
+func summarizeRateLimits(results []CheckResult) []rateLimitSummary {
+	names, groups := groupByTarget(results)
+
+	var out []rateLimitSummary
+	for _, name := range names {
+		s := rateLimitSummary{Name: name}
+		for _, r := range groups[name] {
+			if !r.RateLimited {
+				continue
+			}
+			s.Count++
+			s.RetryAfter = max(s.RetryAfter, r.RetryAfter)
+			if len(r.RateLimit) > 0 {
+				s.Headers = r.RateLimit
+			}
+		}
+		if s.Count > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// printRateLimits выводит ограниченные по частоте ответы отдельно от остальных сбоев
+func printRateLimits(out io.Writer, summaries []rateLimitSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nОграничение частоты запросов (429 и 503 с Retry-After):")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tответов\tRetry-After\tлимиты")
+	for _, s := range summaries {
+		retryAfter := "-"
+		if s.RetryAfter > 0 {
+			retryAfter = s.RetryAfter.String()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", s.Name, s.Count, retryAfter, formatRateLimitHeaders(s.Headers))
+	}
+	w.Flush()
+}
+
+func formatRateLimitHeaders(headers map[string]string) string {
+	if len(headers) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + headers[name]
+	}
+	return strings.Join(parts, " ")
+}
+
+func countRateLimited(results []CheckResult) int {
+	n := 0
+	for _, r := range results {
+		if r.RateLimited {
+			n++
+		}
+	}
+	return n
+}
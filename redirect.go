@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RedirectPolicy — правила перенаправлений цели
+type RedirectPolicy struct {
+	// Следовать перенаправлениям; по умолчанию да
+	Follow *bool `yaml:"follow,omitempty"`
+	// Максимум перенаправлений подряд, по умолчанию 10
+	MaxHops int `yaml:"max_hops,omitempty"`
+	// true — итоговый ответ 3xx (без следования) считается успехом;
+	// false — любое перенаправление делает проверку неуспешной
+	Success *bool `yaml:"success,omitempty"`
+}
+
+const defaultMaxRedirects = 10
+
+// RedirectHop — перенаправление в цепочке запроса
+type RedirectHop struct {
+	URL     string        `json:"url"`
+	Status  int           `json:"status"`
+	Latency time.Duration `json:"latency"` // от отправки запроса до получения перенаправления
+}
+
+func (p *RedirectPolicy) compile() error {
+	if p.MaxHops < 0 {
+		return fmt.Errorf("redirects.max_hops не может быть отрицательным")
+	}
+	return nil
+}
+
+func (t Target) followRedirects() bool {
+	return t.Redirects == nil || t.Redirects.Follow == nil || *t.Redirects.Follow
+}
+
+// redirectRecorder записывает цепочку перенаправлений запроса
+type redirectRecorder struct {
+	maxHops int
+	follow  bool
+	last    time.Time
+	hops    []RedirectHop
+	// Cookie, установленные ответами-перенаправлениями
+	cookies []*http.Cookie
+}
+
+func (t Target) newRedirectRecorder(started time.Time) *redirectRecorder {
+	r := &redirectRecorder{maxHops: defaultMaxRedirects, follow: t.followRedirects(), last: started}
+	if t.Redirects != nil && t.Redirects.MaxHops > 0 {
+		r.maxHops = t.Redirects.MaxHops
+	}
+	return r
+}
+
+// checkRedirect подходит для http.Client.CheckRedirect
+func (r *redirectRecorder) checkRedirect(req *http.Request, via []*http.Request) error {
+	now := time.Now()
+	hop := RedirectHop{URL: via[len(via)-1].URL.String(), Latency: now.Sub(r.last)}
+	if req.Response != nil {
+		hop.Status = req.Response.StatusCode
+	}
+	r.hops = append(r.hops, hop)
+	r.last = now
+
+	if !r.follow {
+		// Ответ-перенаправление становится итоговым и в цепочку не попадает
+		r.hops = r.hops[:len(r.hops)-1]
+		return http.ErrUseLastResponse
+	}
+	if len(via) > r.maxHops {
+		return fmt.Errorf("больше %d перенаправлений подряд", r.maxHops)
+	}
+	if req.Response != nil {
+		r.cookies = append(r.cookies, req.Response.Cookies()...)
+	}
+	return nil
+}
+
+// apply записывает цепочку и общее время перенаправлений в результат
+func (r *redirectRecorder) apply(result *CheckResult) {
+	if len(r.hops) == 0 {
+		return
+	}
+	result.Redirects = r.hops
+	for _, h := range r.hops {
+		result.RedirectLatency += h.Latency
+	}
+}
+
+// redirectVerdict оценивает итоговый ответ по правилу success перенаправлений цели;
+// handled сообщает, что статус ответа оценён этим правилом
+func (t Target) redirectVerdict(resp *http.Response, hops int) (handled bool, err error) {
+	if t.Redirects == nil || t.Redirects.Success == nil {
+		return false, nil
+	}
+	switch {
+	case !*t.Redirects.Success && hops > 0:
+		return true, fmt.Errorf("перенаправление на %s не допускается", resp.Request.URL)
+	case !*t.Redirects.Success && isRedirect(resp.StatusCode):
+		return true, fmt.Errorf("перенаправление на %s не допускается", resp.Header.Get("Location"))
+	case *t.Redirects.Success && isRedirect(resp.StatusCode):
+		return true, nil
+	}
+	return false, nil
+}
+
+func isRedirect(status int) bool {
+	return status >= 300 && status < 400
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ResponseReference — эталонный ответ цели: тело ответа сравнивается с JSON-документом
+// из файла, и каждое расхождение выводится с путём поля
+type ResponseReference struct {
+	File string `yaml:"file"`
+	// Пути, которые не сравниваются, вместе с вложенными полями, например $.meta.requested_at;
+	// [*] — любой элемент массива, .* — любое поле объекта
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Допустимое отличие чисел по модулю
+	Tolerance float64 `yaml:"tolerance,omitempty"`
+}
+
+// responseReference — загруженный эталон и скомпилированные пути исключений
+type responseReference struct {
+	doc       interface{}
+	ignore    []*regexp.Regexp
+	tolerance float64
+}
+
+// compileReference загружает эталонный ответ цели
+func (t *Target) compileReference() error {
+	t.reference = nil
+	if t.Reference == nil {
+		return nil
+	}
+	if t.Reference.File == "" {
+		return fmt.Errorf("reference: не указан file")
+	}
+	if t.Reference.Tolerance < 0 {
+		return fmt.Errorf("reference.tolerance не может быть отрицательным")
+	}
+
+	doc, err := loadSchemaDocument(t.Reference.File)
+	if err != nil {
+		return fmt.Errorf("reference: %w", err)
+	}
+	ref := &responseReference{doc: doc, tolerance: t.Reference.Tolerance}
+	for _, path := range t.Reference.Ignore {
+		re, err := ignorePattern(path)
+		if err != nil {
+			return fmt.Errorf("reference.ignore: %w", err)
+		}
+		ref.ignore = append(ref.ignore, re)
+	}
+	t.reference = ref
+	return nil
+}
+
+// ignorePattern превращает путь вида $.items[*].id в регулярное выражение
+func ignorePattern(path string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("путь %q должен начинаться с $", path)
+	}
+	p := regexp.QuoteMeta(path)
+	p = strings.ReplaceAll(p, `\[\*\]`, `\[\d+\]`)
+	p = strings.ReplaceAll(p, `\.\*`, `\.[^.\[]+`)
+	return regexp.Compile("^" + p + `($|[.\[])`)
+}
+
+func (r *responseReference) ignored(path string) bool {
+	for _, re := range r.ignore {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// check сравнивает тело ответа с эталоном и возвращает все расхождения
+func (r *responseReference) check(body []byte) ([]string, error) {
+	doc, err := parseJSONBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := r.diff(r.doc, doc, "$")
+	if len(diff) == 0 {
+		return nil, nil
+	}
+
+	msg := "ответ отличается от эталона: " + strings.Join(diff[:min(len(diff), maxReportedViolations)], "; ")
+	if extra := len(diff) - maxReportedViolations; extra > 0 {
+		msg += fmt.Sprintf(" и ещё %d", extra)
+	}
+	return diff, errors.New(msg)
+}
+
+// diff рекурсивно сравнивает ожидаемое и полученное значения
+func (r *responseReference) diff(want, got interface{}, path string) []string {
+	if r.ignored(path) {
+		return nil
+	}
+	if jsonTypeName(want) != jsonTypeName(got) {
+		return []string{fmt.Sprintf("%s: тип %s, ожидался %s", path, jsonTypeName(got), jsonTypeName(want))}
+	}
+
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g := got.(map[string]interface{})
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		var out []string
+		for _, k := range keys {
+			sub := path + "." + k
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case r.ignored(sub):
+			case !inGot:
+				out = append(out, sub+": поле отсутствует")
+			case !inWant:
+				out = append(out, sub+": лишнее поле")
+			default:
+				out = append(out, r.diff(wv, gv, sub)...)
+			}
+		}
+		return out
+
+	case []interface{}:
+		g := got.([]interface{})
+		var out []string
+		if len(w) != len(g) {
+			out = append(out, fmt.Sprintf("%s: элементов %d, ожидалось %d", path, len(g), len(w)))
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			out = append(out, r.diff(w[i], g[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return out
+
+	case float64:
+		if math.Abs(w-got.(float64)) > r.tolerance {
+			return []string{fmt.Sprintf("%s: значение %s, ожидалось %s", path, jsonText(got), jsonText(w))}
+		}
+		return nil
+	}
+
+	if !jsonEqual(want, got) {
+		return []string{fmt.Sprintf("%s: значение %s, ожидалось %s", path, jsonText(got), jsonText(want))}
+	}
+	return nil
+}
+
+// jsonText записывает значение так, как оно выглядит в JSON
+func jsonText(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
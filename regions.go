@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Регион результатов, полученных без -region
+const localRegion = "local"
+
+// Число последних результатов каждой цели в каждом регионе, по которым считается сводка
+const regionWindow = 1000
+
+// agentAssignment — цели и интервал, которые координатор выдаёт агентам
+type agentAssignment struct {
+	Interval time.Duration `yaml:"interval"`
+	Targets  []Target      `yaml:"targets"`
+}
+
+// agentReport — пачка результатов, присланная агентом
+type agentReport struct {
+	Agent   string        `json:"agent"`
+	Region  string        `json:"region"`
+	Results []CheckResult `json:"results"`
+}
+
+// AgentInfo — агент, присылавший результаты координатору
+type AgentInfo struct {
+	Name     string    `json:"name"`
+	Region   string    `json:"region"`
+	LastSeen time.Time `json:"last_seen"`
+	Results  int       `json:"results"`
+}
+
+// RegionSummary — доступность и задержки цели, измеренные из одного региона
+type RegionSummary struct {
+	Target       string        `json:"target"`
+	Region       string        `json:"region"`
+	Checks       int           `json:"checks"`
+	Availability float64       `json:"availability"`
+	P50          time.Duration `json:"p50"`
+	P95          time.Duration `json:"p95"`
+	Last         time.Time     `json:"last"`
+}
+
+type regionKey struct {
+	Target, Region string
+}
+
+// regionCollector собирает результаты координатора и агентов по регионам
+type regionCollector struct {
+	mu      sync.Mutex
+	local   string
+	windows map[regionKey][]CheckResult
+	agents  map[string]*AgentInfo
+}
+
+func newRegionCollector(local string) *regionCollector {
+	if local == "" {
+		local = localRegion
+	}
+	return &regionCollector{
+		local:   local,
+		windows: make(map[regionKey][]CheckResult),
+		agents:  make(map[string]*AgentInfo),
+	}
+}
+
+func (c *regionCollector) Observe(r CheckResult) {
+	if r.Shed || r.Warmup {
+		return
+	}
+	region := r.Region
+	if region == "" {
+		region = c.local
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := regionKey{r.Target, region}
+	window := append(c.windows[k], r)
+	if len(window) > regionWindow {
+		window = window[len(window)-regionWindow:]
+	}
+	c.windows[k] = window
+}
+
+// seen отмечает агента и сообщает, присылал ли он результаты раньше
+func (c *regionCollector) seen(report agentReport, accepted int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, ok := c.agents[report.Agent]
+	if !ok {
+		a = &AgentInfo{Name: report.Agent}
+		c.agents[report.Agent] = a
+	}
+	a.Region = report.Region
+	a.LastSeen = time.Now()
+	a.Results += accepted
+	return ok
+}
+
+func (c *regionCollector) forget(target string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.windows {
+		if k.Target == target {
+			delete(c.windows, k)
+		}
+	}
+}
+
+// summaries возвращает сводки, упорядоченные по цели и региону
+func (c *regionCollector) summaries() []RegionSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]RegionSummary, 0, len(c.windows))
+	for k, results := range c.windows {
+		st := computeStats(results)
+		out = append(out, RegionSummary{
+			Target:       k.Target,
+			Region:       k.Region,
+			Checks:       st.Checks,
+			Availability: st.SuccessRate(),
+			P50:          st.P50,
+			P95:          st.P95,
+			Last:         results[len(results)-1].Timestamp,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Target != out[j].Target {
+			return out[i].Target < out[j].Target
+		}
+		return out[i].Region < out[j].Region
+	})
+	return out
+}
+
+func (c *regionCollector) agentList() []AgentInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]AgentInfo, 0, len(c.agents))
+	for _, a := range c.agents {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// printRegions выводит сводку по регионам, если результаты приходили больше чем из одного
+func printRegions(out io.Writer, summaries []RegionSummary) {
+	regions := make(map[string]bool)
+	for _, s := range summaries {
+		regions[s.Region] = true
+	}
+	if len(regions) < 2 {
+		return
+	}
+
+	fmt.Fprintln(out, "\nПо регионам:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tрегион\tпроверок\tдоступность\tp50\tp95")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.2f%%\t%v\t%v\n", s.Target, s.Region, s.Checks, s.Availability,
+			s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond))
+	}
+	w.Flush()
+}
+
+// handleAgentTargets выдаёт агенту активные цели в формате конфигурации
+func (api *controlAPI) handleAgentTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+		return
+	}
+	data, err := yaml.Marshal(agentAssignment{Interval: api.interval, Targets: api.registry.active()})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+// handleAgentResults принимает результаты агента; результаты удалённых целей отбрасываются
+func (api *controlAPI) handleAgentResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+		return
+	}
+	var report agentReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if report.Agent == "" {
+		writeError(w, http.StatusBadRequest, "не указано имя агента")
+		return
+	}
+	if report.Region == "" {
+		report.Region = report.Agent
+	}
+
+	accepted := 0
+	for _, res := range report.Results {
+		if _, ok := api.registry.get(res.Target); !ok {
+			continue
+		}
+		if res.Region == "" {
+			res.Region = report.Region
+		}
+		api.regions.Observe(res)
+		if api.onRemote != nil {
+			api.onRemote(res)
+		}
+		accepted++
+	}
+	if !api.regions.seen(report, accepted) {
+		slog.Info("Агент подключился", "agent", report.Agent, "region", report.Region)
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"accepted": accepted})
+}
+
+func (api *controlAPI) handleRegions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"agents":  api.regions.agentList(),
+		"targets": api.regions.summaries(),
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Как часто проверяется, изменился ли файл конфигурации
+const configPollInterval = 2 * time.Second
+
+// configReloader перечитывает конфигурацию режима мониторинга по SIGHUP и при изменении
+// файла. Применяются цели и defaults; текущие проверки не прерываются.
+type configReloader struct {
+	path     string
+	network  networkFlags
+	registry *targetRegistry
+	alerter  *Alerter // может быть nil
+	// Вызываются для удалённых из конфигурации целей
+	forget []func(name string)
+
+	current Config
+	stat    os.FileInfo
+}
+
+func newConfigReloader(path string, network networkFlags, cfg Config, registry *targetRegistry) *configReloader {
+	r := &configReloader{path: path, network: network, registry: registry, current: cfg}
+	r.stat, _ = os.Stat(path)
+	return r
+}
+
+func (r *configReloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Получен SIGHUP: перечитывание конфигурации", "path", r.path)
+			r.reload()
+		case <-ticker.C:
+			if r.modified() {
+				slog.Info("Файл конфигурации изменился: перечитывание", "path", r.path)
+				r.reload()
+			}
+		}
+	}
+}
+
+// modified сообщает, изменились ли время изменения или размер файла с прошлой проверки
+func (r *configReloader) modified() bool {
+	st, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	changed := r.stat == nil || !st.ModTime().Equal(r.stat.ModTime()) || st.Size() != r.stat.Size()
+	r.stat = st
+	return changed
+}
+
+// reload применяет новую конфигурацию; при ошибке продолжает работать прежняя
+func (r *configReloader) reload() {
+	cfg, err := readConfig(r.path, true)
+	if err == nil {
+		err = r.network.apply(&cfg)
+	}
+	if err != nil {
+		slog.Error("Ошибка в новой конфигурации, продолжает действовать прежняя", "path", r.path, "error", err)
+		return
+	}
+
+	changes := diffConfigs(r.current, cfg)
+	if len(changes) == 0 {
+		slog.Debug("Конфигурация не изменилась", "path", r.path)
+		return
+	}
+
+	managed := make(map[string]bool, len(r.current.Targets))
+	for _, t := range r.current.Targets {
+		managed[t.Name] = true
+	}
+	changed, removed := r.registry.update(cfg.Targets, cfg.Defaults, managed)
+	if r.alerter != nil {
+		for _, t := range changed {
+			if err := r.alerter.setTargetNotifiers(t); err != nil {
+				slog.Error("Ошибка в настройках оповещений цели", "target", t.Name, "error", err)
+			}
+		}
+	}
+	for _, name := range removed {
+		for _, fn := range r.forget {
+			fn(name)
+		}
+	}
+
+	// Прочие разделы (оповещения, приёмники, health) настраиваются при запуске
+	oldRest, newRest := r.current, cfg
+	oldRest.Targets, newRest.Targets = nil, nil
+	oldRest.Defaults, newRest.Defaults = TargetDefaults{}, TargetDefaults{}
+	if rest := diffValues(oldRest, newRest); len(rest) > 0 {
+		slog.Warn("Изменения вне targets и defaults вступят в силу после перезапуска", "changes", rest)
+	}
+
+	r.current = cfg
+	slog.Info("Конфигурация перечитана", "targets", len(cfg.Targets), "changed", len(changed), "removed", len(removed))
+	for _, line := range changes {
+		slog.Info("Изменение конфигурации", "change", line)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// incident — период недоступности цели: серия неуспешных проверок подряд
+type incident struct {
+	Start time.Time
+	// Время первой успешной проверки после сбоев; нулевое, если сбой продолжается
+	End      time.Time
+	Failures int
+	Error    string // ошибка первой неуспешной проверки
+}
+
+func (i incident) resolved() bool {
+	return !i.End.IsZero()
+}
+
+// runReport реализует подкоманду report: доступность, MTTR, инциденты и тренд задержек
+// за период по сохранённым результатам — базе SQLite или JSON-файлам запусков
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	dbPath := fs.String("db", "", "Путь к базе результатов")
+	files := fs.String("results", "", "JSON-файлы результатов через запятую, допускаются шаблоны (по умолчанию test_results.json, если не задан -db)")
+	target := fs.String("target", "", "Имя цели (по умолчанию все цели)")
+	since := fs.Duration("since", 24*time.Hour, "Период от текущего момента, если не заданы -from/-to")
+	fromStr := fs.String("from", "", "Начало периода (RFC3339)")
+	toStr := fs.String("to", "", "Конец периода (RFC3339)")
+	bucket := fs.Duration("bucket", time.Hour, "Шаг тренда задержек")
+	configPath := fs.String("config", "", "Файл конфигурации, из которого берутся SLO целей")
+	tags := tagFlags{}
+	fs.Var(tags, "tag", "Только запуски с меткой ключ=значение (для -results), можно повторять")
+	fs.Parse(args)
+
+	from, to, err := parsePeriod(*since, *fromStr, *toStr)
+	if err != nil {
+		return err
+	}
+	if *bucket <= 0 {
+		return fmt.Errorf("-bucket должен быть положительным")
+	}
+	if *dbPath != "" && *files != "" {
+		return fmt.Errorf("нужно задать либо -db, либо -results")
+	}
+
+	slos := make(map[string]*SLOConfig)
+	if *configPath != "" {
+		cfg, err := readConfig(*configPath, false)
+		if err != nil {
+			return err
+		}
+		for _, t := range cfg.Targets {
+			slos[t.Name] = t.SLO
+		}
+	}
+
+	var results []CheckResult
+	if *dbPath != "" {
+		store, err := openStore(*dbPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if results, err = store.Query(*target, from, to); err != nil {
+			return err
+		}
+	} else {
+		if *files == "" {
+			*files = "test_results.json"
+		}
+		if results, err = loadResultFiles(*files, *target, tags, from, to); err != nil {
+			return err
+		}
+	}
+	if len(results) == 0 {
+		fmt.Println("За выбранный период результатов нет.")
+		return nil
+	}
+
+	fmt.Printf("Отчёт за %s — %s\n", from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"))
+	names, groups := groupByTarget(results)
+	for _, name := range names {
+		if err := printTargetReport(os.Stdout, name, groups[name], slos[name], from, to, *bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePeriod возвращает период [from, to): последние since или границы -from/-to
+func parsePeriod(since time.Duration, fromStr, toStr string) (time.Time, time.Time, error) {
+	to := time.Now()
+	from := to.Add(-since)
+	var err error
+	if fromStr != "" {
+		if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+			return from, to, fmt.Errorf("-from: %w", err)
+		}
+	}
+	if toStr != "" {
+		if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+			return from, to, fmt.Errorf("-to: %w", err)
+		}
+	}
+	return from, to, nil
+}
+
+// loadResultFiles читает результаты запусков из JSON-файлов и оставляет относящиеся
+// к цели и периоду, упорядочив по времени
+func loadResultFiles(patterns, target string, tags tagFlags, from, to time.Time) ([]CheckResult, error) {
+	var paths []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		matches, err := filepath.Glob(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("-results: %w", err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("-results: нет файлов, подходящих под %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+
+	var results []CheckResult
+	for _, path := range paths {
+		tr, err := loadTestResult(path)
+		if err != nil {
+			return nil, err
+		}
+		if !tags.matches(tr.Run) {
+			continue
+		}
+		for _, r := range tr.Results {
+			if (target == "" || r.Target == target) && !r.Timestamp.Before(from) && r.Timestamp.Before(to) {
+				results = append(results, r)
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Timestamp.Before(results[j].Timestamp) })
+	return results, nil
+}
+
+// computeIncidents выделяет периоды недоступности по упорядоченным результатам цели;
+// отброшенные и прогревочные проверки, а также сбои во время обслуживания не учитываются
+func computeIncidents(results []CheckResult) []incident {
+	var incidents []incident
+	var open *incident
+	for _, r := range results {
+		if r.Warmup || !r.countsAgainstSLA() {
+			continue
+		}
+		if r.Success {
+			if open != nil {
+				open.End = r.Timestamp
+				open = nil
+			}
+			continue
+		}
+		if open == nil {
+			incidents = append(incidents, incident{Start: r.Timestamp, Error: r.Error})
+			open = &incidents[len(incidents)-1]
+		}
+		open.Failures++
+	}
+	return incidents
+}
+
+// meanTimeToRecovery возвращает среднюю длительность завершившихся инцидентов
+func meanTimeToRecovery(incidents []incident) (time.Duration, bool) {
+	var total time.Duration
+	n := 0
+	for _, i := range incidents {
+		if i.resolved() {
+			total += i.End.Sub(i.Start)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return total / time.Duration(n), true
+}
+
+func printTargetReport(out io.Writer, name string, results []CheckResult, slo *SLOConfig, from, to time.Time, bucket time.Duration) error {
+	var counted []CheckResult
+	maintenance := 0
+	for _, r := range results {
+		if r.countsAgainstSLA() {
+			counted = append(counted, r)
+		} else if !r.Shed {
+			maintenance++
+		}
+	}
+	st := computeStats(counted)
+	incidents := computeIncidents(results)
+
+	fmt.Fprintf(out, "\n%s: проверок %d, доступность %.2f%%, инцидентов %d", name, st.Checks, st.SuccessRate(), len(incidents))
+	if mttr, ok := meanTimeToRecovery(incidents); ok {
+		fmt.Fprintf(out, ", MTTR %v", mttr.Round(time.Second))
+	}
+	if maintenance > 0 {
+		fmt.Fprintf(out, ", сбоев во время обслуживания %d", maintenance)
+	}
+	fmt.Fprintln(out)
+	if st.Checks == 0 {
+		return nil
+	}
+	if slo != nil {
+		// Бюджет ошибок считается за период отчёта, скорость расхода — за его последний час
+		var recent latencyStats
+		if len(counted) > 0 {
+			recent = computeStats(counted[sort.Search(len(counted), func(i int) bool {
+				return !counted[i].Timestamp.Before(to.Add(-sloBurnWindow))
+			}):])
+		}
+		s := slo.status(name, st.Checks, st.Successful, recent.Checks, recent.Successful)
+		fmt.Fprintf(out, "  SLO %g%%: остаток бюджета ошибок %.1f%%, скорость расхода за последний час %.1fx\n",
+			s.Objective, s.BudgetRemaining, s.BurnRate)
+	}
+
+	if len(incidents) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  начало\tвосстановление\tдлительность\tсбоев\tошибка")
+		for _, i := range incidents {
+			end, duration := "продолжается", "—"
+			if i.resolved() {
+				end = i.End.Format("2006-01-02 15:04:05")
+				duration = i.End.Sub(i.Start).Round(time.Second).String()
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\n", i.Start.Format("2006-01-02 15:04:05"), end, duration, i.Failures, i.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	layout := "2006-01-02 15:04"
+	if bucket < time.Minute {
+		layout = "2006-01-02 15:04:05"
+	}
+	var first, last latencyStats
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  период\tпроверок\tдоступность\tp50\tp95")
+	for _, b := range splitBuckets(counted, from, bucket) {
+		bs := computeStats(b.results)
+		if bs.Checks == 0 {
+			continue
+		}
+		if first.Checks == 0 {
+			first = bs
+		}
+		last = bs
+		fmt.Fprintf(w, "  %s\t%d\t%.2f%%\t%v\t%v\n", b.start.Format(layout), bs.Checks, bs.SuccessRate(),
+			bs.P50.Round(time.Millisecond), bs.P95.Round(time.Millisecond))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if first.P95 > 0 && last.P95 != first.P95 {
+		change := (float64(last.P95) - float64(first.P95)) / float64(first.P95) * 100
+		fmt.Fprintf(out, "  тренд p95: %v → %v (%+.1f%%)\n", first.P95.Round(time.Millisecond), last.P95.Round(time.Millisecond), change)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// targetDialer устанавливает соединения цели с учётом её DNS-сервера и статических адресов хостов
+type targetDialer struct {
+	dialer net.Dialer
+	// 4, 6 или пусто — семейство адресов соединений
+	ipVersion string
+	// Ключ — host или host:port, значение — IP-адрес
+	overrides map[string]string
+}
+
+// compileDialer готовит соединения цели; без dns_server, resolve, ip_version и connection: cold-dns
+// используются стандартные разрешение имён и установка соединений
+func (t *Target) compileDialer() error {
+	t.dialer = nil
+	if t.DNSServer == "" && len(t.Resolve) == 0 && t.IPVersion == "" && t.Connection != ConnectionColdDNS {
+		return nil
+	}
+
+	d := &targetDialer{
+		// Те же значения, что у стандартного транспорта
+		dialer:    net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		ipVersion: t.IPVersion,
+		overrides: make(map[string]string, len(t.Resolve)),
+	}
+
+	for host, ip := range t.Resolve {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("resolve: %s: некорректный IP-адрес %q", host, ip)
+		}
+		d.overrides[strings.ToLower(host)] = ip
+	}
+
+	if t.Connection == ConnectionColdDNS {
+		d.dialer.Resolver = uncachedResolver
+	}
+	if t.DNSServer != "" {
+		server := t.DNSServer
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		d.dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dns net.Dialer
+				return dns.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	t.dialer = d
+	return nil
+}
+
+// override возвращает статический адрес хоста; запись для host:port важнее записи для host
+func (d *targetDialer) override(host, port string) (string, bool) {
+	host = strings.ToLower(host)
+	if ip, ok := d.overrides[net.JoinHostPort(host, port)]; ok {
+		return ip, true
+	}
+	ip, ok := d.overrides[host]
+	return ip, ok
+}
+
+func (d *targetDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip, ok := d.override(host, port); ok {
+		addr = net.JoinHostPort(ip, port)
+	}
+	return d.dialer.DialContext(ctx, familyNetwork(network, d.ipVersion), addr)
+}
+
+// key описывает настройки для кэша транспортов
+func (d *targetDialer) key(server string) string {
+	pairs := make([]string, 0, len(d.overrides))
+	for host, ip := range d.overrides {
+		pairs = append(pairs, host+"="+ip)
+	}
+	sort.Strings(pairs)
+	uncached := ""
+	if d.dialer.Resolver == uncachedResolver {
+		uncached = "uncached"
+	}
+	return server + "|" + uncached + "|" + d.ipVersion + "|" + strings.Join(pairs, ",")
+}
+
+// lookupHost разрешает имя хоста так же, как это делают запросы цели
+func (t Target) lookupHost(ctx context.Context, host, port string) ([]string, error) {
+	resolver := net.DefaultResolver
+	if t.dialer != nil {
+		if ip, ok := t.dialer.override(host, port); ok {
+			return []string{ip}, nil
+		}
+		if r := t.dialer.dialer.Resolver; r != nil {
+			resolver = r
+		}
+	}
+	if t.IPVersion == "" {
+		return resolver.LookupHost(ctx, host)
+	}
+
+	ips, err := resolver.LookupIP(ctx, t.ipNetwork(), host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+	}
+	return addrs, nil
+}
+
+// dialContext устанавливает TCP-соединение так же, как это делают запросы цели
+func (t Target) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if t.dialer == nil {
+		var d net.Dialer
+		return d.DialContext(ctx, familyNetwork(network, t.IPVersion), addr)
+	}
+	return t.dialer.DialContext(ctx, network, addr)
+}
+
+// resolveFlags — повторяемый флаг -resolve host:port:addr в духе curl --resolve
+type resolveFlags map[string]string
+
+func (r resolveFlags) String() string {
+	parts := make([]string, 0, len(r))
+	for k, v := range r {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func (r resolveFlags) Set(s string) error {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return fmt.Errorf("нужен вид host:port:addr, порт можно не указывать: host::addr")
+	}
+	ip := strings.Trim(parts[2], "[]")
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("некорректный IP-адрес %q", parts[2])
+	}
+	key := parts[0]
+	if parts[1] != "" {
+		key = net.JoinHostPort(parts[0], parts[1])
+	}
+	r[key] = ip
+	return nil
+}
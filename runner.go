@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// daemonMaxResults — сколько последних результатов хранится в памяти в режиме мониторинга
+const daemonMaxResults = 10000
+
+type runOptions struct {
+	Interval  time.Duration
+	NumChecks int // <= 0 — до остановки
+	// Максимальное число одновременно выполняемых проверок, 0 — без ограничения
+	Concurrency int
+	// Закрывается в начале плавной остановки: новые итерации не запускаются
+	Draining <-chan struct{}
+	// Вызывается для каждого результата по мере поступления
+	OnResult func(CheckResult)
+	// Внеочередные проверки, запрошенные через API управления
+	Triggers <-chan checkTrigger
+	// Вызывается перед ожиданием следующей итерации со временем пробуждения
+	OnWait func(next time.Time)
+	// Результаты передаются только OnResult и не накапливаются в TestResult
+	DiscardResults bool
+	// Если больше 0, в TestResult остаются только последние MaxResults результатов
+	MaxResults int
+}
+
+// checkTrigger — запрос внеочередной проверки; результат отправляется в Reply
+type checkTrigger struct {
+	Target Target
+	Reply  chan<- CheckResult
+}
+
+// runTests выполняет итерации проверок активных целей с интервалом opts.Interval
+func runTests(ctx context.Context, registry *targetRegistry, opts runOptions) TestResult {
+	// Канал для результатов; проверки останавливаются отменой ctx
+	results := make(chan CheckResult, 64)
+
+	collected := make(chan TestResult, 1)
+	go func() {
+		collected <- collectResults(results, opts)
+	}()
+
+	var sem *prioritySemaphore
+	if opts.Concurrency > 0 {
+		sem = newPrioritySemaphore(opts.Concurrency)
+	}
+
+	wg := sync.WaitGroup{}
+
+	// Время следующей проверки, число запущенных проверок и время первой проверки каждой цели;
+	// цели со своим интервалом или расписанием проверяются независимо, остальные — с opts.Interval
+	due := make(map[string]time.Time)
+	done := make(map[string]int)
+	started := make(map[string]time.Time)
+
+	for {
+		now := time.Now()
+		next := now.Add(opts.Interval)
+		finished := true
+
+		// Критичные цели запускаются первыми
+		for _, target := range registry.active() {
+			left := target.checksLeft(done[target.Name], started[target.Name], now, opts.NumChecks)
+			if left == 0 {
+				continue
+			}
+			finished = false
+			// Цель, ответившая Retry-After, ждёт окончания паузы
+			if until := target.pausedUntil(); until.After(due[target.Name]) {
+				due[target.Name] = until
+			}
+			if now.Before(due[target.Name]) {
+				if due[target.Name].Before(next) {
+					next = due[target.Name]
+				}
+				continue
+			}
+
+			if started[target.Name].IsZero() {
+				started[target.Name] = now
+			}
+			batch := max(target.Concurrency, 1)
+			if left > 0 {
+				batch = min(batch, left)
+			}
+			for i := 0; i < batch; i++ {
+				wg.Add(1)
+				go scheduleCheck(ctx, &wg, sem, opts.Draining, target, results)
+			}
+			done[target.Name] += batch
+			due[target.Name] = now.Add(target.intervalAt(now, opts.Interval))
+			if target.Duration > 0 {
+				// Цель с duration завершается вовремя, даже если её интервал длиннее оставшегося времени
+				if end := started[target.Name].Add(target.Duration); end.Before(due[target.Name]) && end.Before(next) {
+					next = end
+				}
+			}
+			if due[target.Name].Before(next) && target.checksLeft(done[target.Name], started[target.Name], now, opts.NumChecks) != 0 {
+				next = due[target.Name]
+			}
+		}
+		if opts.NumChecks > 0 && finished {
+			break
+		}
+
+		if opts.OnWait != nil {
+			opts.OnWait(next)
+		}
+		timer := time.NewTimer(time.Until(next))
+	wait:
+		for {
+			select {
+			case <-ctx.Done(): // Проверка на сигнал остановки
+				timer.Stop()
+				slog.Info("Получен сигнал остановки. Прерывание тестов.")
+				wg.Wait()
+				close(results)
+				return <-collected
+			case <-opts.Draining:
+				timer.Stop()
+				slog.Info("Плавная остановка: новые проверки не запускаются, ожидание текущих.")
+				wg.Wait()
+				close(results)
+				return <-collected
+			case tr := <-opts.Triggers:
+				wg.Add(1)
+				go func(tr checkTrigger) {
+					defer wg.Done()
+					result := executeCheck(ctx, tr.Target)
+					results <- result
+					tr.Reply <- result
+				}(tr)
+			case <-timer.C:
+				break wait
+			}
+		}
+	}
+
+	wg.Wait()
+	close(results)
+
+	return <-collected
+}
+
+func collectResults(results <-chan CheckResult, opts runOptions) TestResult {
+	testResult := TestResult{
+		Results: make([]CheckResult, 0),
+	}
+
+	for result := range results {
+		if !opts.DiscardResults {
+			testResult.Results = append(testResult.Results, result)
+			// Старые результаты сдвигаются пачкой, когда их набирается вдвое больше предела
+			if opts.MaxResults > 0 && len(testResult.Results) >= 2*opts.MaxResults {
+				drop := len(testResult.Results) - opts.MaxResults
+				testResult.Results = append(testResult.Results[:0], testResult.Results[drop:]...)
+				testResult.Dropped += drop
+			}
+		}
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+	}
+
+	if opts.MaxResults > 0 && len(testResult.Results) > opts.MaxResults {
+		drop := len(testResult.Results) - opts.MaxResults
+		testResult.Results = append(testResult.Results[:0], testResult.Results[drop:]...)
+		testResult.Dropped += drop
+	}
+	return testResult
+}
+
+// scheduleCheck занимает слот с учётом приоритета цели и выполняет проверку.
+// Если слот получить нельзя, проверка отбрасывается и это фиксируется в результатах.
+func scheduleCheck(ctx context.Context, wg *sync.WaitGroup, sem *prioritySemaphore, draining <-chan struct{}, target Target, results chan<- CheckResult) {
+	if sem == nil {
+		performCheck(ctx, wg, target, results)
+		return
+	}
+
+	if err := sem.acquire(ctx, draining, target.priorityLevel()); err != nil {
+		slog.Warn("Проверка отброшена", "target", target.Name, "error", err)
+		results <- CheckResult{Target: target.Name, Timestamp: time.Now(), Success: false, Shed: true, Error: err.Error()}
+		wg.Done()
+		return
+	}
+	defer sem.release()
+
+	performCheck(ctx, wg, target, results)
+}
+
+var (
+	errShedPressure = errors.New("нет свободных слотов для проверки с приоритетом bulk")
+	errShedDraining = errors.New("проверка с приоритетом bulk во время остановки")
+	errShedStopped  = errors.New("остановка до начала проверки")
+)
+
+// prioritySemaphore ограничивает число одновременных проверок.
+// Освободившийся слот достаётся ожидающей проверке с наивысшим приоритетом.
+type prioritySemaphore struct {
+	mu      sync.Mutex
+	free    int
+	waiters [priorityLevels][]chan struct{}
+}
+
+func newPrioritySemaphore(size int) *prioritySemaphore {
+	return &prioritySemaphore{free: size}
+}
+
+func (s *prioritySemaphore) acquire(ctx context.Context, draining <-chan struct{}, level int) error {
+	s.mu.Lock()
+
+	if level == priorityBulk {
+		select {
+		case <-draining:
+			s.mu.Unlock()
+			return errShedDraining
+		default:
+		}
+	}
+
+	if s.free > 0 {
+		s.free--
+		s.mu.Unlock()
+		return nil
+	}
+
+	// Проверки bulk не ждут слота, а сразу отбрасываются
+	if level == priorityBulk {
+		s.mu.Unlock()
+		return errShedPressure
+	}
+
+	w := make(chan struct{})
+	s.waiters[level] = append(s.waiters[level], w)
+	s.mu.Unlock()
+
+	select {
+	case <-w:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := s.removeWaiter(level, w)
+		s.mu.Unlock()
+		if !removed {
+			// Слот уже был передан этой проверке, возвращаем его
+			s.release()
+		}
+		return errShedStopped
+	}
+}
+
+func (s *prioritySemaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for level := range s.waiters {
+		if len(s.waiters[level]) > 0 {
+			w := s.waiters[level][0]
+			s.waiters[level] = s.waiters[level][1:]
+			close(w)
+			return
+		}
+	}
+	s.free++
+}
+
+func (s *prioritySemaphore) removeWaiter(level int, w chan struct{}) bool {
+	for i, candidate := range s.waiters[level] {
+		if candidate == w {
+			s.waiters[level] = append(s.waiters[level][:i], s.waiters[level][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters ждёт, пока на уровне level не встанут в очередь n проверок
+func waitForWaiters(t *testing.T, s *prioritySemaphore, level, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		got := len(s.waiters[level])
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("на уровне %d ожидают %d, ожидалось %d", level, got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrioritySemaphoreAcquire(t *testing.T) {
+	open := make(chan struct{})
+	closed := make(chan struct{})
+	close(closed)
+
+	tests := []struct {
+		name     string
+		free     int
+		draining <-chan struct{}
+		level    int
+		want     error
+	}{
+		{"свободный слот", 1, open, priorityNormal, nil},
+		{"bulk при свободном слоте", 1, open, priorityBulk, nil},
+		{"bulk без слотов отбрасывается", 0, open, priorityBulk, errShedPressure},
+		{"bulk во время остановки", 1, closed, priorityBulk, errShedDraining},
+		{"critical во время остановки", 1, closed, priorityCritical, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPrioritySemaphore(tt.free)
+			if err := s.acquire(context.Background(), tt.draining, tt.level); err != tt.want {
+				t.Errorf("acquire = %v, ожидалось %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrioritySemaphoreOrder(t *testing.T) {
+	s := newPrioritySemaphore(1)
+	if err := s.acquire(context.Background(), nil, priorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ждущие проверки получают слот по приоритету, внутри уровня — по очереди
+	order := make(chan string, 4)
+	var wg sync.WaitGroup
+	start := func(name string, level, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.acquire(context.Background(), nil, level); err != nil {
+				order <- "ошибка " + err.Error()
+				return
+			}
+			order <- name
+			s.release()
+		}()
+		waitForWaiters(t, s, level, queued)
+	}
+	start("normal-1", priorityNormal, 1)
+	start("normal-2", priorityNormal, 2)
+	start("critical-1", priorityCritical, 1)
+	start("critical-2", priorityCritical, 2)
+
+	s.release()
+	want := []string{"critical-1", "critical-2", "normal-1", "normal-2"}
+	for _, name := range want {
+		select {
+		case got := <-order:
+			if got != name {
+				t.Fatalf("слот получила %s, ожидалась %s", got, name)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s не получила слот", name)
+		}
+	}
+
+	wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.free != 1 {
+		t.Errorf("свободных слотов %d, ожидался 1", s.free)
+	}
+}
+
+func TestPrioritySemaphoreCancel(t *testing.T) {
+	s := newPrioritySemaphore(1)
+	if err := s.acquire(context.Background(), nil, priorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.acquire(ctx, nil, priorityCritical) }()
+	waitForWaiters(t, s, priorityCritical, 1)
+	cancel()
+
+	if err := <-done; err != errShedStopped {
+		t.Errorf("acquire после отмены = %v, ожидалось %v", err, errShedStopped)
+	}
+	waitForWaiters(t, s, priorityCritical, 0)
+
+	// Отменённая проверка не забирает освободившийся слот
+	s.release()
+	if err := s.acquire(context.Background(), nil, priorityBulk); err != nil {
+		t.Errorf("слот не вернулся после отмены: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScheduleRule задаёт интервал проверок цели для дней недели и времени суток.
+// Правила проверяются по порядку; правило без days и hours подходит всегда.
+type ScheduleRule struct {
+	Days     string        `yaml:"days,omitempty"`     // например mon-fri или sat,sun
+	Hours    string        `yaml:"hours,omitempty"`    // например 09:00-18:00; 22:00-06:00 переходит через полночь
+	Timezone string        `yaml:"timezone,omitempty"` // по умолчанию локальное время
+	Interval time.Duration `yaml:"interval"`
+
+	days     [7]bool
+	allDays  bool
+	from, to int // минуты от начала суток
+	allDay   bool
+	loc      *time.Location
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compile разбирает дни, часы и часовой пояс правила
+func (r *ScheduleRule) compile() error {
+	if r.Interval <= 0 {
+		return fmt.Errorf("не указан interval")
+	}
+	return r.compileWindow()
+}
+
+// compileWindow разбирает дни, часы и часовой пояс без интервала проверок
+func (r *ScheduleRule) compileWindow() error {
+	r.loc = time.Local
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return fmt.Errorf("часовой пояс %q: %w", r.Timezone, err)
+		}
+		r.loc = loc
+	}
+
+	r.days = [7]bool{}
+	r.allDays = strings.TrimSpace(r.Days) == ""
+	if !r.allDays {
+		for _, part := range strings.Split(strings.ToLower(r.Days), ",") {
+			part = strings.TrimSpace(part)
+			first, last, isRange := strings.Cut(part, "-")
+			from, ok := weekdayNames[first]
+			if !ok {
+				return fmt.Errorf("неизвестный день недели %q", first)
+			}
+			to := from
+			if isRange {
+				if to, ok = weekdayNames[last]; !ok {
+					return fmt.Errorf("неизвестный день недели %q", last)
+				}
+			}
+			// Диапазон может переходить через воскресенье: fri-mon
+			for d := from; ; d = (d + 1) % 7 {
+				r.days[d] = true
+				if d == to {
+					break
+				}
+			}
+		}
+	}
+
+	r.allDay = strings.TrimSpace(r.Hours) == ""
+	if !r.allDay {
+		start, end, ok := strings.Cut(r.Hours, "-")
+		if !ok {
+			return fmt.Errorf("hours должен иметь вид ЧЧ:ММ-ЧЧ:ММ, получено %q", r.Hours)
+		}
+		var err error
+		if r.from, err = parseClock(start); err != nil {
+			return err
+		}
+		if r.to, err = parseClock(end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("некорректное время %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matches сообщает, действует ли правило в момент now
+func (r *ScheduleRule) matches(now time.Time) bool {
+	now = now.In(r.loc)
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+
+	if !r.allDay && r.from > r.to {
+		// Интервал через полночь относится к дню, в который он начался
+		if minute < r.to {
+			day = (day + 6) % 7
+		} else if minute < r.from {
+			return false
+		}
+	} else if !r.allDay && (minute < r.from || minute >= r.to) {
+		return false
+	}
+
+	return r.allDays || r.days[day]
+}
+
+// intervalAt возвращает интервал проверок цели в момент now; без подходящего правила —
+// interval цели, а если он не задан — fallback
+func (t Target) intervalAt(now time.Time, fallback time.Duration) time.Duration {
+	for i := range t.Schedule {
+		if t.Schedule[i].matches(now) {
+			return t.Schedule[i].Interval
+		}
+	}
+	if t.Interval > 0 {
+		return t.Interval
+	}
+	return fallback
+}
+
+// checksLeft возвращает, сколько проверок цели ещё можно запустить в момент now:
+// done — уже запущено, started — время первой проверки, defaultChecks — общий -n
+// (<= 0 — без ограничения); -1 означает отсутствие ограничения
+func (t Target) checksLeft(done int, started, now time.Time, defaultChecks int) int {
+	if t.Duration > 0 && !started.IsZero() && now.Sub(started) >= t.Duration {
+		return 0
+	}
+	limit := t.Checks
+	if limit == 0 && t.Duration == 0 {
+		limit = defaultChecks
+	}
+	if limit <= 0 {
+		return -1
+	}
+	return max(limit-done, 0)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleRuleCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     ScheduleRule
+		days     string // дни действия правила, начиная с воскресенья
+		from, to int
+		wantErr  string
+	}{
+		{name: "по умолчанию", rule: ScheduleRule{Interval: time.Minute}, days: "1111111"},
+		{name: "будни", rule: ScheduleRule{Days: "mon-fri", Interval: time.Minute}, days: "0111110"},
+		{name: "список и регистр", rule: ScheduleRule{Days: "Sat, SUN", Interval: time.Minute}, days: "1000001"},
+		{name: "диапазон через воскресенье", rule: ScheduleRule{Days: "fri-mon", Interval: time.Minute}, days: "1100011"},
+		{name: "часы", rule: ScheduleRule{Hours: "09:00-18:30", Interval: time.Minute}, days: "1111111", from: 9 * 60, to: 18*60 + 30},
+		{name: "часы через полночь", rule: ScheduleRule{Hours: " 22:00 - 06:00 ", Interval: time.Minute}, days: "1111111", from: 22 * 60, to: 6 * 60},
+		{name: "без интервала", rule: ScheduleRule{Days: "mon"}, wantErr: "не указан interval"},
+		{name: "неизвестный день", rule: ScheduleRule{Days: "mon,funday", Interval: time.Minute}, wantErr: `неизвестный день недели "funday"`},
+		{name: "неизвестный конец диапазона", rule: ScheduleRule{Days: "mon-xyz", Interval: time.Minute}, wantErr: `неизвестный день недели "xyz"`},
+		{name: "часы без диапазона", rule: ScheduleRule{Hours: "09:00", Interval: time.Minute}, wantErr: "ЧЧ:ММ-ЧЧ:ММ"},
+		{name: "некорректное время", rule: ScheduleRule{Hours: "9-18", Interval: time.Minute}, wantErr: `некорректное время "9"`},
+		{name: "24:00", rule: ScheduleRule{Hours: "00:00-24:00", Interval: time.Minute}, wantErr: `некорректное время "24:00"`},
+		{name: "неизвестный часовой пояс", rule: ScheduleRule{Timezone: "Mars/Olympus", Interval: time.Minute}, wantErr: "часовой пояс"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.rule
+			err := r.compile()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			var days strings.Builder
+			for d := time.Sunday; d <= time.Saturday; d++ {
+				if r.allDays || r.days[d] {
+					days.WriteByte('1')
+				} else {
+					days.WriteByte('0')
+				}
+			}
+			if days.String() != tt.days || r.from != tt.from || r.to != tt.to {
+				t.Errorf("дни %s, часы %d-%d, ожидалось %s, %d-%d", days.String(), r.from, r.to, tt.days, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestScheduleRuleMatches(t *testing.T) {
+	// 1 января 2024 года — понедельник
+	at := func(day int, clock string) time.Time {
+		c, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2024, 1, day, c.Hour(), c.Minute(), 0, 0, time.UTC)
+	}
+	const (
+		mon  = 1
+		tue  = 2
+		fri  = 5
+		sat  = 6
+		sun  = 7
+		mon2 = 8
+	)
+
+	tests := []struct {
+		name string
+		rule ScheduleRule
+		now  time.Time
+		want bool
+	}{
+		{"будни в рабочее время", ScheduleRule{Days: "mon-fri", Hours: "09:00-18:00"}, at(mon, "09:00"), true},
+		{"до начала интервала", ScheduleRule{Days: "mon-fri", Hours: "09:00-18:00"}, at(mon, "08:59"), false},
+		{"конец интервала не включается", ScheduleRule{Days: "mon-fri", Hours: "09:00-18:00"}, at(fri, "18:00"), false},
+		{"выходной", ScheduleRule{Days: "mon-fri", Hours: "09:00-18:00"}, at(sat, "10:00"), false},
+
+		{"ночь: вечер дня начала", ScheduleRule{Days: "mon", Hours: "22:00-06:00"}, at(mon, "23:30"), true},
+		{"ночь: утро после дня начала", ScheduleRule{Days: "mon", Hours: "22:00-06:00"}, at(tue, "05:59"), true},
+		{"ночь: утро дня начала относится к воскресенью", ScheduleRule{Days: "mon", Hours: "22:00-06:00"}, at(mon, "03:00"), false},
+		{"ночь: конец интервала не включается", ScheduleRule{Days: "mon", Hours: "22:00-06:00"}, at(tue, "06:00"), false},
+		{"ночь: между окончанием и началом", ScheduleRule{Days: "mon", Hours: "22:00-06:00"}, at(mon, "12:00"), false},
+		{"ночь: вечер следующего дня", ScheduleRule{Days: "mon", Hours: "22:00-06:00"}, at(tue, "22:30"), false},
+		{"ночь каждый день", ScheduleRule{Hours: "22:00-06:00"}, at(mon, "03:00"), true},
+
+		{"ночь воскресенья переходит в понедельник", ScheduleRule{Days: "sat,sun", Hours: "22:00-02:00"}, at(mon2, "01:00"), true},
+		{"ночь пятницы не входит в выходные", ScheduleRule{Days: "sat,sun", Hours: "22:00-02:00"}, at(sat, "01:00"), false},
+		{"ночь субботы", ScheduleRule{Days: "sat,sun", Hours: "22:00-02:00"}, at(sun, "01:00"), true},
+
+		{"диапазон дней через воскресенье: воскресенье", ScheduleRule{Days: "fri-mon"}, at(sun, "12:00"), true},
+		{"диапазон дней через воскресенье: понедельник", ScheduleRule{Days: "fri-mon"}, at(mon2, "00:00"), true},
+		{"диапазон дней через воскресенье: вторник", ScheduleRule{Days: "fri-mon"}, at(tue, "12:00"), false},
+
+		{"часовой пояс", ScheduleRule{Days: "mon", Hours: "09:00-18:00", Timezone: "Europe/Moscow"}, at(mon, "06:00"), true},
+		{"часовой пояс: конец дня", ScheduleRule{Days: "mon", Hours: "09:00-18:00", Timezone: "Europe/Moscow"}, at(mon, "15:00"), false},
+		{"часовой пояс меняет день", ScheduleRule{Days: "tue", Timezone: "Europe/Moscow"}, at(mon, "22:00"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.rule
+			if r.Timezone == "" {
+				r.Timezone = "UTC"
+			}
+			if err := r.compileWindow(); err != nil {
+				t.Fatal(err)
+			}
+			if got := r.matches(tt.now); got != tt.want {
+				t.Errorf("%s %v: получено %v, ожидалось %v", tt.now.Weekday(), tt.now.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetIntervalAt(t *testing.T) {
+	target := Target{
+		Interval: 5 * time.Minute,
+		Schedule: []ScheduleRule{
+			{Days: "sat,sun", Interval: 30 * time.Minute, Timezone: "UTC"},
+			{Hours: "09:00-18:00", Interval: time.Minute, Timezone: "UTC"},
+		},
+	}
+	for i := range target.Schedule {
+		if err := target.Schedule[i].compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		now      time.Time
+		target   Target
+		fallback time.Duration
+		want     time.Duration
+	}{
+		{time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), target, time.Hour, time.Minute},
+		// Первое подходящее правило важнее следующих
+		{time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC), target, time.Hour, 30 * time.Minute},
+		{time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), target, time.Hour, 5 * time.Minute},
+		{time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), Target{Schedule: target.Schedule}, time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := tt.target.intervalAt(tt.now, tt.fallback); got != tt.want {
+			t.Errorf("%v: получено %v, ожидалось %v", tt.now, got, tt.want)
+		}
+	}
+}
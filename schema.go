@@ -0,0 +1,469 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaValidator проверяет JSON-документ по JSON Schema (подмножество draft 7 и
+// схемы OpenAPI: type, enum, const, properties, required, additionalProperties,
+// items, ограничения длины и диапазона, pattern, nullable, allOf/anyOf/oneOf, $ref)
+type schemaValidator struct {
+	// Документ, относительно которого разрешаются ссылки "#/..."
+	root interface{}
+	// Ограничение на глубину ссылок, чтобы не зациклиться на рекурсивных схемах
+	depth int
+}
+
+const maxSchemaDepth = 64
+
+// validateJSONSchema возвращает список нарушений схемы; пустой список означает успех
+func validateJSONSchema(schema map[string]interface{}, root interface{}, value interface{}) []string {
+	if root == nil {
+		root = schema
+	}
+	v := &schemaValidator{root: root}
+	return v.validate(schema, value, "$")
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		if v.depth >= maxSchemaDepth {
+			return nil
+		}
+		resolved, err := resolveSchemaRef(v.root, ref)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %v", path, err)}
+		}
+		v.depth++
+		defer func() { v.depth-- }()
+		return v.validate(resolved, value, path)
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+	}
+
+	var errs []string
+
+	if t, ok := schema["type"]; ok {
+		if !matchesSchemaType(t, value) {
+			return []string{fmt.Sprintf("%s: ожидался тип %v, получено %s", path, t, jsonTypeName(value))}
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: значение %v не входит в enum", path, value))
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		errs = append(errs, fmt.Sprintf("%s: ожидалось %v", path, c))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, v.validateObject(schema, val, path)...)
+	case []interface{}:
+		errs = append(errs, v.validateArray(schema, val, path)...)
+	case string:
+		errs = append(errs, validateString(schema, val, path)...)
+	case float64:
+		errs = append(errs, validateNumber(schema, val, path)...)
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, v.validate(asSchema(sub), value, path)...)
+		}
+	}
+	if any, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range any {
+			if len(v.validate(asSchema(sub), value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s: значение не подходит ни под одну из схем anyOf", path))
+		}
+	}
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range one {
+			if len(v.validate(asSchema(sub), value, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			errs = append(errs, fmt.Sprintf("%s: значение подходит под %d схем oneOf вместо одной", path, matched))
+		}
+	}
+
+	return errs
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) []string {
+	var errs []string
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name := fmt.Sprint(r)
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s: обязательное поле отсутствует", path, name))
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if sub, ok := props[k]; ok {
+			errs = append(errs, v.validate(asSchema(sub), obj[k], path+"."+k)...)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				errs = append(errs, fmt.Sprintf("%s.%s: лишнее поле", path, k))
+			}
+		case map[string]interface{}:
+			errs = append(errs, v.validate(extra, obj[k], path+"."+k)...)
+		}
+	}
+
+	return errs
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, arr []interface{}, path string) []string {
+	var errs []string
+
+	if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(arr)) < min {
+		errs = append(errs, fmt.Sprintf("%s: элементов %d, минимум %v", path, len(arr), min))
+	}
+	if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(arr)) > max {
+		errs = append(errs, fmt.Sprintf("%s: элементов %d, максимум %v", path, len(arr), max))
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			errs = append(errs, v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return errs
+}
+
+func validateString(schema map[string]interface{}, s, path string) []string {
+	var errs []string
+	length := float64(len([]rune(s)))
+
+	if min, ok := schemaNumber(schema, "minLength"); ok && length < min {
+		errs = append(errs, fmt.Sprintf("%s: длина %v меньше %v", path, length, min))
+	}
+	if max, ok := schemaNumber(schema, "maxLength"); ok && length > max {
+		errs = append(errs, fmt.Sprintf("%s: длина %v больше %v", path, length, max))
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: некорректный pattern %q", path, pattern))
+		} else if !re.MatchString(s) {
+			errs = append(errs, fmt.Sprintf("%s: значение не соответствует шаблону %s", path, pattern))
+		}
+	}
+
+	return errs
+}
+
+func validateNumber(schema map[string]interface{}, n float64, path string) []string {
+	var errs []string
+
+	if min, ok := schemaNumber(schema, "minimum"); ok {
+		// В OpenAPI 3.0 exclusiveMinimum — логический флаг
+		if excl, _ := schema["exclusiveMinimum"].(bool); excl && n <= min || n < min {
+			errs = append(errs, fmt.Sprintf("%s: значение %v меньше минимума %v", path, n, min))
+		}
+	}
+	if max, ok := schemaNumber(schema, "maximum"); ok {
+		if excl, _ := schema["exclusiveMaximum"].(bool); excl && n >= max || n > max {
+			errs = append(errs, fmt.Sprintf("%s: значение %v больше максимума %v", path, n, max))
+		}
+	}
+	if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && n <= min {
+		errs = append(errs, fmt.Sprintf("%s: значение %v должно быть больше %v", path, n, min))
+	}
+	if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && n >= max {
+		errs = append(errs, fmt.Sprintf("%s: значение %v должно быть меньше %v", path, n, max))
+	}
+
+	return errs
+}
+
+func matchesSchemaType(t interface{}, value interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesSingleType(tt, value)
+	case []interface{}:
+		for _, candidate := range tt {
+			if matchesSingleType(fmt.Sprint(candidate), value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// resolveSchemaRef разрешает локальную ссылку вида #/components/schemas/User
+func resolveSchemaRef(root interface{}, ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("поддерживаются только локальные ссылки, получено %q", ref)
+	}
+
+	node := root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ссылка %q не найдена", ref)
+		}
+		if node, ok = m[part]; !ok {
+			return nil, fmt.Errorf("ссылка %q не найдена", ref)
+		}
+	}
+
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ссылка %q указывает не на схему", ref)
+	}
+	return schema, nil
+}
+
+func asSchema(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	switch n := schema[key].(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// jsonEqual сравнивает значения после приведения к типам encoding/json
+func jsonEqual(a, b interface{}) bool {
+	na, err := normalizeJSON(a)
+	if err != nil {
+		return false
+	}
+	nb, err := normalizeJSON(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+// parseJSONBody разбирает тело ответа для проверки схемой
+func parseJSONBody(body []byte) (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("ответ не является JSON: %w", err)
+	}
+	return doc, nil
+}
+
+// maxReportedViolations — сколько нарушений схемы попадает в текст ошибки
+const maxReportedViolations = 3
+
+// OpenAPIResponse ссылается на схему ответа операции в документе OpenAPI
+type OpenAPIResponse struct {
+	Spec      string `yaml:"spec"`                // путь к спецификации
+	Operation string `yaml:"operation,omitempty"` // operationId
+	// Вместо operationId можно указать путь и метод операции
+	Path   string `yaml:"path,omitempty"`
+	Method string `yaml:"method,omitempty"`
+	// Код ответа; по умолчанию наименьший успешный из спецификации
+	Status string `yaml:"status,omitempty"`
+}
+
+// responseSchema — разобранная схема ответа и документ для разрешения ссылок $ref
+type responseSchema struct {
+	schema map[string]interface{}
+	root   interface{}
+}
+
+var (
+	schemaDocsMu sync.Mutex
+	// Загруженные файлы схем и спецификаций; несколько целей могут ссылаться на один файл
+	schemaDocs = make(map[string]interface{})
+)
+
+// compileResponseSchema выбирает источник схемы цели: schema, schema_file или openapi
+func (t *Target) compileResponseSchema() error {
+	t.responseSchema = nil
+
+	sources := 0
+	for _, set := range []bool{t.Schema != nil, t.SchemaFile != "", t.OpenAPI != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("нужно указать только одно из schema, schema_file, openapi")
+	}
+
+	switch {
+	case t.Schema != nil:
+		doc, err := normalizeJSON(stringKeys(t.Schema))
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		t.responseSchema = &responseSchema{schema: asSchema(doc), root: doc}
+	case t.SchemaFile != "":
+		doc, err := loadSchemaDocument(t.SchemaFile)
+		if err != nil {
+			return fmt.Errorf("schema_file: %w", err)
+		}
+		schema := asSchema(doc)
+		if schema == nil {
+			return fmt.Errorf("schema_file: %s не содержит объект схемы", t.SchemaFile)
+		}
+		t.responseSchema = &responseSchema{schema: schema, root: doc}
+	case t.OpenAPI != nil:
+		doc, err := loadSchemaDocument(t.OpenAPI.Spec)
+		if err != nil {
+			return fmt.Errorf("openapi: %w", err)
+		}
+		spec := &openAPISpec{doc: asSchema(doc)}
+		schema, err := spec.responseSchema(*t.OpenAPI)
+		if err != nil {
+			return fmt.Errorf("openapi: %w", err)
+		}
+		t.responseSchema = &responseSchema{schema: schema, root: doc}
+	}
+	return nil
+}
+
+// loadSchemaDocument читает JSON или YAML и приводит значения к типам encoding/json
+func loadSchemaDocument(path string) (interface{}, error) {
+	schemaDocsMu.Lock()
+	defer schemaDocsMu.Unlock()
+
+	if doc, ok := schemaDocs[path]; ok {
+		return doc, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	doc, err := normalizeJSON(stringKeys(raw))
+	if err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+
+	schemaDocs[path] = doc
+	return doc, nil
+}
+
+// check проверяет тело ответа и возвращает все нарушения схемы
+func (s *responseSchema) check(body []byte) ([]string, error) {
+	doc, err := parseJSONBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := validateJSONSchema(s.schema, s.root, doc)
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	msg := "ответ не соответствует схеме: " + strings.Join(violations[:min(len(violations), maxReportedViolations)], "; ")
+	if extra := len(violations) - maxReportedViolations; extra > 0 {
+		msg += fmt.Sprintf(" и ещё %d", extra)
+	}
+	return violations, errors.New(msg)
+}
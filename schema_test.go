@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, src string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(src), &v); err != nil {
+		t.Fatalf("разбор %s: %v", src, err)
+	}
+	return v
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{"тип совпадает", `{"type": "string"}`, `"a"`, nil},
+		{"тип не совпадает", `{"type": "string"}`, `1`, []string{"$: ожидался тип string, получено number"}},
+		{"integer и дробное", `{"type": "integer"}`, `1.5`, []string{"$: ожидался тип integer, получено number"}},
+		{"список типов", `{"type": ["string", "null"]}`, `null`, nil},
+		{"nullable", `{"type": "string", "nullable": true}`, `null`, nil},
+		{"enum", `{"enum": [1, "a"]}`, `2`, []string{"$: значение 2 не входит в enum"}},
+		{"const объекта", `{"const": {"a": [1]}}`, `{"a": [1]}`, nil},
+		{
+			name:   "обязательные и лишние поля",
+			schema: `{"type": "object", "required": ["id", "name"], "properties": {"id": {"type": "integer"}}, "additionalProperties": false}`,
+			value:  `{"id": "x", "extra": 1}`,
+			want:   []string{"$.name: обязательное поле отсутствует", "$.extra: лишнее поле", "$.id: ожидался тип integer, получено string"},
+		},
+		{
+			name:   "схема дополнительных полей",
+			schema: `{"additionalProperties": {"type": "number"}}`,
+			value:  `{"a": 1, "b": "2"}`,
+			want:   []string{"$.b: ожидался тип number, получено string"},
+		},
+		{
+			name:   "массив",
+			schema: `{"type": "array", "minItems": 3, "items": {"type": "integer", "minimum": 0}}`,
+			value:  `[1, -1]`,
+			want:   []string{"$: элементов 2, минимум 3", "$[1]: значение -1 меньше минимума 0"},
+		},
+		{
+			name:   "длина строки в символах и шаблон",
+			schema: `{"minLength": 2, "maxLength": 3, "pattern": "^[а-я]+$"}`,
+			value:  `"абвг"`,
+			want:   []string{"$: длина 4 больше 3"},
+		},
+		{"шаблон не совпадает", `{"pattern": "^\\d+$"}`, `"12a"`, []string{`$: значение не соответствует шаблону ^\d+$`}},
+		{"draft 7 exclusiveMaximum", `{"exclusiveMaximum": 10}`, `10`, []string{"$: значение 10 должно быть меньше 10"}},
+		{"OpenAPI 3.0 exclusiveMinimum", `{"minimum": 1, "exclusiveMinimum": true}`, `1`, []string{"$: значение 1 меньше минимума 1"}},
+		{"allOf", `{"allOf": [{"required": ["a"]}, {"required": ["b"]}]}`, `{"a": 1}`, []string{"$.b: обязательное поле отсутствует"}},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, []string{"$: значение не подходит ни под одну из схем anyOf"}},
+		{"oneOf с двумя совпадениями", `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, []string{"$: значение подходит под 2 схем oneOf вместо одной"}},
+		{"oneOf", `{"oneOf": [{"type": "number"}, {"type": "string"}]}`, `1`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := asSchema(decodeJSON(t, tt.schema))
+			got := validateJSONSchema(schema, nil, decodeJSON(t, tt.value))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("получено %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchemaRefs(t *testing.T) {
+	root := decodeJSON(t, `{
+		"components": {"schemas": {
+			"Node": {"type": "object", "required": ["value"], "properties": {
+				"value": {"type": "integer"},
+				"next": {"$ref": "#/components/schemas/Node"}
+			}},
+			"a/b": {"type": "string"}
+		}}
+	}`)
+
+	tests := []struct {
+		ref   string
+		value string
+		want  []string
+	}{
+		{"#/components/schemas/Node", `{"value": 1, "next": {"value": 2, "next": {"value": "x"}}}`, []string{"$.next.next.value: ожидался тип integer, получено string"}},
+		{"#/components/schemas/Node", `{"value": 1, "next": {}}`, []string{"$.next.value: обязательное поле отсутствует"}},
+		{"#/components/schemas/a~1b", `"s"`, nil},
+		{"#/components/schemas/Missing", `1`, []string{`$: ссылка "#/components/schemas/Missing" не найдена`}},
+		{"other.json#/a", `1`, []string{`$: поддерживаются только локальные ссылки, получено "other.json#/a"`}},
+	}
+
+	for _, tt := range tests {
+		schema := map[string]interface{}{"$ref": tt.ref}
+		got := validateJSONSchema(schema, root, decodeJSON(t, tt.value))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: получено %q, ожидалось %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestValidateJSONSchemaRecursiveRef(t *testing.T) {
+	// Ссылка сама на себя не должна приводить к бесконечной рекурсии
+	root := decodeJSON(t, `{"definitions": {"Loop": {"$ref": "#/definitions/Loop"}}}`)
+	schema := map[string]interface{}{"$ref": "#/definitions/Loop"}
+	if got := validateJSONSchema(schema, root, decodeJSON(t, `1`)); got != nil {
+		t.Errorf("получено %q", got)
+	}
+}
+
+func TestResponseSchemaCheck(t *testing.T) {
+	s := &responseSchema{schema: asSchema(decodeJSON(t, `{"items": {"type": "integer"}}`))}
+	s.root = s.schema
+
+	if violations, err := s.check([]byte(`[1, 2]`)); err != nil || violations != nil {
+		t.Errorf("корректный ответ: %v %v", violations, err)
+	}
+	if _, err := s.check([]byte(`not json`)); err == nil || !strings.Contains(err.Error(), "не является JSON") {
+		t.Errorf("ответ не JSON: %v", err)
+	}
+
+	violations, err := s.check([]byte(`["a", "b", "c", "d", "e"]`))
+	if len(violations) != 5 {
+		t.Errorf("нарушений %d, ожидалось 5", len(violations))
+	}
+	if err == nil || !strings.HasSuffix(err.Error(), " и ещё 2") || strings.Contains(err.Error(), "$[3]") {
+		t.Errorf("текст ошибки: %v", err)
+	}
+}
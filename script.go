@@ -0,0 +1,1338 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Скрипты проверки — подмножество JavaScript для правил, которые не выразить
+// утверждениями и условиями, например:
+//
+//	const order = json.order
+//	assert(order.items.length > 0, "заказ без позиций")
+//	const total = order.items.reduce((s, i) => s + i.price * i.qty, 0)
+//	metric("total", total)
+//	if (Math.abs(total - order.total) > 0.01) fail(`сумма ${order.total}, по позициям ${total}`)
+//
+// Поддерживаются let/const/var, if/else, for (... of ...), break, continue, return,
+// стрелочные функции, операторы JavaScript (кроме побитовых, ++ и --), ?. и ??,
+// литералы массивов и объектов и строки в кавычках и обратных апострофах с ${...}.
+// Числа, строки, логические значения, null, массивы и объекты ведут себя как в
+// JavaScript; undefined и null не различаются.
+
+// Предельное число шагов скрипта, чтобы ошибка в цикле не остановила проверки
+const scriptMaxSteps = 1000000
+
+// Предельный суммарный объём строк и массивов, создаваемых скриптом за запуск: шаги
+// не ограничивают память, например s = s + s удваивает строку за шаг
+const scriptMaxAlloc = 64 << 20
+
+type scriptTokenKind int
+
+const (
+	stIdent scriptTokenKind = iota
+	stNumber
+	stString
+	stTemplate
+	stPunct
+	stEOF
+)
+
+type scriptToken struct {
+	kind scriptTokenKind
+	text string
+	num  float64
+	line int
+}
+
+// Операторы из нескольких символов, более длинные — первыми
+var scriptPuncts = []string{
+	"===", "!==",
+	"==", "!=", "<=", ">=", "&&", "||", "??", "?.", "=>", "+=", "-=", "*=", "/=",
+	"+", "-", "*", "/", "%", "<", ">", "!", "=", "(", ")", "[", "]", "{", "}", ",", ";", ".", ":", "?",
+}
+
+func tokenizeScript(src string) ([]scriptToken, error) {
+	var tokens []scriptToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("строка %d: незакрытый комментарий", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				(src[j] == '-' || src[j] == '+') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: некорректное число %q", line, src[i:j])
+			}
+			tokens = append(tokens, scriptToken{kind: stNumber, text: src[i:j], num: n, line: line})
+			i = j
+		case c == '"' || c == '\'' || c == '`':
+			s, n, err := scanScriptString(src[i:], line)
+			if err != nil {
+				return nil, err
+			}
+			kind := stString
+			if c == '`' {
+				kind = stTemplate
+			}
+			tokens = append(tokens, scriptToken{kind: kind, text: s, line: line})
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+		case c == '_' || c == '$' || c < 0x80 && unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '$' || src[j] < 0x80 && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])))) {
+				j++
+			}
+			tokens = append(tokens, scriptToken{kind: stIdent, text: src[i:j], line: line})
+			i = j
+		default:
+			matched := ""
+			for _, p := range scriptPuncts {
+				if strings.HasPrefix(src[i:], p) {
+					matched = p
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("строка %d: неожиданный символ %q", line, rune(c))
+			}
+			tokens = append(tokens, scriptToken{kind: stPunct, text: matched, line: line})
+			i += len(matched)
+		}
+	}
+	return append(tokens, scriptToken{kind: stEOF, line: line}), nil
+}
+
+// scanScriptString читает строку в кавычках; для шаблонных строк ${...} сохраняется как есть
+func scanScriptString(src string, line int) (string, int, error) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\n' && quote != '`':
+			return "", 0, fmt.Errorf("строка %d: незакрытая строка", line)
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '$':
+				// \${ в шаблонной строке — буквальный текст, а не подстановка
+				if quote == '`' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte('$')
+			default:
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("строка %d: незакрытая строка", line)
+}
+
+// Узлы скрипта
+type scriptExpr interface {
+	eval(vm *scriptVM, sc *scriptScope) (interface{}, error)
+}
+
+type scriptStmt interface {
+	exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error)
+}
+
+type scriptControl int
+
+const (
+	ctrlNone scriptControl = iota
+	ctrlReturn
+	ctrlBreak
+	ctrlContinue
+)
+
+type scriptParser struct {
+	tokens []scriptToken
+	pos    int
+}
+
+func (p *scriptParser) peek() scriptToken { return p.tokens[p.pos] }
+
+func (p *scriptParser) next() scriptToken {
+	t := p.tokens[p.pos]
+	if t.kind != stEOF {
+		p.pos++
+	}
+	return t
+}
+
+// back возвращает прочитанный next токен, чтобы сообщить о нём в ошибке
+func (p *scriptParser) back(t scriptToken) {
+	if t.kind != stEOF {
+		p.pos--
+	}
+}
+
+func (p *scriptParser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == stPunct || t.kind == stIdent) && t.text == text
+}
+
+func (p *scriptParser) accept(text string) bool {
+	if p.is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *scriptParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("ожидалось %q", text)
+	}
+	return nil
+}
+
+func (p *scriptParser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	found := "конец скрипта"
+	if t.kind != stEOF {
+		found = strconv.Quote(t.text)
+	}
+	return fmt.Errorf("строка %d: %s, найдено %s", t.line, fmt.Sprintf(format, args...), found)
+}
+
+func (p *scriptParser) ident() (string, error) {
+	t := p.peek()
+	if t.kind != stIdent || scriptKeywords[t.text] {
+		return "", p.errorf("ожидалось имя")
+	}
+	p.pos++
+	return t.text, nil
+}
+
+var scriptKeywords = map[string]bool{
+	"let": true, "const": true, "var": true, "if": true, "else": true, "for": true,
+	"return": true, "break": true, "continue": true, "true": true, "false": true, "null": true,
+	"undefined": true, "typeof": true, "function": true,
+}
+
+func parseScript(src string) ([]scriptStmt, error) {
+	tokens, err := tokenizeScript(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &scriptParser{tokens: tokens}
+	var stmts []scriptStmt
+	for p.peek().kind != stEOF {
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts, nil
+}
+
+func (p *scriptParser) parseStatement() (scriptStmt, error) {
+	line := p.peek().line
+	switch {
+	case p.accept(";"):
+		return blockStmt{}, nil
+	case p.is("{"):
+		return p.parseBlock()
+	case p.is("let") || p.is("const") || p.is("var"):
+		p.next()
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		var value scriptExpr = literalExpr{nil}
+		if p.accept("=") {
+			if value, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		p.accept(";")
+		return declareStmt{name: name, value: value}, nil
+	case p.accept("if"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		then, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		var otherwise scriptStmt
+		if p.accept("else") {
+			if otherwise, err = p.parseStatement(); err != nil {
+				return nil, err
+			}
+		}
+		return ifStmt{cond: cond, then: then, otherwise: otherwise}, nil
+	case p.accept("for"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		if !p.accept("const") && !p.accept("let") {
+			p.accept("var")
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept("of") {
+			return nil, p.errorf("поддерживается только for (x of список)")
+		}
+		list, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		body, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		return forOfStmt{name: name, list: list, body: body, line: line}, nil
+	case p.accept("return"):
+		var value scriptExpr = literalExpr{nil}
+		if !p.is(";") && !p.is("}") && p.peek().kind != stEOF && p.peek().line == line {
+			var err error
+			if value, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		p.accept(";")
+		return returnStmt{value}, nil
+	case p.accept("break"):
+		p.accept(";")
+		return controlStmt(ctrlBreak), nil
+	case p.accept("continue"):
+		p.accept(";")
+		return controlStmt(ctrlContinue), nil
+	}
+
+	// Присваивание переменной или выражение
+	if t := p.peek(); t.kind == stIdent && !scriptKeywords[t.text] {
+		if op := p.tokens[p.pos+1]; op.kind == stPunct && (op.text == "=" || op.text == "+=" || op.text == "-=" || op.text == "*=" || op.text == "/=") {
+			p.pos += 2
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			p.accept(";")
+			return assignStmt{name: t.text, op: strings.TrimSuffix(op.text, "="), value: value, line: line}, nil
+		}
+	}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.accept(";")
+	return exprStmt{e}, nil
+}
+
+func (p *scriptParser) parseBlock() (scriptStmt, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var b blockStmt
+	for !p.accept("}") {
+		if p.peek().kind == stEOF {
+			return nil, p.errorf("ожидалось \"}\"")
+		}
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, s)
+	}
+	return b, nil
+}
+
+func (p *scriptParser) parseExpr() (scriptExpr, error) {
+	if p.isArrow() {
+		return p.parseArrow()
+	}
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return ternaryExpr{cond, then, otherwise}, nil
+}
+
+// Приоритеты бинарных операторов, от низшего к высшему
+var scriptBinaryLevels = [][]string{
+	{"??"},
+	{"||"},
+	{"&&"},
+	{"==", "!=", "===", "!=="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *scriptParser) parseBinary(level int) (scriptExpr, error) {
+	if level == len(scriptBinaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != stPunct || !containsString(scriptBinaryLevels[level], t.text) {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: t.text, left: left, right: right, line: t.line}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *scriptParser) parseUnary() (scriptExpr, error) {
+	t := p.peek()
+	if (t.kind == stPunct && (t.text == "!" || t.text == "-" || t.text == "+")) || (t.kind == stIdent && t.text == "typeof") {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: t.text, inner: inner, line: t.line}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *scriptParser) parsePostfix() (scriptExpr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("."), p.accept("?."):
+			optional := t.text == "?."
+			if optional && p.is("[") {
+				p.next()
+				key, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				e = memberExpr{object: e, key: key, optional: true, line: t.line}
+				continue
+			}
+			name := p.next()
+			if name.kind != stIdent {
+				return nil, fmt.Errorf("строка %d: после %q ожидалось имя свойства", t.line, t.text)
+			}
+			e = memberExpr{object: e, key: literalExpr{name.text}, optional: optional, line: t.line}
+		case p.accept("["):
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			e = memberExpr{object: e, key: key, line: t.line}
+		case p.accept("("):
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			e = callExpr{fn: e, args: args, line: t.line}
+		default:
+			return e, nil
+		}
+	}
+}
+
+// parseList разбирает выражения через запятую до закрывающей скобки
+func (p *scriptParser) parseList(closing string) ([]scriptExpr, error) {
+	var list []scriptExpr
+	for !p.accept(closing) {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.accept(",") && !p.is(closing) {
+			return nil, p.errorf("ожидалось \",\" или %q", closing)
+		}
+	}
+	return list, nil
+}
+
+func (p *scriptParser) parsePrimary() (scriptExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case stNumber:
+		return literalExpr{t.num}, nil
+	case stString:
+		return literalExpr{t.text}, nil
+	case stTemplate:
+		return parseTemplateLiteral(t)
+	case stIdent:
+		switch t.text {
+		case "true":
+			return literalExpr{true}, nil
+		case "false":
+			return literalExpr{false}, nil
+		case "null", "undefined":
+			return literalExpr{nil}, nil
+		}
+		if scriptKeywords[t.text] {
+			p.back(t)
+			return nil, p.errorf("неожиданное ключевое слово")
+		}
+		return varExpr{name: t.text, line: t.line}, nil
+	case stPunct:
+		switch t.text {
+		case "(":
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return arrayExpr(items), nil
+		case "{":
+			return p.parseObject()
+		}
+	}
+	p.back(t)
+	return nil, p.errorf("ожидалось выражение")
+}
+
+func (p *scriptParser) parseObject() (scriptExpr, error) {
+	var obj objectExpr
+	for !p.accept("}") {
+		t := p.next()
+		if t.kind != stIdent && t.kind != stString && t.kind != stNumber {
+			p.back(t)
+			return nil, p.errorf("ожидалось имя поля")
+		}
+		var value scriptExpr
+		if p.accept(":") {
+			var err error
+			if value, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		} else if t.kind == stIdent {
+			// Краткая запись {name}
+			value = varExpr{name: t.text, line: t.line}
+		} else {
+			return nil, p.errorf("ожидалось \":\"")
+		}
+		obj = append(obj, objectField{key: t.text, value: value})
+		if !p.accept(",") && !p.is("}") {
+			return nil, p.errorf("ожидалось \",\" или \"}\"")
+		}
+	}
+	return obj, nil
+}
+
+// isArrow определяет начало стрелочной функции: x => или (a, b) =>
+func (p *scriptParser) isArrow() bool {
+	t := p.peek()
+	if t.kind == stIdent {
+		next := p.tokens[p.pos+1]
+		return next.kind == stPunct && next.text == "=>"
+	}
+	if t.kind != stPunct || t.text != "(" {
+		return false
+	}
+	depth := 0
+	for i := p.pos; i < len(p.tokens); i++ {
+		switch p.tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				next := p.tokens[i+1]
+				return next.kind == stPunct && next.text == "=>"
+			}
+		}
+	}
+	return false
+}
+
+func (p *scriptParser) parseArrow() (scriptExpr, error) {
+	var params []string
+	if p.accept("(") {
+		for !p.accept(")") {
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, name)
+			if !p.accept(",") && !p.is(")") {
+				return nil, p.errorf("ожидалось \",\" или \")\"")
+			}
+		}
+	} else {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		params = []string{name}
+	}
+	if err := p.expect("=>"); err != nil {
+		return nil, err
+	}
+	if p.is("{") {
+		body, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		return arrowExpr{params: params, body: body}, nil
+	}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return arrowExpr{params: params, body: returnStmt{e}}, nil
+}
+
+// parseTemplateLiteral разбирает `текст ${выражение} текст`
+func parseTemplateLiteral(t scriptToken) (scriptExpr, error) {
+	var parts []scriptExpr
+	s := t.text
+	for {
+		i := strings.Index(s, "${")
+		if i > 0 && s[i-1] == '\\' {
+			parts = append(parts, literalExpr{strings.ReplaceAll(s[:i-1], `\$`, "$") + "${"})
+			s = s[i+2:]
+			continue
+		}
+		if i < 0 {
+			parts = append(parts, literalExpr{strings.ReplaceAll(s, `\$`, "$")})
+			return templateExpr(parts), nil
+		}
+		end := strings.Index(s[i:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("строка %d: незакрытое ${ в шаблонной строке", t.line)
+		}
+		parts = append(parts, literalExpr{strings.ReplaceAll(s[:i], `\$`, "$")})
+		tokens, err := tokenizeScript(s[i+2 : i+end])
+		if err != nil {
+			return nil, err
+		}
+		p := &scriptParser{tokens: tokens}
+		e, err := p.parseExpr()
+		if err == nil && p.peek().kind != stEOF {
+			err = p.errorf("лишний текст в ${...}")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: шаблонная строка: %w", t.line, err)
+		}
+		parts = append(parts, e)
+		s = s[i+end+1:]
+	}
+}
+
+// Исполнение
+
+type scriptScope struct {
+	vars   map[string]interface{}
+	parent *scriptScope
+}
+
+func newScriptScope(parent *scriptScope) *scriptScope {
+	return &scriptScope{vars: make(map[string]interface{}), parent: parent}
+}
+
+func (s *scriptScope) lookup(name string) (*scriptScope, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if _, ok := sc.vars[name]; ok {
+			return sc, true
+		}
+	}
+	return nil, false
+}
+
+// scriptVM — состояние одного запуска скрипта
+type scriptVM struct {
+	steps     int
+	allocated int
+	metrics   map[string]float64
+}
+
+func (vm *scriptVM) step(line int) error {
+	vm.steps++
+	if vm.steps > scriptMaxSteps {
+		return scriptError(line, "превышено число шагов скрипта (%d)", scriptMaxSteps)
+	}
+	return nil
+}
+
+// alloc учитывает память нового значения скрипта
+func (vm *scriptVM) alloc(v interface{}, line int) error {
+	switch x := v.(type) {
+	case string:
+		return vm.allocBytes(len(x), line)
+	case []interface{}:
+		return vm.allocBytes(16*len(x), line)
+	case map[string]interface{}:
+		return vm.allocBytes(48*len(x), line)
+	}
+	return nil
+}
+
+func (vm *scriptVM) allocBytes(n, line int) error {
+	vm.allocated += n
+	if vm.allocated > scriptMaxAlloc {
+		return scriptError(line, "скрипт создал строки и массивы больше %d МБ", scriptMaxAlloc>>20)
+	}
+	return nil
+}
+
+// scriptFailure — проверка, проваленная скриптом через fail или assert
+type scriptFailure struct {
+	message string
+}
+
+func (f scriptFailure) Error() string { return f.message }
+
+// scriptError добавляет к ошибке номер строки; line 0 — строка неизвестна (вызов из встроенной
+// функции), её добавит вызов этой функции
+func scriptError(line int, format string, args ...interface{}) error {
+	if line <= 0 {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Errorf("строка %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// scriptFunc — функция, объявленная в скрипте
+type scriptFunc struct {
+	params  []string
+	body    scriptStmt
+	closure *scriptScope
+}
+
+// scriptBuiltin — встроенная функция или метод
+type scriptBuiltin func(vm *scriptVM, args []interface{}) (interface{}, error)
+
+func (vm *scriptVM) call(fn interface{}, args []interface{}, line int) (interface{}, error) {
+	if err := vm.step(line); err != nil {
+		return nil, err
+	}
+	switch f := fn.(type) {
+	case scriptBuiltin:
+		v, err := f(vm, args)
+		if err == nil {
+			err = vm.alloc(v, line)
+		}
+		if err != nil {
+			if _, failed := err.(scriptFailure); !failed && !strings.HasPrefix(err.Error(), "строка ") {
+				err = scriptError(line, "%v", err)
+			}
+		}
+		return v, err
+	case *scriptFunc:
+		sc := newScriptScope(f.closure)
+		for i, name := range f.params {
+			var v interface{}
+			if i < len(args) {
+				v = args[i]
+			}
+			sc.vars[name] = v
+		}
+		_, v, err := f.body.exec(vm, sc)
+		return v, err
+	}
+	return nil, scriptError(line, "%s не является функцией", scriptTypeOf(fn))
+}
+
+type blockStmt []scriptStmt
+
+func (b blockStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	inner := newScriptScope(sc)
+	for _, s := range b {
+		ctrl, v, err := s.exec(vm, inner)
+		if err != nil || ctrl != ctrlNone {
+			return ctrl, v, err
+		}
+	}
+	return ctrlNone, nil, nil
+}
+
+type declareStmt struct {
+	name  string
+	value scriptExpr
+}
+
+func (s declareStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	v, err := s.value.eval(vm, sc)
+	sc.vars[s.name] = v
+	return ctrlNone, nil, err
+}
+
+type assignStmt struct {
+	name  string
+	op    string // пусто для =, иначе + - * /
+	value scriptExpr
+	line  int
+}
+
+func (s assignStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	owner, ok := sc.lookup(s.name)
+	if !ok {
+		return ctrlNone, nil, scriptError(s.line, "переменная %s не объявлена", s.name)
+	}
+	if owner == scriptGlobals {
+		// Встроенные значения общие для всех проверок
+		return ctrlNone, nil, scriptError(s.line, "%s нельзя изменить", s.name)
+	}
+	v, err := s.value.eval(vm, sc)
+	if err != nil {
+		return ctrlNone, nil, err
+	}
+	if s.op != "" {
+		if v, err = applyBinary(vm, s.op, owner.vars[s.name], v, s.line); err != nil {
+			return ctrlNone, nil, err
+		}
+	}
+	owner.vars[s.name] = v
+	return ctrlNone, nil, nil
+}
+
+type ifStmt struct {
+	cond            scriptExpr
+	then, otherwise scriptStmt
+}
+
+func (s ifStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	c, err := s.cond.eval(vm, sc)
+	if err != nil {
+		return ctrlNone, nil, err
+	}
+	if scriptTruthy(c) {
+		return s.then.exec(vm, sc)
+	}
+	if s.otherwise != nil {
+		return s.otherwise.exec(vm, sc)
+	}
+	return ctrlNone, nil, nil
+}
+
+type forOfStmt struct {
+	name string
+	list scriptExpr
+	body scriptStmt
+	line int
+}
+
+func (s forOfStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	v, err := s.list.eval(vm, sc)
+	if err != nil {
+		return ctrlNone, nil, err
+	}
+	var items []interface{}
+	switch list := v.(type) {
+	case []interface{}:
+		items = list
+	case string:
+		if err := vm.allocBytes(16*len(list), s.line); err != nil {
+			return ctrlNone, nil, err
+		}
+		for _, r := range list {
+			items = append(items, string(r))
+		}
+	default:
+		return ctrlNone, nil, scriptError(s.line, "for ... of: %s не является массивом", scriptTypeOf(v))
+	}
+	for _, item := range items {
+		if err := vm.step(s.line); err != nil {
+			return ctrlNone, nil, err
+		}
+		inner := newScriptScope(sc)
+		inner.vars[s.name] = item
+		ctrl, v, err := s.body.exec(vm, inner)
+		if err != nil || ctrl == ctrlReturn {
+			return ctrl, v, err
+		}
+		if ctrl == ctrlBreak {
+			break
+		}
+	}
+	return ctrlNone, nil, nil
+}
+
+type returnStmt struct {
+	value scriptExpr
+}
+
+func (s returnStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	v, err := s.value.eval(vm, sc)
+	return ctrlReturn, v, err
+}
+
+type controlStmt scriptControl
+
+func (s controlStmt) exec(*scriptVM, *scriptScope) (scriptControl, interface{}, error) {
+	return scriptControl(s), nil, nil
+}
+
+type exprStmt struct {
+	e scriptExpr
+}
+
+func (s exprStmt) exec(vm *scriptVM, sc *scriptScope) (scriptControl, interface{}, error) {
+	_, err := s.e.eval(vm, sc)
+	return ctrlNone, nil, err
+}
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e literalExpr) eval(*scriptVM, *scriptScope) (interface{}, error) { return e.value, nil }
+
+type varExpr struct {
+	name string
+	line int
+}
+
+func (e varExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	owner, ok := sc.lookup(e.name)
+	if !ok {
+		return nil, scriptError(e.line, "переменная %s не объявлена", e.name)
+	}
+	return owner.vars[e.name], nil
+}
+
+type arrayExpr []scriptExpr
+
+func (e arrayExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	out := make([]interface{}, 0, len(e))
+	for _, item := range e {
+		v, err := item.eval(vm, sc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+type objectField struct {
+	key   string
+	value scriptExpr
+}
+
+type objectExpr []objectField
+
+func (e objectExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	out := make(map[string]interface{}, len(e))
+	for _, f := range e {
+		v, err := f.value.eval(vm, sc)
+		if err != nil {
+			return nil, err
+		}
+		out[f.key] = v
+	}
+	return out, nil
+}
+
+type templateExpr []scriptExpr
+
+func (e templateExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	var b strings.Builder
+	for _, part := range e {
+		v, err := part.eval(vm, sc)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(scriptString(v))
+	}
+	return b.String(), vm.alloc(b.String(), 0)
+}
+
+type arrowExpr struct {
+	params []string
+	body   scriptStmt
+}
+
+func (e arrowExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	return &scriptFunc{params: e.params, body: e.body, closure: sc}, nil
+}
+
+type ternaryExpr struct {
+	cond, then, otherwise scriptExpr
+}
+
+func (e ternaryExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	c, err := e.cond.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	if scriptTruthy(c) {
+		return e.then.eval(vm, sc)
+	}
+	return e.otherwise.eval(vm, sc)
+}
+
+type unaryExpr struct {
+	op    string
+	inner scriptExpr
+	line  int
+}
+
+func (e unaryExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	v, err := e.inner.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "!":
+		return !scriptTruthy(v), nil
+	case "-":
+		return -scriptNumber(v), nil
+	case "+":
+		return scriptNumber(v), nil
+	default: // typeof
+		return scriptTypeOf(v), nil
+	}
+}
+
+type binaryExpr struct {
+	op          string
+	left, right scriptExpr
+	line        int
+}
+
+func (e binaryExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	l, err := e.left.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	// Логические операторы вычисляют правую часть только при необходимости
+	switch e.op {
+	case "&&":
+		if !scriptTruthy(l) {
+			return l, nil
+		}
+		return e.right.eval(vm, sc)
+	case "||":
+		if scriptTruthy(l) {
+			return l, nil
+		}
+		return e.right.eval(vm, sc)
+	case "??":
+		if l != nil {
+			return l, nil
+		}
+		return e.right.eval(vm, sc)
+	}
+	r, err := e.right.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	return applyBinary(vm, e.op, l, r, e.line)
+}
+
+func applyBinary(vm *scriptVM, op string, l, r interface{}, line int) (interface{}, error) {
+	switch op {
+	case "==", "===":
+		return scriptEqual(l, r), nil
+	case "!=", "!==":
+		return !scriptEqual(l, r), nil
+	case "+":
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			a, b := scriptString(l), scriptString(r)
+			if err := vm.allocBytes(len(a)+len(b), line); err != nil {
+				return nil, err
+			}
+			return a + b, nil
+		}
+		return scriptNumber(l) + scriptNumber(r), nil
+	case "-":
+		return scriptNumber(l) - scriptNumber(r), nil
+	case "*":
+		return scriptNumber(l) * scriptNumber(r), nil
+	case "/":
+		return scriptNumber(l) / scriptNumber(r), nil
+	case "%":
+		return math.Mod(scriptNumber(l), scriptNumber(r)), nil
+	case "<", "<=", ">", ">=":
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		var c int
+		if lok && rok {
+			c = strings.Compare(ls, rs)
+		} else {
+			a, b := scriptNumber(l), scriptNumber(r)
+			if math.IsNaN(a) || math.IsNaN(b) {
+				return false, nil
+			}
+			switch {
+			case a < b:
+				c = -1
+			case a > b:
+				c = 1
+			}
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+	return nil, scriptError(line, "неизвестный оператор %s", op)
+}
+
+type memberExpr struct {
+	object   scriptExpr
+	key      scriptExpr
+	optional bool
+	line     int
+}
+
+func (e memberExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	obj, err := e.object.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.key.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		if e.optional {
+			return nil, nil
+		}
+		return nil, scriptError(e.line, "чтение свойства %s у null", scriptString(key))
+	}
+	return scriptMember(obj, key), nil
+}
+
+type callExpr struct {
+	fn   scriptExpr
+	args []scriptExpr
+	line int
+}
+
+func (e callExpr) eval(vm *scriptVM, sc *scriptScope) (interface{}, error) {
+	fn, err := e.fn.eval(vm, sc)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(e.args))
+	for _, a := range e.args {
+		v, err := a.eval(vm, sc)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	if fn == nil {
+		if m, ok := e.fn.(memberExpr); ok && m.optional {
+			return nil, nil
+		}
+	}
+	return vm.call(fn, args, e.line)
+}
+
+// Преобразования значений по правилам JavaScript
+
+func scriptTruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0 && !math.IsNaN(x)
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+func scriptNumber(v interface{}) float64 {
+	switch x := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case float64:
+		return x
+	case string:
+		s := strings.TrimSpace(x)
+		if s == "" {
+			return 0
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case []interface{}:
+		if len(x) == 0 {
+			return 0
+		}
+		if len(x) == 1 {
+			return scriptNumber(x[0])
+		}
+	}
+	return math.NaN()
+}
+
+func scriptString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		if math.IsInf(x, 1) {
+			return "Infinity"
+		}
+		if math.IsInf(x, -1) {
+			return "-Infinity"
+		}
+		if math.IsNaN(x) {
+			return "NaN"
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return x
+	case []interface{}:
+		parts := make([]string, len(x))
+		for i, item := range x {
+			if item != nil {
+				parts[i] = scriptString(item)
+			}
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		return "[object Object]"
+	}
+	return "function"
+}
+
+func scriptTypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "undefined"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case scriptBuiltin, *scriptFunc:
+		return "function"
+	}
+	return "object"
+}
+
+func scriptEqual(l, r interface{}) bool {
+	switch a := l.(type) {
+	case []interface{}, map[string]interface{}:
+		return reflect.DeepEqual(l, r)
+	case float64:
+		b, ok := r.(float64)
+		return ok && a == b
+	}
+	return l == r
+}
+
+// scriptKeys возвращает имена полей объекта по алфавиту
+func scriptKeys(obj map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		out[i] = k
+	}
+	return out
+}
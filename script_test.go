@@ -0,0 +1,195 @@
+package main
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// evalScript выполняет скрипт и возвращает значение return
+func evalScript(src string) (interface{}, error) {
+	stmts, err := parseScript(src)
+	if err != nil {
+		return nil, err
+	}
+	vm := &scriptVM{}
+	sc := newScriptScope(scriptGlobals)
+	for _, stmt := range stmts {
+		ctrl, v, err := stmt.exec(vm, sc)
+		if err != nil || ctrl == ctrlReturn {
+			return v, err
+		}
+	}
+	return nil, nil
+}
+
+func TestScriptEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"return 1 + 2 * 3", 7.0},
+		{"return (1 + 2) * 3 % 4", 1.0},
+		{"return 10 - 4 - 3", 3.0},
+		{"return -2 * -(1 + 1) - -1", 5.0},
+		{`return "a" + 1 + 2`, "a12"},
+		{`return 1 + 2 + "a"`, "3a"},
+		{`return "5" * "2"`, 10.0},
+		// == сравнивает без приведения типов, как ===
+		{`return 1 == "1" ? "нестрого" : "строго"`, "строго"},
+		{`return 1 === "1"`, false},
+		{"return null ?? 0 ?? 1", 0.0},
+		{"return 0 || null || 'x'", "x"},
+		{"return 1 && 0", 0.0},
+		{"return !''", true},
+		{"return typeof null + typeof 1 + typeof 'a' + typeof [] + typeof (() => 1)", "undefinednumberstringobjectfunction"},
+		{"const a = {b: {c: [1, 2]}}; return a.b.c[1] + a['b'].c.length", 4.0},
+		{"const a = null; return a?.b?.c ?? 'нет'", "нет"},
+		{"let s = 0; for (const x of [1, 2, 3, 4]) { if (x == 2) continue; if (x > 3) break; s += x } return s", 4.0},
+		{"let s = ''; for (const ch of 'абв') s = ch + s; return s", "вба"},
+		{"let r = ''; for (const k of Object.keys({b: 1, a: 2})) r += k; return r", "ab"},
+		{"let x = 1; { let x = 2 } return x", 1.0},
+		{"var n = 5; if (n > 3) { n -= 1 } else n = 0; return n", 4.0},
+		{"const add = (a, b) => a + b; const inc = x => { return add(x, 1) }; return inc(41)", 42.0},
+		{"const mk = n => () => n * 2; return mk(21)()", 42.0},
+		{"return [3, 1, 2].map(x => x * 10).filter(x => x > 10).reduce((s, x) => s + x, 0)", 50.0},
+		{"return [3, 1, 10].sort()", []interface{}{1.0, 10.0, 3.0}},
+		{"return [3, 1, 10].sort((a, b) => a - b).join('-')", "1-3-10"},
+		{"return [1, 2, 3].find(x => x > 1) + [1, 2, 3].findIndex(x => x > 5)", 1.0},
+		{"return [1, 2].some(x => x > 1) && [1, 2].every(x => x > 1)", false},
+		{"return [1, 2, 3].includes(2) && [1, 2, 3].indexOf(3) == 2", true},
+		{"return [1, 2, 3, 4].slice(1, -1)", []interface{}{2.0, 3.0}},
+		{"return ' A,b '.trim().toLowerCase().split(',')", []interface{}{"a", "b"}},
+		{"return 'привет'.slice(1, 3) + 'привет'.substring(4)", "риет"},
+		{"return 'abc'.startsWith('ab') && 'abc'.endsWith('bc') && 'abc'.includes('x') == false", true},
+		{"const n = 7; return `n=${n}, ${n > 5 ? 'много' : 'мало'}`", "n=7, много"},
+		{`return "a\tb\n\"c\"A"`, "a\tb\n\"c\"A"},
+		{"return Math.max(1, 5, 3) + Math.min(2, 0) + Math.abs(-1) + Math.round(1.5)", 8.0},
+		{"return parseInt('42px') + parseFloat('0.5') + Number('') + Number(true)", 43.5},
+		{"return String(null) + String([1, [2, 3]]) + String({})", "null1,2,3[object Object]"},
+		{"return JSON.parse('{\"a\": [1]}').a[0]", 1.0},
+		{"return JSON.stringify({b: 1, a: 'x'})", `{"a":"x","b":1}`},
+		{"return [1, 2] == [1, 2]", true},
+		{"return 1 / 0", math.Inf(1)},
+		{"return -1 < 0 && 'b' > 'a' && 2 >= 2 && !(2 != 2)", true},
+		{"return Array.isArray([]) && !Array.isArray({})", true},
+		{"const o = {c: 3, a: 1}; return Object.values(o)", []interface{}{1.0, 3.0}},
+		{"return [1, [2], {}].length", 3.0},
+		{"// комментарий\nreturn /* ещё */ 1", 1.0},
+		{"return", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := evalScript(tt.src)
+		if err != nil {
+			t.Errorf("%q: ошибка: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q = %#v, ожидалось %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestScriptNaN(t *testing.T) {
+	for _, src := range []string{"return Number('x')", "return 0 / 0", "return parseInt('a')"} {
+		got, err := evalScript(src)
+		if f, ok := got.(float64); err != nil || !ok || !math.IsNaN(f) {
+			t.Errorf("%q = %v, %v; ожидалось NaN", src, got, err)
+		}
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []string{
+		"let = 1",
+		"return (1 + 2",
+		"if 1 return 2",
+		"for (x in [1]) {}",
+		"x++",
+		"return 1 & 2",
+		"const s = 'незакрыта",
+		"return `${1 + }`",
+		"return {a: 1",
+		"return [1, 2",
+		"let x = 1 let y = 2 @",
+		"/* незакрытый комментарий",
+	}
+
+	for _, src := range tests {
+		if _, err := parseScript(src); err == nil {
+			t.Errorf("%q: ожидалась ошибка разбора", src)
+		}
+	}
+}
+
+func TestScriptRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"const a = null\nreturn a.b", "строка 2: чтение свойства b у null"},
+		{"const f = 1\nf()", "строка 2: number не является функцией"},
+		{"return missing", "строка 1: переменная missing не объявлена"},
+		{"let n = 0\nfor (const x of [1]) { m = 1 }", "строка 2: переменная m не объявлена"},
+		{"Math = 1", "Math нельзя изменить"},
+		{"const a = '0123456789'.split('')\nlet n = 0\nfor (const b of a) for (const c of a) for (const d of a) for (const e of a) for (const f of a) for (const g of a) n += 1", "превышено число шагов"},
+		{"let s = 'xxxxxxxxxxxxxxxx'; for (const i of [1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23]) s = s + s", "больше 64 МБ"},
+	}
+
+	for _, tt := range tests {
+		_, err := evalScript(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ошибка %v, ожидалась содержащая %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestCheckScriptRun(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"X-Total": {"3"}},
+		Request:    &http.Request{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/orders"}},
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+	body := []byte(`{"order": {"total": 30, "items": [{"price": 10, "qty": 1}, {"price": 5, "qty": 4}]}}`)
+
+	tests := []struct {
+		name        string
+		src         string
+		wantErr     string
+		wantMetrics map[string]float64
+	}{
+		{
+			name:        "успех и показатели",
+			src:         "const total = json.order.items.reduce((s, i) => s + i.price * i.qty, 0)\nmetric('total', total)\nassert(total == json.order.total, 'сумма')",
+			wantMetrics: map[string]float64{"total": 30},
+		},
+		{name: "переменные ответа", src: "return status == 200 && headers['x-total'] == '3' && response.url == 'https://example.com/orders' && body.length > 0"},
+		{name: "assert", src: "metric('n', 1)\nassert(json.order.items.length > 5, 'мало позиций')", wantErr: "мало позиций", wantMetrics: map[string]float64{"n": 1}},
+		{name: "fail", src: "fail(`итог ${json.order.total}`)", wantErr: "итог 30"},
+		{name: "false", src: "return json.order.total > 100", wantErr: "скрипт вернул false"},
+		{name: "строка с причиной", src: "return 'причина'", wantErr: "причина"},
+		{name: "ошибка выполнения", src: "json.missing.x", wantErr: "ошибка скрипта: строка 1: чтение свойства x у null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts, err := parseScript(tt.src)
+			if err != nil {
+				t.Fatalf("разбор: %v", err)
+			}
+			metrics, err := (&checkScript{stmts: stmts}).run(resp, body, CheckResult{})
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("ошибка %v, ожидалась %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(metrics, tt.wantMetrics) {
+				t.Errorf("показатели %v, ожидались %v", metrics, tt.wantMetrics)
+			}
+		})
+	}
+}
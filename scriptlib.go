@@ -0,0 +1,493 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkScript — скрипт проверки цели, разобранный при загрузке конфигурации
+type checkScript struct {
+	stmts []scriptStmt
+}
+
+// compileScript разбирает script или script_file нормализуемой цели
+func (t *Target) compileScript() error {
+	if t.Script != "" && t.ScriptFile != "" {
+		return fmt.Errorf("нужно указать только одно из script, script_file")
+	}
+	src := t.Script
+	if t.ScriptFile != "" {
+		data, err := ioutil.ReadFile(t.ScriptFile)
+		if err != nil {
+			return fmt.Errorf("script_file: %w", err)
+		}
+		src = string(data)
+	}
+	if src == "" {
+		t.script = nil
+		return nil
+	}
+	stmts, err := parseScript(src)
+	if err != nil {
+		return fmt.Errorf("script: %w", err)
+	}
+	t.script = &checkScript{stmts: stmts}
+	return nil
+}
+
+// run выполняет скрипт для ответа. Скрипт проваливает проверку вызовом fail,
+// невыполненным assert, ошибкой или возвратом false либо строки с причиной.
+// Показатели, записанные metric, возвращаются и при провале.
+func (s *checkScript) run(resp *http.Response, body []byte, result CheckResult) (map[string]float64, error) {
+	vm := &scriptVM{}
+	sc := newScriptScope(scriptGlobals)
+	for name, v := range scriptResponse(resp, body, result) {
+		sc.vars[name] = v
+	}
+
+	var returned interface{}
+	var err error
+	for _, stmt := range s.stmts {
+		var ctrl scriptControl
+		ctrl, returned, err = stmt.exec(vm, sc)
+		if err != nil || ctrl == ctrlReturn {
+			break
+		}
+	}
+	if err != nil {
+		if f, ok := err.(scriptFailure); ok {
+			return vm.metrics, f
+		}
+		return vm.metrics, fmt.Errorf("ошибка скрипта: %w", err)
+	}
+
+	switch v := returned.(type) {
+	case bool:
+		if !v {
+			return vm.metrics, fmt.Errorf("скрипт вернул false")
+		}
+	case string:
+		return vm.metrics, scriptFailure{v}
+	}
+	return vm.metrics, nil
+}
+
+// scriptResponse — переменные, доступные скрипту: response и сокращения status,
+// headers, body и json. Заголовки — с именами в нижнем регистре, тайминги — в миллисекундах.
+func scriptResponse(resp *http.Response, body []byte, result CheckResult) map[string]interface{} {
+	headers := make(map[string]interface{}, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+
+	var parsed interface{}
+	if json.Unmarshal(body, &parsed) != nil {
+		parsed = nil
+	}
+
+	ms := func(d time.Duration) interface{} { return float64(d) / float64(time.Millisecond) }
+	timings := map[string]interface{}{"total": ms(time.Since(result.Timestamp))}
+	if p := result.Phases; p != nil {
+		timings["dns"], timings["connect"], timings["tls"], timings["ttfb"] = ms(p.DNS), ms(p.Connect), ms(p.TLS), ms(p.TTFB)
+	}
+
+	response := map[string]interface{}{
+		"status":  float64(resp.StatusCode),
+		"headers": headers,
+		"body":    string(body),
+		"json":    parsed,
+		"timings": timings,
+		"url":     resp.Request.URL.String(),
+	}
+	return map[string]interface{}{
+		"response": response,
+		"status":   response["status"],
+		"headers":  headers,
+		"body":     response["body"],
+		"json":     parsed,
+	}
+}
+
+// scriptGlobals — встроенные функции и объекты, общие для всех скриптов
+var scriptGlobals = &scriptScope{vars: map[string]interface{}{
+	"fail": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		msg := "проверка провалена скриптом"
+		if len(args) > 0 {
+			msg = scriptString(args[0])
+		}
+		return nil, scriptFailure{msg}
+	}),
+	"assert": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		if len(args) > 0 && scriptTruthy(args[0]) {
+			return nil, nil
+		}
+		msg := "не выполнено утверждение скрипта"
+		if len(args) > 1 {
+			msg = scriptString(args[1])
+		}
+		return nil, scriptFailure{msg}
+	}),
+	"metric": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("metric(имя, значение)")
+		}
+		if vm.metrics == nil {
+			vm.metrics = make(map[string]float64)
+		}
+		vm.metrics[scriptString(args[0])] = scriptNumber(args[1])
+		return nil, nil
+	}),
+	"Number": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		return scriptNumber(scriptArg(args, 0)), nil
+	}),
+	"String": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		return scriptString(scriptArg(args, 0)), nil
+	}),
+	"Boolean": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		return scriptTruthy(scriptArg(args, 0)), nil
+	}),
+	"parseInt": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		s := strings.TrimSpace(scriptString(scriptArg(args, 0)))
+		end := 0
+		for end < len(s) && (s[end] >= '0' && s[end] <= '9' || end == 0 && (s[0] == '-' || s[0] == '+')) {
+			end++
+		}
+		n, err := strconv.ParseFloat(s[:end], 64)
+		if err != nil {
+			return math.NaN(), nil
+		}
+		return n, nil
+	}),
+	"parseFloat": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		return scriptNumber(strings.TrimSpace(scriptString(scriptArg(args, 0)))), nil
+	}),
+	"isNaN": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		return math.IsNaN(scriptNumber(scriptArg(args, 0))), nil
+	}),
+	"Math": map[string]interface{}{
+		"abs":   scriptMath(math.Abs),
+		"floor": scriptMath(math.Floor),
+		"ceil":  scriptMath(math.Ceil),
+		"round": scriptMath(func(x float64) float64 { return math.Floor(x + 0.5) }),
+		"sqrt":  scriptMath(math.Sqrt),
+		"min": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			out := math.Inf(1)
+			for _, a := range args {
+				out = math.Min(out, scriptNumber(a))
+			}
+			return out, nil
+		}),
+		"max": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			out := math.Inf(-1)
+			for _, a := range args {
+				out = math.Max(out, scriptNumber(a))
+			}
+			return out, nil
+		}),
+	},
+	"JSON": map[string]interface{}{
+		"parse": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(scriptString(scriptArg(args, 0))), &v); err != nil {
+				return nil, fmt.Errorf("JSON.parse: %v", err)
+			}
+			return v, nil
+		}),
+		"stringify": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			data, err := json.Marshal(scriptArg(args, 0))
+			if err != nil {
+				return nil, fmt.Errorf("JSON.stringify: %v", err)
+			}
+			return string(data), nil
+		}),
+	},
+	"Object": map[string]interface{}{
+		"keys": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			obj, _ := scriptArg(args, 0).(map[string]interface{})
+			return scriptKeys(obj), nil
+		}),
+		"values": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			obj, _ := scriptArg(args, 0).(map[string]interface{})
+			keys := scriptKeys(obj)
+			out := make([]interface{}, len(keys))
+			for i, k := range keys {
+				out[i] = obj[k.(string)]
+			}
+			return out, nil
+		}),
+	},
+	"Array": map[string]interface{}{
+		"isArray": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			_, ok := scriptArg(args, 0).([]interface{})
+			return ok, nil
+		}),
+	},
+	"Date": map[string]interface{}{
+		"now": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			return float64(time.Now().UnixMilli()), nil
+		}),
+		// Date.parse принимает время в RFC 3339 и возвращает миллисекунды или NaN
+		"parse": scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			t, err := time.Parse(time.RFC3339Nano, scriptString(scriptArg(args, 0)))
+			if err != nil {
+				return math.NaN(), nil
+			}
+			return float64(t.UnixMilli()), nil
+		}),
+	},
+}}
+
+func scriptArg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func scriptMath(f func(float64) float64) scriptBuiltin {
+	return func(vm *scriptVM, args []interface{}) (interface{}, error) {
+		return f(scriptNumber(scriptArg(args, 0))), nil
+	}
+}
+
+// scriptMember возвращает свойство объекта, элемент массива или метод массива и строки
+func scriptMember(obj, key interface{}) interface{} {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		return o[scriptString(key)]
+	case []interface{}:
+		if n, ok := key.(float64); ok {
+			if i := int(n); float64(i) == n && i >= 0 && i < len(o) {
+				return o[i]
+			}
+			return nil
+		}
+		if scriptString(key) == "length" {
+			return float64(len(o))
+		}
+		return arrayMethod(o, scriptString(key))
+	case string:
+		if n, ok := key.(float64); ok {
+			r := []rune(o)
+			if i := int(n); float64(i) == n && i >= 0 && i < len(r) {
+				return string(r[i])
+			}
+			return nil
+		}
+		if scriptString(key) == "length" {
+			return float64(len([]rune(o)))
+		}
+		return stringMethod(o, scriptString(key))
+	}
+	return nil
+}
+
+func arrayMethod(list []interface{}, name string) interface{} {
+	// each вызывает fn для элементов, пока она не вернёт stop
+	each := func(vm *scriptVM, args []interface{}, stop func(i int, v interface{}) bool) error {
+		fn := scriptArg(args, 0)
+		for i, item := range list {
+			v, err := vm.call(fn, []interface{}{item, float64(i)}, 0)
+			if err != nil {
+				return err
+			}
+			if stop(i, v) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	switch name {
+	case "every", "some":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			result := name == "every"
+			err := each(vm, args, func(_ int, v interface{}) bool {
+				if scriptTruthy(v) != result {
+					result = !result
+					return true
+				}
+				return false
+			})
+			return result, err
+		})
+	case "find", "findIndex":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			var found interface{}
+			index := -1.0
+			err := each(vm, args, func(i int, v interface{}) bool {
+				if scriptTruthy(v) {
+					found, index = list[i], float64(i)
+					return true
+				}
+				return false
+			})
+			if name == "findIndex" {
+				return index, err
+			}
+			return found, err
+		})
+	case "filter", "map":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			out := make([]interface{}, 0, len(list))
+			err := each(vm, args, func(i int, v interface{}) bool {
+				if name == "map" {
+					out = append(out, v)
+				} else if scriptTruthy(v) {
+					out = append(out, list[i])
+				}
+				return false
+			})
+			return out, err
+		})
+	case "forEach":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			return nil, each(vm, args, func(int, interface{}) bool { return false })
+		})
+	case "reduce":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			items := list
+			var acc interface{}
+			if len(args) > 1 {
+				acc = args[1]
+			} else if len(items) > 0 {
+				acc, items = items[0], items[1:]
+			}
+			for i, item := range items {
+				v, err := vm.call(scriptArg(args, 0), []interface{}{acc, item, float64(i)}, 0)
+				if err != nil {
+					return nil, err
+				}
+				acc = v
+			}
+			return acc, nil
+		})
+	case "includes", "indexOf":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			for i, item := range list {
+				if scriptEqual(item, scriptArg(args, 0)) {
+					if name == "includes" {
+						return true, nil
+					}
+					return float64(i), nil
+				}
+			}
+			if name == "includes" {
+				return false, nil
+			}
+			return -1.0, nil
+		})
+	case "join":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			sep := ","
+			if len(args) > 0 {
+				sep = scriptString(args[0])
+			}
+			parts := make([]string, len(list))
+			for i, item := range list {
+				if item != nil {
+					parts[i] = scriptString(item)
+				}
+			}
+			return strings.Join(parts, sep), nil
+		})
+	case "slice":
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			from, to := scriptRange(len(list), args)
+			return append([]interface{}(nil), list[from:to]...), nil
+		})
+	case "sort":
+		// sort без функции сравнения упорядочивает по строковому представлению, как в JavaScript
+		return scriptBuiltin(func(vm *scriptVM, args []interface{}) (interface{}, error) {
+			out := append([]interface{}(nil), list...)
+			var err error
+			sort.SliceStable(out, func(i, j int) bool {
+				if len(args) == 0 {
+					return scriptString(out[i]) < scriptString(out[j])
+				}
+				v, callErr := vm.call(args[0], []interface{}{out[i], out[j]}, 0)
+				if callErr != nil && err == nil {
+					err = callErr
+				}
+				return scriptNumber(v) < 0
+			})
+			return out, err
+		})
+	}
+	return nil
+}
+
+func stringMethod(s, name string) interface{} {
+	str := func(f func(args []interface{}) interface{}) scriptBuiltin {
+		return func(vm *scriptVM, args []interface{}) (interface{}, error) { return f(args), nil }
+	}
+	switch name {
+	case "includes":
+		return str(func(args []interface{}) interface{} { return strings.Contains(s, scriptString(scriptArg(args, 0))) })
+	case "startsWith":
+		return str(func(args []interface{}) interface{} { return strings.HasPrefix(s, scriptString(scriptArg(args, 0))) })
+	case "endsWith":
+		return str(func(args []interface{}) interface{} { return strings.HasSuffix(s, scriptString(scriptArg(args, 0))) })
+	case "indexOf":
+		return str(func(args []interface{}) interface{} {
+			i := strings.Index(s, scriptString(scriptArg(args, 0)))
+			if i < 0 {
+				return -1.0
+			}
+			return float64(len([]rune(s[:i])))
+		})
+	case "toLowerCase":
+		return str(func([]interface{}) interface{} { return strings.ToLower(s) })
+	case "toUpperCase":
+		return str(func([]interface{}) interface{} { return strings.ToUpper(s) })
+	case "trim":
+		return str(func([]interface{}) interface{} { return strings.TrimSpace(s) })
+	case "split":
+		return str(func(args []interface{}) interface{} {
+			var parts []string
+			if len(args) == 0 {
+				parts = []string{s}
+			} else {
+				parts = strings.Split(s, scriptString(args[0]))
+			}
+			out := make([]interface{}, len(parts))
+			for i, p := range parts {
+				out[i] = p
+			}
+			return out
+		})
+	case "slice", "substring":
+		return str(func(args []interface{}) interface{} {
+			r := []rune(s)
+			from, to := scriptRange(len(r), args)
+			return string(r[from:to])
+		})
+	}
+	return nil
+}
+
+// scriptRange переводит аргументы slice (начало и конец, отрицательные — с конца) в границы
+func scriptRange(n int, args []interface{}) (int, int) {
+	bound := func(v interface{}, def int) int {
+		if v == nil {
+			return def
+		}
+		i := int(scriptNumber(v))
+		if i < 0 {
+			i += n
+		}
+		return max(0, min(i, n))
+	}
+	from, to := bound(scriptArg(args, 0), 0), bound(scriptArg(args, 1), n)
+	if to < from {
+		to = from
+	}
+	return from, to
+}
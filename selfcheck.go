@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Насколько планировщик может опоздать с пробуждением, прежде чем /healthz сочтёт его зависшим
+const schedulerStallGrace = time.Minute
+
+// checkerState — собственное состояние монитора для /healthz и /readyz
+type checkerState struct {
+	mu         sync.Mutex
+	store      *ResultStore
+	nextWake   time.Time
+	lastResult time.Time
+	draining   bool
+}
+
+func newCheckerState(store *ResultStore) *checkerState {
+	return &checkerState{store: store}
+}
+
+// scheduled вызывается планировщиком перед ожиданием следующей итерации
+func (s *checkerState) scheduled(next time.Time) {
+	s.mu.Lock()
+	s.nextWake = next
+	s.mu.Unlock()
+}
+
+func (s *checkerState) Observe(r CheckResult) {
+	s.mu.Lock()
+	s.lastResult = r.Timestamp
+	s.mu.Unlock()
+}
+
+// drain отмечает начало остановки: монитор перестаёт быть готовым
+func (s *checkerState) drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+}
+
+// liveness проверяет, что планировщик не завис; пустая строка — всё в порядке
+func (s *checkerState) liveness(now time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.nextWake.IsZero() && now.After(s.nextWake.Add(schedulerStallGrace)) {
+		return "планировщик не запускал проверки с " + s.nextWake.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// readiness проверяет, что монитор выполняет проверки и сохраняет результаты
+func (s *checkerState) readiness(now time.Time) map[string]string {
+	checks := map[string]string{"scheduler": "ok", "checks": "ok"}
+	if problem := s.liveness(now); problem != "" {
+		checks["scheduler"] = problem
+	}
+
+	s.mu.Lock()
+	if s.draining {
+		checks["scheduler"] = "остановка"
+	}
+	if s.lastResult.IsZero() {
+		checks["checks"] = "ещё не выполнено ни одной проверки"
+	}
+	s.mu.Unlock()
+
+	if s.store != nil {
+		checks["store"] = "ok"
+		if err := s.store.db.Ping(); err != nil {
+			checks["store"] = err.Error()
+		}
+	}
+	return checks
+}
+
+// registerSelfChecks добавляет /healthz (жив ли процесс) и /readyz (готов ли он проверять),
+// например для livenessProbe и readinessProbe Kubernetes
+func registerSelfChecks(mux *http.ServeMux, state *checkerState) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if problem := state.liveness(time.Now()); problem != "" {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "fail", "error": problem})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		checks := state.readiness(time.Now())
+		status, code := "ok", http.StatusOK
+		for _, v := range checks {
+			if v != "ok" {
+				status, code = "fail", http.StatusServiceUnavailable
+			}
+		}
+		writeJSON(w, code, map[string]interface{}{"status": status, "checks": checks})
+	})
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionConfig — cookie между запросами цели: шагами сценария, перенаправлениями
+// и, если нужно, проверками
+type SessionConfig struct {
+	// check (по умолчанию) — начинать каждую проверку без cookie;
+	// never — хранить cookie между проверками цели
+	Reset string `yaml:"reset,omitempty"`
+}
+
+// Когда сбрасываются cookie сессии
+const (
+	SessionResetCheck = "check"
+	SessionResetNever = "never"
+)
+
+// sessionState — хранилище cookie цели, которое сохраняется между проверками
+type sessionState struct {
+	persistent http.CookieJar
+}
+
+// jar возвращает хранилище cookie для очередной проверки
+func (s *sessionState) jar() http.CookieJar {
+	if s.persistent != nil {
+		return s.persistent
+	}
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+// compileSession проверяет session и set_cookies нормализуемой цели
+func (t *Target) compileSession() error {
+	t.session = nil
+	if t.Session != nil || t.Type == CheckTypeScenario {
+		s := &sessionState{}
+		reset := SessionResetCheck
+		if t.Session != nil && t.Session.Reset != "" {
+			reset = t.Session.Reset
+		}
+		switch reset {
+		case SessionResetCheck:
+		case SessionResetNever:
+			s.persistent, _ = cookiejar.New(nil)
+		default:
+			return fmt.Errorf("session.reset: неизвестное значение %q (check или never)", reset)
+		}
+		t.session = s
+	}
+
+	t.setCookies = nil
+	for name, pattern := range t.SetCookies {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("set_cookies.%s: %w", name, err)
+		}
+		if t.setCookies == nil {
+			t.setCookies = make(map[string]*regexp.Regexp, len(t.SetCookies))
+		}
+		t.setCookies[name] = re
+	}
+	return nil
+}
+
+// cookieError проверяет, что ответы запроса, включая перенаправления, установили
+// ожидаемые cookie со значениями, подходящими под шаблоны set_cookies
+func (t Target) cookieError(cookies []*http.Cookie) error {
+	if len(t.setCookies) == 0 {
+		return nil
+	}
+	set := make(map[string]string, len(cookies))
+	for _, c := range cookies {
+		set[c.Name] = c.Value
+	}
+
+	names := make([]string, 0, len(t.setCookies))
+	for name := range t.setCookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := set[name]
+		if !ok {
+			return fmt.Errorf("ответ не установил cookie %s", name)
+		}
+		if !t.setCookies[name].MatchString(value) {
+			return fmt.Errorf("cookie %s=%q не соответствует %q", name, value, t.SetCookies[name])
+		}
+	}
+	return nil
+}
+
+// compileSteps готовит шаги сценария: каждый шаг — цель, наследующая сетевые настройки,
+// таймаут и заголовки сценария; относительный url шага отсчитывается от url сценария
+func (t *Target) compileSteps() error {
+	if t.Type != CheckTypeScenario {
+		if len(t.Steps) > 0 {
+			return fmt.Errorf("steps поддерживается только для типа scenario")
+		}
+		return nil
+	}
+	if len(t.Steps) == 0 {
+		return fmt.Errorf("для типа scenario нужны steps")
+	}
+	base, err := url.Parse(t.URL)
+	if err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+
+	defaults := TargetDefaults{
+		Timeout:     t.Timeout,
+		Headers:     t.Headers,
+		Proxy:       t.Proxy,
+		DNSServer:   t.DNSServer,
+		Resolve:     t.Resolve,
+		IPVersion:   t.IPVersion,
+		HTTPVersion: t.HTTPVersion,
+		Connection:  t.Connection,
+		Enrich:      t.Enrich,
+	}
+	steps := make([]Target, len(t.Steps))
+	for i, step := range t.Steps {
+		if step.Type == CheckTypeScenario {
+			return fmt.Errorf("шаг #%d: вложенные сценарии не поддерживаются", i+1)
+		}
+		if step.Session != nil {
+			return fmt.Errorf("шаг #%d: session задаётся для сценария целиком", i+1)
+		}
+		step.inherit(defaults)
+		if step.URL == "" {
+			step.URL = t.URL
+		} else if ref, err := url.Parse(step.URL); err == nil && !ref.IsAbs() && !strings.Contains(step.URL, "{{") {
+			step.URL = base.ResolveReference(ref).String()
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("#%d", i+1)
+		}
+		// Повторяется сценарий целиком, а не отдельные шаги
+		step.Retries = nil
+		name := step.Name
+		step.Name = t.Name + " / " + name
+		if err := step.normalize(); err != nil {
+			return fmt.Errorf("шаг %s: %w", name, err)
+		}
+		step.Name = name
+		steps[i] = step
+	}
+	t.Steps = steps
+	return nil
+}
+
+// executeScenario выполняет шаги сценария по порядку с общими cookie; первый неуспешный
+// шаг завершает сценарий. Задержка сценария — сумма задержек шагов, статус — последнего шага.
+func executeScenario(ctx context.Context, target Target) CheckResult {
+	result := CheckResult{Target: target.Name, Timestamp: time.Now()}
+	jar := target.session.jar()
+
+	for _, step := range target.Steps {
+		step.jar = jar
+		name := step.Name
+		step.Name = target.Name + " / " + name
+		r := executeRequest(ctx, step)
+
+		result.Latency += r.Latency
+		result.Status = r.Status
+		result.Size += r.Size
+		for k, v := range r.Metrics {
+			if result.Metrics == nil {
+				result.Metrics = make(map[string]float64)
+			}
+			result.Metrics[name+"."+k] = v
+		}
+		sub := SubCheckResult{Name: name, Success: r.Success, Latency: r.Latency, Error: r.Error}
+		if r.Status != 0 {
+			sub.Detail = fmt.Sprintf("HTTP %d", r.Status)
+		}
+		result.SubChecks = append(result.SubChecks, sub)
+
+		if !r.Success {
+			result.Error = fmt.Sprintf("шаг %s: %s", name, r.Error)
+			result.Artifact = r.Artifact
+			result.RateLimited, result.RetryAfter, result.RateLimit = r.RateLimited, r.RetryAfter, r.RateLimit
+			target.applySeverity(&result, signalsFor(result, false))
+			return result
+		}
+	}
+
+	result.Success = true
+	target.applySeverity(&result, signalsFor(result, true))
+	return result
+}
@@ -0,0 +1,100 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("graphite", newGraphite)
+}
+
+// graphite отправляет метрики в текстовом протоколе Carbon по TCP:
+//
+//	<prefix>.<target>.latency_ms 12.5 1700000000
+//	<prefix>.<target>.success 1 1700000000
+//	<prefix>.<target>.status 200 1700000000
+//
+// Соединение устанавливается заново после ошибки записи.
+type graphite struct {
+	addr   string
+	prefix string
+	conn   net.Conn
+	w      *bufio.Writer
+}
+
+const graphiteDialTimeout = 5 * time.Second
+
+func newGraphite(options map[string]interface{}) (Sink, error) {
+	addr, err := stringOption(options, "address", "127.0.0.1:2003")
+	if err != nil {
+		return nil, fmt.Errorf("graphite: %w", err)
+	}
+	prefix, err := stringOption(options, "prefix", "apichecker")
+	if err != nil {
+		return nil, fmt.Errorf("graphite: %w", err)
+	}
+	return &graphite{addr: addr, prefix: strings.TrimSuffix(prefix, ".")}, nil
+}
+
+func (g *graphite) connect(ctx context.Context) error {
+	if g.conn != nil {
+		return nil
+	}
+	d := net.Dialer{Timeout: graphiteDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", g.addr)
+	if err != nil {
+		return err
+	}
+	g.conn, g.w = conn, bufio.NewWriter(conn)
+	return nil
+}
+
+func (g *graphite) reset() {
+	if g.conn != nil {
+		g.conn.Close()
+	}
+	g.conn, g.w = nil, nil
+}
+
+func (g *graphite) Write(ctx context.Context, r Result) error {
+	if err := g.connect(ctx); err != nil {
+		return fmt.Errorf("graphite: %w", err)
+	}
+	name := g.prefix + "." + metricName(r.Target)
+	ts := r.Timestamp.Unix()
+	success := 0
+	if r.Success {
+		success = 1
+	}
+	fmt.Fprintf(g.w, "%s.latency_ms %g %d\n", name, float64(r.Latency.Microseconds())/1000, ts)
+	fmt.Fprintf(g.w, "%s.success %d %d\n", name, success, ts)
+	if r.Status != 0 {
+		fmt.Fprintf(g.w, "%s.status %d %d\n", name, r.Status, ts)
+	}
+	// Метрики отправляются сразу, чтобы попадать в Graphite в реальном времени
+	return g.Flush(ctx)
+}
+
+func (g *graphite) Flush(ctx context.Context) error {
+	if g.w == nil {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		g.conn.SetWriteDeadline(deadline)
+	}
+	if err := g.w.Flush(); err != nil {
+		g.reset()
+		return fmt.Errorf("graphite: %w", err)
+	}
+	return nil
+}
+
+func (g *graphite) Close() error {
+	g.reset()
+	return nil
+}
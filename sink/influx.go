@@ -0,0 +1,116 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("influx", newInflux)
+}
+
+// influx отправляет результаты в InfluxDB в формате line protocol:
+//
+//	apichecker,target=api latency=12500000i,success=true,status=200i 1700000000000000000
+//
+// options.url — адрес записи с параметрами базы, например
+// http://influx:8086/api/v2/write?org=ops&bucket=checks (InfluxDB 2) или
+// http://influx:8086/write?db=checks (InfluxDB 1). Токен берётся из переменной
+// окружения options.token_env и передаётся в заголовке Authorization: Token.
+// Строки накапливаются и отправляются одним запросом при Flush.
+type influx struct {
+	url         string
+	token       string
+	measurement string
+	client      *http.Client
+	buf         bytes.Buffer
+}
+
+const influxTimeout = 10 * time.Second
+
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+func newInflux(options map[string]interface{}) (Sink, error) {
+	url, err := stringOption(options, "url", "")
+	if err != nil {
+		return nil, fmt.Errorf("influx: %w", err)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("influx: не указан options.url")
+	}
+	measurement, err := stringOption(options, "measurement", "apichecker")
+	if err != nil {
+		return nil, fmt.Errorf("influx: %w", err)
+	}
+	tokenEnv, err := stringOption(options, "token_env", "")
+	if err != nil {
+		return nil, fmt.Errorf("influx: %w", err)
+	}
+	token := ""
+	if tokenEnv != "" {
+		if token = os.Getenv(tokenEnv); token == "" {
+			return nil, fmt.Errorf("influx: переменная окружения %s не задана", tokenEnv)
+		}
+	}
+	return &influx{
+		url:         url,
+		token:       token,
+		measurement: influxTagEscaper.Replace(measurement),
+		client:      &http.Client{Timeout: influxTimeout},
+	}, nil
+}
+
+func (s *influx) Write(ctx context.Context, r Result) error {
+	fmt.Fprintf(&s.buf, "%s,target=%s", s.measurement, influxTagEscaper.Replace(r.Target))
+	if r.Severity != "" {
+		fmt.Fprintf(&s.buf, ",severity=%s", influxTagEscaper.Replace(r.Severity))
+	}
+	fmt.Fprintf(&s.buf, " latency=%di,success=%t", r.Latency.Nanoseconds(), r.Success)
+	if r.Status != 0 {
+		fmt.Fprintf(&s.buf, ",status=%di", r.Status)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&s.buf, ",error=%s", strconv.Quote(r.Error))
+	}
+	fmt.Fprintf(&s.buf, " %d\n", r.Timestamp.UnixNano())
+	return nil
+}
+
+func (s *influx) Flush(ctx context.Context) error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(s.buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("influx: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	// Буфер очищается и при ошибке: повторная доставка не выполняется
+	defer s.buf.Reset()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influx: статус %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (s *influx) Close() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func init() {
+	Register("jsonl", newJSONLines)
+}
+
+// jsonLines дописывает результаты в файл по одному JSON-объекту в строке;
+// служит и примером реализации приёмника
+type jsonLines struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newJSONLines(options map[string]interface{}) (Sink, error) {
+	path, _ := options["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("jsonl: не указан options.path")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLines{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (s *jsonLines) Write(ctx context.Context, r Result) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = s.w.Write(data)
+	return err
+}
+
+func (s *jsonLines) Flush(ctx context.Context) error {
+	return s.w.Flush()
+}
+
+func (s *jsonLines) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,39 @@
+package sink
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// stringOption возвращает строковый параметр приёмника или значение по умолчанию
+func stringOption(options map[string]interface{}, key, def string) (string, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("options.%s должен быть строкой", key)
+	}
+	return s, nil
+}
+
+// boolOption возвращает логический параметр приёмника; по умолчанию false
+func boolOption(options map[string]interface{}, key string) (bool, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("options.%s должен быть true или false", key)
+	}
+	return b, nil
+}
+
+var unsafeMetricChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// metricName приводит имя цели к виду, допустимому в пути метрики StatsD и Graphite
+func metricName(s string) string {
+	return unsafeMetricChars.ReplaceAllString(s, "_")
+}
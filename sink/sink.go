@@ -0,0 +1,127 @@
+// Пакет sink описывает приёмники результатов проверок: реализации поставляются
+// отдельными модулями Go и подключаются к утилите импортом пакета, который
+// регистрирует приёмник в init:
+//
+//	import _ "example.com/apichecker-kafka"
+//
+// Приёмник включается в конфигурации по имени, под которым он зарегистрирован:
+//
+//	sinks:
+//	  - type: kafka
+//	    buffer: 5000
+//	    overflow: drop
+//	    options:
+//	      brokers: ["kafka:9092"]
+//
+// # Доставка и обратное давление
+//
+// Для каждого приёмника утилита держит очередь ограниченного размера (buffer)
+// и отдельную горутину доставки, поэтому методы одного приёмника никогда не
+// вызываются одновременно, а результаты приходят в порядке завершения проверок.
+//
+// Write может накапливать результаты и отправлять их пачками; медленный Write
+// заполняет очередь. Когда очередь полна, поведение задаётся overflow:
+// block (по умолчанию) задерживает сохранение результатов, а с ним и проверки,
+// пока в очереди не освободится место; drop отбрасывает новый результат,
+// отброшенные результаты подсчитываются и попадают в журнал.
+//
+// Write и Flush должны соблюдать ctx: после его отмены (остановка утилиты с
+// истёкшим временем на завершение) они должны вернуться как можно скорее.
+// Ошибка Write или Flush записывается в журнал, повторная доставка не
+// выполняется — повторы, если нужны, реализует сам приёмник.
+//
+// Flush вызывается каждые flush_interval (по умолчанию 10 секунд) и после того,
+// как очередь опустела при остановке; приёмник должен отправить накопленное.
+// Close вызывается один раз после последнего Flush; после Close методы не вызываются.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Result — результат одной проверки в том виде, в котором он передаётся приёмникам
+type Result struct {
+	Target    string        `json:"target"`
+	Timestamp time.Time     `json:"timestamp"`
+	Success   bool          `json:"success"`
+	Status    int           `json:"status,omitempty"`
+	Latency   time.Duration `json:"latency"`
+	Error     string        `json:"error,omitempty"`
+	Severity  string        `json:"severity,omitempty"`
+	Attempts  int           `json:"attempts,omitempty"`
+	// Имя исходной цели для строк набора данных
+	Dataset string `json:"dataset,omitempty"`
+	// Сервер и соединение, по которым получен ответ
+	RemoteIP   string `json:"remote_ip,omitempty"`
+	Protocol   string `json:"protocol,omitempty"`
+	TLSVersion string `json:"tls_version,omitempty"`
+	TLSCipher  string `json:"tls_cipher,omitempty"`
+	CertSerial string `json:"cert_serial,omitempty"`
+	// Регион проверки в распределённом режиме
+	Region string `json:"region,omitempty"`
+	// Показатели скрипта проверки или проверки из плагина
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Проверка выполнена во время обслуживания цели
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Ответ 429 или 503 с Retry-After
+	RateLimited bool `json:"rate_limited,omitempty"`
+}
+
+// Sink — приёмник результатов проверок; правила вызова описаны в документации пакета
+type Sink interface {
+	Write(ctx context.Context, r Result) error
+	Flush(ctx context.Context) error
+	Close() error
+}
+
+// Factory создаёт приёмник из options раздела sinks конфигурации
+type Factory func(options map[string]interface{}) (Sink, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register регистрирует приёмник под именем; обычно вызывается из init пакета
+// с реализацией. Повторная регистрация имени — ошибка программы.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if factory == nil {
+		panic("sink: Register с nil factory для " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("sink: повторная регистрация " + name)
+	}
+	factories[name] = factory
+}
+
+// New создаёт зарегистрированный приёмник
+func New(name string, options map[string]interface{}) (Sink, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("неизвестный приёмник %q (доступны: %v)", name, Names())
+	}
+	return factory(options)
+}
+
+// Names возвращает имена зарегистрированных приёмников по алфавиту
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,84 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+func init() {
+	Register("statsd", newStatsD)
+}
+
+// statsD отправляет по UDP задержку (timer), счётчики успешных и неуспешных проверок
+// и счётчик HTTP-статусов каждой цели:
+//
+//	<prefix>.<target>.latency:12.5|ms
+//	<prefix>.<target>.success:1|c
+//	<prefix>.<target>.status.200:1|c
+//
+// С options.tags: true имена метрик постоянны, а цель и статус передаются тегами
+// DogStatsD: apichecker.latency:12.5|ms|#target:api.
+type statsD struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+}
+
+func newStatsD(options map[string]interface{}) (Sink, error) {
+	addr, err := stringOption(options, "address", "127.0.0.1:8125")
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+	prefix, err := stringOption(options, "prefix", "apichecker")
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+	tags, err := boolOption(options, "tags")
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+	return &statsD{conn: conn, prefix: strings.TrimSuffix(prefix, "."), tags: tags}, nil
+}
+
+func (s *statsD) Write(ctx context.Context, r Result) error {
+	outcome := "failure"
+	if r.Success {
+		outcome = "success"
+	}
+	latency := float64(r.Latency.Microseconds()) / 1000
+
+	var buf bytes.Buffer
+	if s.tags {
+		tags := "|#target:" + strings.ReplaceAll(r.Target, ",", "_")
+		fmt.Fprintf(&buf, "%s.latency:%g|ms%s\n", s.prefix, latency, tags)
+		fmt.Fprintf(&buf, "%s.%s:1|c%s\n", s.prefix, outcome, tags)
+		if r.Status != 0 {
+			fmt.Fprintf(&buf, "%s.status:1|c%s,status:%d\n", s.prefix, tags, r.Status)
+		}
+	} else {
+		name := s.prefix + "." + metricName(r.Target)
+		fmt.Fprintf(&buf, "%s.latency:%g|ms\n", name, latency)
+		fmt.Fprintf(&buf, "%s.%s:1|c\n", name, outcome)
+		if r.Status != 0 {
+			fmt.Fprintf(&buf, "%s.status.%d:1|c\n", name, r.Status)
+		}
+	}
+	// Одна датаграмма на результат; строки разделены переводом строки
+	_, err := s.conn.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return err
+}
+
+func (s *statsD) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (s *statsD) Close() error {
+	return s.conn.Close()
+}
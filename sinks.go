@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"test/sink"
+)
+
+// SinkConfig — приёмник результатов из пакета sink
+type SinkConfig struct {
+	Type string `yaml:"type"`
+	// Размер очереди результатов приёмника
+	Buffer int `yaml:"buffer"`
+	// block (по умолчанию) или drop — что делать, когда очередь полна
+	Overflow      string                 `yaml:"overflow"`
+	FlushInterval time.Duration          `yaml:"flush_interval"`
+	Options       map[string]interface{} `yaml:"options"`
+}
+
+// Поведение при заполненной очереди приёмника
+const (
+	SinkOverflowBlock = "block"
+	SinkOverflowDrop  = "drop"
+)
+
+const (
+	defaultSinkBuffer        = 1000
+	defaultSinkFlushInterval = 10 * time.Second
+	// Время на доставку оставшихся результатов при остановке
+	sinkShutdownTimeout = 10 * time.Second
+)
+
+// sinkWorker доставляет результаты одному приёмнику из своей очереди
+type sinkWorker struct {
+	name     string
+	sink     sink.Sink
+	queue    chan sink.Result
+	drop     bool
+	interval time.Duration
+	dropped  uint64
+	done     chan struct{}
+}
+
+// sinkDispatcher рассылает результаты проверок всем настроенным приёмникам
+type sinkDispatcher struct {
+	workers []*sinkWorker
+	ctx     context.Context
+	cancel  context.CancelFunc
+	once    sync.Once
+}
+
+// metricSinkFlags превращает флаги -statsd, -influx и -graphite в приёмники
+func metricSinkFlags(statsd, influx, graphite string) []SinkConfig {
+	var out []SinkConfig
+	if statsd != "" {
+		out = append(out, SinkConfig{Type: "statsd", Options: map[string]interface{}{"address": statsd}})
+	}
+	if influx != "" {
+		// Результаты в InfluxDB отправляются пачками раз в секунду
+		out = append(out, SinkConfig{Type: "influx", FlushInterval: time.Second, Options: map[string]interface{}{"url": influx}})
+	}
+	if graphite != "" {
+		out = append(out, SinkConfig{Type: "graphite", Options: map[string]interface{}{"address": graphite}})
+	}
+	return out
+}
+
+func newSinkDispatcher(configs []SinkConfig) (*sinkDispatcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &sinkDispatcher{ctx: ctx, cancel: cancel}
+
+	for i, cfg := range configs {
+		w, err := newSinkWorker(cfg)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("приёмник #%d: %w", i+1, err)
+		}
+		d.workers = append(d.workers, w)
+		go w.run(ctx)
+	}
+	return d, nil
+}
+
+func newSinkWorker(cfg SinkConfig) (*sinkWorker, error) {
+	if cfg.Type == "" {
+		return nil, fmt.Errorf("не указан type")
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = defaultSinkBuffer
+	}
+	w := &sinkWorker{
+		name:     cfg.Type,
+		queue:    make(chan sink.Result, cfg.Buffer),
+		interval: cfg.FlushInterval,
+		done:     make(chan struct{}),
+	}
+	if w.interval <= 0 {
+		w.interval = defaultSinkFlushInterval
+	}
+	switch cfg.Overflow {
+	case "", SinkOverflowBlock:
+	case SinkOverflowDrop:
+		w.drop = true
+	default:
+		return nil, fmt.Errorf("%s: неизвестный режим overflow %q", cfg.Type, cfg.Overflow)
+	}
+
+	s, err := sink.New(cfg.Type, cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	w.sink = s
+	return w, nil
+}
+
+// Observe передаёт результат в очереди приёмников; отброшенные планировщиком проверки не передаются
+func (d *sinkDispatcher) Observe(r CheckResult) {
+	if r.Shed {
+		return
+	}
+	res := sink.Result{
+		Target:      r.Target,
+		Timestamp:   r.Timestamp,
+		Success:     r.Success,
+		Status:      r.Status,
+		Latency:     r.Latency,
+		Error:       r.Error,
+		Severity:    r.Severity,
+		Attempts:    r.Attempts,
+		Dataset:     r.Dataset,
+		RemoteIP:    r.RemoteIP,
+		Protocol:    r.Protocol,
+		Region:      r.Region,
+		Maintenance: r.Maintenance,
+		RateLimited: r.RateLimited,
+		Metrics:     r.Metrics,
+	}
+	if r.TLS != nil {
+		res.TLSVersion, res.TLSCipher, res.CertSerial = r.TLS.Version, r.TLS.Cipher, r.TLS.CertSerial
+	}
+
+	for _, w := range d.workers {
+		if !w.drop {
+			select {
+			case w.queue <- res:
+			case <-d.ctx.Done():
+			}
+			continue
+		}
+		select {
+		case w.queue <- res:
+		default:
+			if n := atomic.AddUint64(&w.dropped, 1); n == 1 || n%1000 == 0 {
+				slog.Warn("Приёмник не успевает, результаты отброшены", "sink", w.name, "dropped", n)
+			}
+		}
+	}
+}
+
+// Close дожидается доставки оставшихся результатов (не дольше sinkShutdownTimeout)
+// и закрывает приёмники. Observe после Close вызывать нельзя.
+func (d *sinkDispatcher) Close() {
+	d.once.Do(func() {
+		for _, w := range d.workers {
+			close(w.queue)
+		}
+		timer := time.AfterFunc(sinkShutdownTimeout, d.cancel)
+		defer timer.Stop()
+
+		for _, w := range d.workers {
+			<-w.done
+			if n := atomic.LoadUint64(&w.dropped); n > 0 {
+				slog.Warn("Приёмник: всего отброшено результатов", "sink", w.name, "dropped", n)
+			}
+		}
+		d.cancel()
+	})
+}
+
+func (w *sinkWorker) run(ctx context.Context) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case r, ok := <-w.queue:
+			if !ok {
+				w.flush(ctx)
+				if err := w.sink.Close(); err != nil {
+					slog.Error("Ошибка при закрытии приёмника", "sink", w.name, "error", err)
+				}
+				return
+			}
+			if ctx.Err() != nil {
+				// Время на остановку истекло: оставшиеся результаты не доставляются
+				continue
+			}
+			if err := w.sink.Write(ctx, r); err != nil {
+				slog.Error("Ошибка приёмника", "sink", w.name, "error", err)
+			}
+		case <-ticker.C:
+			w.flush(ctx)
+		}
+	}
+}
+
+func (w *sinkWorker) flush(ctx context.Context) {
+	if err := w.sink.Flush(ctx); err != nil {
+		slog.Error("Ошибка при отправке накопленного приёмником", "sink", w.name, "error", err)
+	}
+}
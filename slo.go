@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SLOConfig — целевой уровень доступности цели
+type SLOConfig struct {
+	Objective float64 `yaml:"objective"` // процент успешных проверок, например 99.5
+	// Период, за который считается бюджет ошибок, по умолчанию 30 суток
+	Window time.Duration `yaml:"window,omitempty"`
+	// Оповещать, когда неизрасходованный остаток бюджета за период опускается ниже этого процента
+	BudgetAlert float64 `yaml:"budget_alert,omitempty"`
+}
+
+const (
+	defaultSLOWindow = 30 * 24 * time.Hour
+	// Окно, за которое показывается текущая скорость расхода бюджета
+	sloBurnWindow = time.Hour
+)
+
+// errorBudget возвращает допустимую долю неуспешных проверок
+func (s SLOConfig) errorBudget() float64 {
+	return 1 - s.Objective/100
+}
+
+func (s SLOConfig) window() time.Duration {
+	if s.Window > 0 {
+		return s.Window
+	}
+	return defaultSLOWindow
+}
+
+func (s SLOConfig) check() error {
+	if s.Objective <= 0 || s.Objective >= 100 {
+		return fmt.Errorf("slo.objective должен быть в интервале (0, 100)")
+	}
+	if s.Window < 0 {
+		return fmt.Errorf("slo.window не может быть отрицательным")
+	}
+	if s.BudgetAlert < 0 || s.BudgetAlert >= 100 {
+		return fmt.Errorf("slo.budget_alert должен быть в интервале [0, 100)")
+	}
+	return nil
+}
+
+// SLOStatus — выполнение SLO цели за период
+type SLOStatus struct {
+	Target      string        `json:"target"`
+	Objective   float64       `json:"objective"`
+	Window      time.Duration `json:"window"`
+	Checks      int           `json:"checks"`
+	SuccessRate float64       `json:"success_rate"`
+	// Неизрасходованная доля бюджета ошибок за период, %; отрицательная — бюджет превышен
+	BudgetRemaining float64 `json:"budget_remaining"`
+	// Скорость расхода бюджета за последний час: 1 — бюджет закончится ровно к концу периода
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// burnRateOf возвращает отношение доли неуспешных проверок к бюджету ошибок
+func (s SLOConfig) burnRateOf(total, successful int) float64 {
+	if total == 0 || s.errorBudget() <= 0 {
+		return 0
+	}
+	return float64(total-successful) / float64(total) / s.errorBudget()
+}
+
+// status считает выполнение SLO по числу проверок за период и за последний час
+func (s SLOConfig) status(target string, total, successful, recentTotal, recentSuccessful int) SLOStatus {
+	st := SLOStatus{
+		Target:          target,
+		Objective:       s.Objective,
+		Window:          s.window(),
+		Checks:          total,
+		SuccessRate:     100,
+		BudgetRemaining: 100,
+		BurnRate:        s.burnRateOf(recentTotal, recentSuccessful),
+	}
+	if total > 0 {
+		st.SuccessRate = float64(successful) / float64(total) * 100
+		st.BudgetRemaining = (1 - s.burnRateOf(total, successful)) * 100
+	}
+	return st
+}
+
+// BurnRateConfig — многооконные оповещения о скорости расходования бюджета ошибок.
+// Политика срабатывает, когда скорость превышает порог и в длинном, и в коротком окне:
+// длинное окно отсекает кратковременные всплески, короткое — быстро снимает оповещение.
+// Незаданные поля политик берутся из политик по умолчанию.
+type BurnRateConfig struct {
+	Fast *BurnPolicy `yaml:"fast"` // по умолчанию 1h/5m, порог 14.4 (2% месячного бюджета за час)
+	Slow *BurnPolicy `yaml:"slow"` // по умолчанию 72h/6h, порог 1 (бюджет расходуется к концу периода)
+	// Периодичность пересчёта, по умолчанию 1m
+	EvaluateEvery time.Duration `yaml:"evaluate_every"`
+
+	Template        string `yaml:"template"`
+	ResolveTemplate string `yaml:"resolve_template"`
+}
+
+type BurnPolicy struct {
+	Long      time.Duration `yaml:"long"`
+	Short     time.Duration `yaml:"short"`
+	Threshold float64       `yaml:"threshold"`
+}
+
+func defaultBurnPolicies() (fast, slow BurnPolicy) {
+	return BurnPolicy{Long: time.Hour, Short: 5 * time.Minute, Threshold: 14.4},
+		BurnPolicy{Long: 72 * time.Hour, Short: 6 * time.Hour, Threshold: 1}
+}
+
+// UnmarshalYAML разбирает раздел поверх политик по умолчанию, чтобы частично
+// заданная политика, например только threshold, дополнялась остальными полями
+func (c *BurnRateConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain BurnRateConfig
+	fast, slow := defaultBurnPolicies()
+	c.Fast, c.Slow = &fast, &slow
+	return node.Decode((*plain)(c))
+}
+
+func (c BurnRateConfig) check() error {
+	for _, p := range []struct {
+		name   string
+		policy *BurnPolicy
+	}{{"fast", c.Fast}, {"slow", c.Slow}} {
+		if p.policy == nil {
+			continue
+		}
+		if err := p.policy.check(); err != nil {
+			return fmt.Errorf("alerts.burn_rate.%s: %w", p.name, err)
+		}
+	}
+	if c.EvaluateEvery < 0 {
+		return fmt.Errorf("alerts.burn_rate.evaluate_every не может быть отрицательным")
+	}
+	return nil
+}
+
+func (p BurnPolicy) check() error {
+	if p.Long <= 0 || p.Short <= 0 || p.Threshold <= 0 {
+		return fmt.Errorf("long, short и threshold должны быть больше нуля")
+	}
+	if p.Short > p.Long {
+		return fmt.Errorf("short не может быть больше long")
+	}
+	return nil
+}
+
+type namedBurnPolicy struct {
+	name string
+	BurnPolicy
+}
+
+// sloMonitor периодически считает по сохранённым результатам остаток бюджета ошибок
+// и скорость его расходования для целей с SLO и оповещает об их нарушении
+type sloMonitor struct {
+	store    *ResultStore
+	alerter  *Alerter // nil — без оповещений
+	registry *targetRegistry
+	policies []namedBurnPolicy
+	every    time.Duration
+	firing   map[string]bool // ключ: цель/политика
+
+	mu       sync.RWMutex
+	statuses map[string]SLOStatus
+}
+
+// newSLOMonitor создаёт расчёт SLO; политики скорости расхода действуют, только если задан cfg
+func newSLOMonitor(cfg *BurnRateConfig, store *ResultStore, alerter *Alerter, registry *targetRegistry) *sloMonitor {
+	m := &sloMonitor{
+		store:    store,
+		alerter:  alerter,
+		registry: registry,
+		every:    time.Minute,
+		firing:   make(map[string]bool),
+		statuses: make(map[string]SLOStatus),
+	}
+	if cfg == nil {
+		return m
+	}
+
+	fast, slow := defaultBurnPolicies()
+	if cfg.Fast != nil {
+		fast = *cfg.Fast
+	}
+	if cfg.Slow != nil {
+		slow = *cfg.Slow
+	}
+	if cfg.EvaluateEvery > 0 {
+		m.every = cfg.EvaluateEvery
+	}
+	m.policies = []namedBurnPolicy{{"быстрое", fast}, {"медленное", slow}}
+	return m
+}
+
+func (m *sloMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(m.every)
+	defer ticker.Stop()
+
+	m.evaluate(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.evaluate(time.Now())
+		}
+	}
+}
+
+func (m *sloMonitor) evaluate(now time.Time) {
+	statuses := make(map[string]SLOStatus)
+	for _, t := range m.registry.active() {
+		if t.SLO == nil {
+			continue
+		}
+		st, err := m.status(t, now)
+		if err != nil {
+			slog.Error("Ошибка при расчёте бюджета ошибок", "target", t.Name, "error", err)
+			continue
+		}
+		statuses[t.Name] = st
+		if m.alerter == nil {
+			continue
+		}
+		m.evaluateBudget(t, st, now)
+		for _, p := range m.policies {
+			m.evaluatePolicy(t, p, now)
+		}
+	}
+
+	m.mu.Lock()
+	m.statuses = statuses
+	m.mu.Unlock()
+}
+
+func (m *sloMonitor) status(t Target, now time.Time) (SLOStatus, error) {
+	total, successful, err := m.store.Counts(t.Name, now.Add(-t.SLO.window()), now)
+	if err != nil {
+		return SLOStatus{}, err
+	}
+	recentTotal, recentSuccessful, err := m.store.Counts(t.Name, now.Add(-sloBurnWindow), now)
+	if err != nil {
+		return SLOStatus{}, err
+	}
+	return t.SLO.status(t.Name, total, successful, recentTotal, recentSuccessful), nil
+}
+
+// evaluateBudget оповещает, когда остаток бюджета опускается ниже slo.budget_alert, и когда восстанавливается
+func (m *sloMonitor) evaluateBudget(t Target, st SLOStatus, now time.Time) {
+	if t.SLO.BudgetAlert <= 0 {
+		return
+	}
+	key := t.Name + "/бюджет"
+	low := st.BudgetRemaining < t.SLO.BudgetAlert
+	if low == m.firing[key] {
+		return
+	}
+	m.firing[key] = low
+
+	alert := Alert{
+		Target:      t.Name,
+		Resolved:    !low,
+		SuccessRate: st.SuccessRate,
+		BurnRate:    st.BurnRate,
+		Time:        now,
+		Reason:      fmt.Sprintf("остаток бюджета ошибок ниже %.1f%%", t.SLO.BudgetAlert),
+	}
+	if low {
+		alert.Reason += fmt.Sprintf(": осталось %.1f%% за %v", st.BudgetRemaining, st.Window)
+	}
+	m.alerter.notifyBurn(alert)
+}
+
+func (m *sloMonitor) evaluatePolicy(t Target, p namedBurnPolicy, now time.Time) {
+	longSuccess, longRate, err := m.burnRate(t, p.Long, now)
+	if err != nil {
+		slog.Error("Ошибка при расчёте скорости расхода бюджета", "target", t.Name, "error", err)
+		return
+	}
+	_, shortRate, err := m.burnRate(t, p.Short, now)
+	if err != nil {
+		slog.Error("Ошибка при расчёте скорости расхода бюджета", "target", t.Name, "error", err)
+		return
+	}
+
+	key := t.Name + "/" + p.name
+	burning := longRate >= p.Threshold && shortRate >= p.Threshold
+	if burning == m.firing[key] {
+		return
+	}
+	m.firing[key] = burning
+
+	alert := Alert{
+		Target:      t.Name,
+		Resolved:    !burning,
+		SuccessRate: longSuccess,
+		BurnRate:    longRate,
+		Time:        now,
+		Reason: fmt.Sprintf("%s сжигание бюджета ошибок: %.1fx за %v и %.1fx за %v (порог %.1fx)",
+			p.name, longRate, p.Long, shortRate, p.Short, p.Threshold),
+	}
+	m.alerter.notifyBurn(alert)
+}
+
+// burnRate возвращает процент успешных проверок за окно и отношение
+// доли неуспешных к бюджету ошибок SLO
+func (m *sloMonitor) burnRate(t Target, window time.Duration, now time.Time) (float64, float64, error) {
+	total, successful, err := m.store.Counts(t.Name, now.Add(-window), now)
+	if err != nil || total == 0 {
+		return 100, 0, err
+	}
+	return float64(successful) / float64(total) * 100, t.SLO.burnRateOf(total, successful), nil
+}
+
+// snapshot возвращает последний расчёт SLO по целям; nil-монитор возвращает пустой список
+func (m *sloMonitor) snapshot() []SLOStatus {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]SLOStatus, 0, len(m.statuses))
+	for _, st := range m.statuses {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Target < out[j].Target })
+	return out
+}
+
+// hasSLO сообщает, задан ли SLO хотя бы у одной цели
+func hasSLO(targets []Target) bool {
+	for _, t := range targets {
+		if t.SLO != nil {
+			return true
+		}
+	}
+	return false
+}
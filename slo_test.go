@@ -0,0 +1,229 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBurnRateConfigPolicies(t *testing.T) {
+	fast, slow := defaultBurnPolicies()
+
+	tests := []struct {
+		name    string
+		src     string
+		fast    BurnPolicy
+		slow    BurnPolicy
+		wantErr string
+	}{
+		{"по умолчанию", "evaluate_every: 1m", fast, slow, ""},
+		{"только порог", "fast: {threshold: 10}", BurnPolicy{Long: time.Hour, Short: 5 * time.Minute, Threshold: 10}, slow, ""},
+		{"только окна", "slow: {long: 24h, short: 2h}", fast, BurnPolicy{Long: 24 * time.Hour, Short: 2 * time.Hour, Threshold: 1}, ""},
+		{"полная политика", "fast: {long: 30m, short: 1m, threshold: 20}", BurnPolicy{Long: 30 * time.Minute, Short: time.Minute, Threshold: 20}, slow, ""},
+		{"пустой раздел политики", "fast:", fast, slow, ""},
+		{"нулевой порог", "fast: {threshold: 0}", BurnPolicy{}, BurnPolicy{}, "alerts.burn_rate.fast: long, short и threshold"},
+		{"отрицательный порог", "slow: {threshold: -1}", BurnPolicy{}, BurnPolicy{}, "alerts.burn_rate.slow: long, short и threshold"},
+		{"нулевое окно", "slow: {short: 0s}", BurnPolicy{}, BurnPolicy{}, "alerts.burn_rate.slow: long, short и threshold"},
+		{"короткое окно длиннее длинного", "fast: {short: 2h}", BurnPolicy{}, BurnPolicy{}, "short не может быть больше long"},
+		{"отрицательная периодичность", "evaluate_every: -1m", BurnPolicy{}, BurnPolicy{}, "evaluate_every"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg BurnRateConfig
+			if err := yaml.Unmarshal([]byte(tt.src), &cfg); err != nil {
+				t.Fatal(err)
+			}
+			err := cfg.check()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			m := newSLOMonitor(&cfg, nil, nil, nil)
+			if len(m.policies) != 2 || m.policies[0].BurnPolicy != tt.fast || m.policies[1].BurnPolicy != tt.slow {
+				t.Errorf("политики %+v, ожидалось %+v и %+v", m.policies, tt.fast, tt.slow)
+			}
+		})
+	}
+}
+
+// recordingNotifier запоминает отправленные оповещения
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Notify(alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func (n *recordingNotifier) take() []Alert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := n.alerts
+	n.alerts = nil
+	return out
+}
+
+// burnSegment — n результатов с шагом every, начиная за ago до момента расчёта
+type burnSegment struct {
+	ago    time.Duration
+	n      int
+	every  time.Duration
+	result CheckResult
+}
+
+type burnFixture struct {
+	store    *ResultStore
+	monitor  *sloMonitor
+	alerter  *Alerter
+	notifier *recordingNotifier
+}
+
+// newBurnFixture создаёт монитор SLO с целью api (objective 99) и политиками из src
+func newBurnFixture(t *testing.T, src string) burnFixture {
+	t.Helper()
+	store, err := openStore(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var cfg BurnRateConfig
+	if err := yaml.Unmarshal([]byte(src), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	alerter, err := newAlerter(AlertConfig{BurnRate: &cfg}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := &recordingNotifier{}
+	alerter.notifiers = []Notifier{notifier}
+
+	registry := newTargetRegistry([]Target{{Name: "api", URL: "https://example.com/", SLO: &SLOConfig{Objective: 99}}}, TargetDefaults{})
+	return burnFixture{store, newSLOMonitor(&cfg, store, alerter, registry), alerter, notifier}
+}
+
+func (f burnFixture) record(t *testing.T, now time.Time, segments ...burnSegment) {
+	t.Helper()
+	for _, s := range segments {
+		for i := 0; i < s.n; i++ {
+			r := s.result
+			r.Target = "api"
+			r.Timestamp = now.Add(-s.ago + time.Duration(i)*s.every)
+			if err := f.store.Save(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// evaluate пересчитывает SLO и возвращает отправленные оповещения
+func (f burnFixture) evaluate(now time.Time) []Alert {
+	f.monitor.evaluate(now)
+	f.alerter.Wait()
+	return f.notifier.take()
+}
+
+// Быстрая политика задана только порогом: окна 1h и 5m берутся по умолчанию,
+// медленная политика отключена высоким порогом
+const burnTestPolicies = "fast: {threshold: 10}\nslow: {threshold: 1000}"
+
+var (
+	burnOK      = CheckResult{Success: true}
+	burnFail    = CheckResult{Error: "status 500"}
+	burnMaint   = CheckResult{Error: "status 503", Maintenance: true}
+	burnShedErr = CheckResult{Error: "перегрузка", Shed: true}
+)
+
+func TestSLOBurnPolicyFiring(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []burnSegment
+		fire     bool
+	}{
+		{
+			name: "оба окна выше порога",
+			// длинное окно: 10 из 60 неуспешны (16.7x), короткое: все неуспешны
+			segments: []burnSegment{{60 * time.Minute, 50, time.Minute, burnOK}, {4 * time.Minute, 10, 20 * time.Second, burnFail}},
+			fire:     true,
+		},
+		{
+			name: "только короткое окно",
+			// длинное окно: 3 из 53 (5.7x)
+			segments: []burnSegment{{60 * time.Minute, 50, time.Minute, burnOK}, {3 * time.Minute, 3, 30 * time.Second, burnFail}},
+		},
+		{
+			name: "только длинное окно",
+			// длинное окно: 20 из 40 (50x), в коротком сбоев нет
+			segments: []burnSegment{{50 * time.Minute, 20, time.Minute, burnFail}, {4 * time.Minute, 20, 10 * time.Second, burnOK}},
+		},
+		{
+			name:     "сбои при обслуживании и отброшенные проверки не учитываются",
+			segments: []burnSegment{{60 * time.Minute, 50, time.Minute, burnOK}, {4 * time.Minute, 10, 20 * time.Second, burnMaint}, {4 * time.Minute, 10, 20 * time.Second, burnShedErr}},
+		},
+		{name: "нет результатов"},
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newBurnFixture(t, burnTestPolicies)
+			f.record(t, now, tt.segments...)
+
+			alerts := f.evaluate(now)
+			if !tt.fire {
+				if len(alerts) != 0 {
+					t.Errorf("лишние оповещения: %+v", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 || alerts[0].Resolved || !strings.Contains(alerts[0].Reason, "быстрое") {
+				t.Fatalf("оповещения %+v, ожидалось одно о быстром сжигании", alerts)
+			}
+			if !strings.Contains(alerts[0].Reason, "за 1h0m0s") || !strings.Contains(alerts[0].Reason, "за 5m0s") {
+				t.Errorf("окна политики по умолчанию не применены: %s", alerts[0].Reason)
+			}
+		})
+	}
+}
+
+func TestSLOBurnPolicyResolves(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := newBurnFixture(t, burnTestPolicies)
+	f.record(t, now, burnSegment{60 * time.Minute, 50, time.Minute, burnOK}, burnSegment{4 * time.Minute, 10, 20 * time.Second, burnFail})
+
+	if alerts := f.evaluate(now); len(alerts) != 1 || alerts[0].Resolved {
+		t.Fatalf("оповещения %+v, ожидалось одно о сжигании", alerts)
+	}
+	// Повторный расчёт при том же расходе не дублирует оповещение
+	if alerts := f.evaluate(now.Add(time.Second)); len(alerts) != 0 {
+		t.Fatalf("повторные оповещения: %+v", alerts)
+	}
+
+	// Через 6 минут короткое окно чистое, хотя в длинном расход ещё выше порога
+	later := now.Add(6 * time.Minute)
+	f.record(t, later, burnSegment{5 * time.Minute, 30, 10 * time.Second, burnOK})
+	alerts := f.evaluate(later)
+	if len(alerts) != 1 || !alerts[0].Resolved {
+		t.Fatalf("оповещения %+v, ожидалось снятие", alerts)
+	}
+	if alerts[0].BurnRate < 10 {
+		t.Errorf("расход в длинном окне %.1f, ожидался выше порога", alerts[0].BurnRate)
+	}
+	if alerts := f.evaluate(later.Add(time.Minute)); len(alerts) != 0 {
+		t.Errorf("оповещения после снятия: %+v", alerts)
+	}
+}
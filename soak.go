@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// soakRecorder — результаты длительного теста (-rotate): сырые результаты каждые every
+// записываются в отдельный файл results-ГГГГММДД-ЧЧММ.json и не накапливаются в памяти,
+// а для итогов хранятся только счётчики и гистограммы задержек целей
+type soakRecorder struct {
+	every      time.Duration
+	dir        string
+	run        *RunMetadata
+	histograms *targetHistograms
+
+	mu     sync.Mutex
+	buf    []CheckResult
+	totals map[string]*soakTotals
+	files  []string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// soakTotals — счётчики цели за весь тест
+type soakTotals struct {
+	Checks      int
+	Successful  int
+	Shed        int
+	Maintenance int
+}
+
+func newSoakRecorder(every time.Duration, dir string, run *RunMetadata, histograms *targetHistograms) *soakRecorder {
+	s := &soakRecorder{
+		every:      every,
+		dir:        dir,
+		run:        run,
+		histograms: histograms,
+		totals:     make(map[string]*soakTotals),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *soakRecorder) Observe(r CheckResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, r)
+	t, ok := s.totals[r.Target]
+	if !ok {
+		t = &soakTotals{}
+		s.totals[r.Target] = t
+	}
+	switch {
+	case r.Shed:
+		t.Shed++
+	case r.Warmup:
+	case !r.countsAgainstSLA():
+		t.Maintenance++
+	default:
+		t.Checks++
+		if r.Success {
+			t.Successful++
+		}
+	}
+}
+
+func (s *soakRecorder) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkpoint()
+		}
+	}
+}
+
+// checkpoint записывает накопленные с прошлой контрольной точки результаты в новый файл
+// и выводит в журнал итоги целей с начала теста
+func (s *soakRecorder) checkpoint() {
+	s.mu.Lock()
+	results := s.buf
+	s.buf = nil
+	totals := make(map[string]soakTotals, len(s.totals))
+	for name, t := range s.totals {
+		totals[name] = *t
+	}
+	s.mu.Unlock()
+
+	if len(results) > 0 {
+		now := time.Now()
+		path := filepath.Join(s.dir, "results-"+now.Format("20060102-1504")+".json")
+		// Файл с тем же именем (контрольные точки чаще раза в минуту) дополняется индексом
+		for i := 2; ; i++ {
+			if _, err := os.Stat(path); err != nil {
+				break
+			}
+			path = filepath.Join(s.dir, fmt.Sprintf("results-%s-%d.json", now.Format("20060102-1504"), i))
+		}
+		run := *s.run
+		run.Finished = now
+		data, err := json.MarshalIndent(TestResult{Results: results, Run: &run}, "", "    ")
+		if err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			slog.Error("Ошибка при записи контрольной точки", "path", path, "error", err)
+		} else {
+			s.mu.Lock()
+			s.files = append(s.files, path)
+			s.mu.Unlock()
+			slog.Info("Контрольная точка", "path", path, "results", len(results))
+		}
+	}
+
+	s.histograms.each(func(name string, h *latencyHistogram) {
+		t := totals[name]
+		slog.Info("Итоги цели с начала теста", "target", name, "checks", t.Checks,
+			"success_rate", fmt.Sprintf("%.2f%%", t.SuccessRate()),
+			"p50", h.quantile(50).Round(time.Millisecond), "p95", h.quantile(95).Round(time.Millisecond),
+			"p99", h.quantile(99).Round(time.Millisecond), "max", h.max.Round(time.Millisecond))
+	})
+}
+
+// Close записывает последние результаты и возвращает список файлов теста
+func (s *soakRecorder) Close() []string {
+	close(s.stop)
+	<-s.done
+	s.checkpoint()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.files...)
+}
+
+// printSummary выводит итоги длительного теста по счётчикам и гистограммам целей
+func (s *soakRecorder) printSummary(out io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.totals))
+	for name := range s.totals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	latencies := s.histograms.snapshot()
+	var table bytes.Buffer
+	colors := make([]string, 0, len(names))
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tпроверок\tуспешных\tp50\tp95\tp99\tmax")
+	var all soakTotals
+	for _, name := range names {
+		t := s.totals[name]
+		all.Checks += t.Checks
+		all.Successful += t.Successful
+		all.Shed += t.Shed
+		all.Maintenance += t.Maintenance
+		h := latencies[name]
+		rate := t.SuccessRate()
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%v\t%v\t%v\t%v\n", name, t.Checks, rate,
+			h.P50.Round(time.Millisecond), h.P95.Round(time.Millisecond), h.P99.Round(time.Millisecond), h.Max.Round(time.Millisecond))
+		colors = append(colors, rateColor(rate))
+	}
+	w.Flush()
+	printColoredTable(out, &table, colors)
+
+	rate := all.SuccessRate()
+	fmt.Fprintln(out, colorize(fmt.Sprintf("Процент успешных запросов: %.2f%%", rate), rateColor(rate)))
+	if all.Shed > 0 {
+		fmt.Fprintf(out, "Отброшено или прервано проверок: %d\n", all.Shed)
+	}
+	if all.Maintenance > 0 {
+		fmt.Fprintf(out, "Сбоев во время обслуживания (не учитываются): %d\n", all.Maintenance)
+	}
+	fmt.Fprintf(out, "Файлов с результатами: %d\n", len(s.files))
+}
+
+func (t soakTotals) SuccessRate() float64 {
+	if t.Checks == 0 {
+		return 0
+	}
+	return float64(t.Successful) / float64(t.Checks) * 100
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// latencyStats — сводка по набору результатов проверок
+type latencyStats struct {
+	Checks     int           `json:"checks"`
+	Successful int           `json:"successful"`
+	Avg        time.Duration `json:"avg"`
+	P50        time.Duration `json:"p50"`
+	P95        time.Duration `json:"p95"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+}
+
+func (s latencyStats) SuccessRate() float64 {
+	if s.Checks == 0 {
+		return 0
+	}
+	return float64(s.Successful) / float64(s.Checks) * 100
+}
+
+// computeStats считает статистику без учёта отброшенных и прогревочных проверок
+// и сбоев во время обслуживания.
+// Задержки берутся только по успешным проверкам: сбой по таймауту или отказ соединения
+// сдвигали бы процентили к таймауту или нулю. Процентили берутся из гистограммы,
+// поэтому память не зависит от числа результатов.
+func computeStats(results []CheckResult) latencyStats {
+	var st latencyStats
+	var h latencyHistogram
+
+	for _, r := range results {
+		if r.Warmup || !r.countsAgainstSLA() {
+			continue
+		}
+		st.Checks++
+		if r.Success {
+			st.Successful++
+			h.record(r.Latency)
+		}
+	}
+
+	if st.Successful == 0 {
+		return st
+	}
+
+	st.Avg = h.mean()
+	st.P50 = h.quantile(50)
+	st.P95 = h.quantile(95)
+	st.P99 = h.quantile(99)
+	st.Max = h.max
+
+	return st
+}
+
+// percentile возвращает p-й процентиль отсортированной выборки (метод nearest-rank)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// groupByTarget раскладывает результаты по целям, сохраняя порядок появления целей
+func groupByTarget(results []CheckResult) ([]string, map[string][]CheckResult) {
+	var names []string
+	groups := make(map[string][]CheckResult)
+	for _, r := range results {
+		if _, ok := groups[r.Target]; !ok {
+			names = append(names, r.Target)
+		}
+		groups[r.Target] = append(groups[r.Target], r)
+	}
+	return names, groups
+}
+
+// printTargetSummaries выводит итоги запуска по каждой цели; строки наборов данных
+// выводятся отдельно, в printDatasetSummaries
+func printTargetSummaries(out io.Writer, results []CheckResult) {
+	var plain []CheckResult
+	for _, r := range results {
+		if r.Dataset == "" {
+			plain = append(plain, r)
+		}
+	}
+	names, groups := groupByTarget(plain)
+	if len(names) == 0 {
+		return
+	}
+
+	var table bytes.Buffer
+	colors := make([]string, 0, len(names))
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tпроверок\tуспешных\tp50\tp95\tmax")
+	for _, name := range names {
+		st := computeStats(groups[name])
+		if st.Successful == 0 {
+			// Задержки считаются по успешным проверкам
+			fmt.Fprintf(w, "%s\t%d\t%.2f%%\t-\t-\t-\n", name, st.Checks, st.SuccessRate())
+		} else {
+			fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%v\t%v\t%v\n", name, st.Checks, st.SuccessRate(),
+				st.P50.Round(time.Millisecond), st.P95.Round(time.Millisecond), st.Max.Round(time.Millisecond))
+		}
+		colors = append(colors, rateColor(st.SuccessRate()))
+	}
+	w.Flush()
+	printColoredTable(out, &table, colors)
+}
+
+// mannWhitneyP возвращает двустороннее p-значение критерия Манна — Уитни
+// (нормальное приближение) для гипотезы о совпадении распределений двух выборок
+func mannWhitneyP(a, b []time.Duration) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		v     time.Duration
+		first bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Одинаковым значениям назначается средний ранг
+	var rankSum float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].first {
+				rankSum += rank
+			}
+		}
+		i = j
+	}
+
+	u := rankSum - float64(n1*(n1+1))/2
+	mean := float64(n1*n2) / 2
+	sd := math.Sqrt(float64(n1*n2*(n1+n2+1)) / 12)
+	if sd == 0 {
+		return 1
+	}
+	z := (u - mean) / sd
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// twoProportionP возвращает двустороннее p-значение z-критерия для разности двух долей
+func twoProportionP(x1, n1, x2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	p := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(p * (1 - p) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+	z := (p1 - p2) / se
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
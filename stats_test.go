@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeStatsSkipsUncounted(t *testing.T) {
+	results := []CheckResult{
+		{Success: true, Latency: 10 * time.Millisecond},
+		{Success: true, Latency: 30 * time.Millisecond},
+		{Success: false, Latency: time.Second},
+		{Success: false, Shed: true},
+		{Success: true, Warmup: true, Latency: time.Second},
+		{Success: false, Maintenance: true, Latency: time.Second},
+		// Успешная проверка во время обслуживания учитывается
+		{Success: true, Maintenance: true, Latency: 20 * time.Millisecond},
+	}
+
+	st := computeStats(results)
+	if st.Checks != 4 || st.Successful != 3 {
+		t.Errorf("проверок %d, успешных %d, ожидалось 4 и 3", st.Checks, st.Successful)
+	}
+	if st.Max != 30*time.Millisecond || st.Avg != 20*time.Millisecond {
+		t.Errorf("max %v, avg %v", st.Max, st.Avg)
+	}
+}
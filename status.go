@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusRange — допустимые коды ответа [from, to]
+type statusRange struct {
+	from, to int
+}
+
+// compileExpectedStatus разбирает expected_status: коды (401), классы (2xx) и диапазоны (200-204)
+func (t *Target) compileExpectedStatus() error {
+	t.expectedStatus = nil
+	for _, s := range t.ExpectedStatus {
+		s = strings.ToLower(strings.TrimSpace(s))
+		var r statusRange
+		var err error
+		switch {
+		case len(s) == 3 && strings.HasSuffix(s, "xx"):
+			var class int
+			class, err = strconv.Atoi(s[:1])
+			r = statusRange{class * 100, class*100 + 99}
+		case strings.Contains(s, "-"):
+			from, to, _ := strings.Cut(s, "-")
+			if r.from, err = strconv.Atoi(strings.TrimSpace(from)); err == nil {
+				r.to, err = strconv.Atoi(strings.TrimSpace(to))
+			}
+		default:
+			r.from, err = strconv.Atoi(s)
+			r.to = r.from
+		}
+		if err != nil || r.from < 100 || r.to > 599 || r.from > r.to {
+			return fmt.Errorf("expected_status: некорректный код %q", s)
+		}
+		t.expectedStatus = append(t.expectedStatus, r)
+	}
+	return nil
+}
+
+// statusExpected сообщает, считается ли код ответа успешным; по умолчанию допустим только 200
+func (t Target) statusExpected(status int) bool {
+	if len(t.expectedStatus) == 0 {
+		return status == 200
+	}
+	for _, r := range t.expectedStatus {
+		if status >= r.from && status <= r.to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readTargetList читает список URL, по одному в строке, и возвращает цели с настройками
+// из defaults. Пустые строки и строки, начинающиеся с #, пропускаются, повторы — тоже;
+// URL без схемы проверяется по https.
+func readTargetList(r io.Reader, defaults TargetDefaults) ([]Target, error) {
+	var targets []Target
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		url := strings.TrimSpace(scanner.Text())
+		if url == "" || strings.HasPrefix(url, "#") {
+			continue
+		}
+		if !strings.Contains(url, "://") {
+			url = "https://" + url
+		}
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+
+		t := Target{URL: url}
+		t.inherit(defaults)
+		for _, ft := range t.expandVariants() {
+			if err := ft.normalize(); err != nil {
+				return nil, fmt.Errorf("строка %d: %w", line, err)
+			}
+			targets = append(targets, ft)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("во входных данных нет ни одного URL")
+	}
+	return targets, nil
+}
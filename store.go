@@ -0,0 +1,301 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	target     TEXT    NOT NULL,
+	ts         INTEGER NOT NULL, -- unix-время в наносекундах
+	success    INTEGER NOT NULL,
+	shed       INTEGER NOT NULL DEFAULT 0,
+	latency_ns INTEGER NOT NULL,
+	error      TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS results_target_ts ON results (target, ts);
+`
+
+// storeMigrations применяются по порядку; номер последней хранится в PRAGMA user_version
+var storeMigrations = []string{
+	`ALTER TABLE results ADD COLUMN status INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE annotations (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		start_ns INTEGER NOT NULL,
+		end_ns   INTEGER NOT NULL,
+		target   TEXT    NOT NULL DEFAULT '', -- пустое значение относится ко всем целям
+		kind     TEXT    NOT NULL DEFAULT '',
+		text     TEXT    NOT NULL
+	);
+	CREATE INDEX annotations_start ON annotations (start_ns)`,
+	`CREATE TABLE missed_windows (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		target   TEXT    NOT NULL,
+		start_ns INTEGER NOT NULL,
+		end_ns   INTEGER NOT NULL
+	);
+	CREATE INDEX missed_windows_target_start ON missed_windows (target, start_ns)`,
+	`ALTER TABLE results ADD COLUMN remote_ip TEXT NOT NULL DEFAULT '';
+	ALTER TABLE results ADD COLUMN protocol TEXT NOT NULL DEFAULT '';
+	ALTER TABLE results ADD COLUMN tls_version TEXT NOT NULL DEFAULT '';
+	ALTER TABLE results ADD COLUMN tls_cipher TEXT NOT NULL DEFAULT '';
+	ALTER TABLE results ADD COLUMN cert_serial TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE results ADD COLUMN region TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE results ADD COLUMN maintenance INTEGER NOT NULL DEFAULT 0`,
+}
+
+// ResultStore хранит историю результатов проверок в SQLite
+type ResultStore struct {
+	db *sql.DB
+}
+
+func openStore(path string) (*ResultStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite не любит параллельную запись из нескольких соединений
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("создание схемы %s: %w", path, err)
+	}
+	if err := migrateStore(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("обновление схемы %s: %w", path, err)
+	}
+
+	return &ResultStore{db: db}, nil
+}
+
+func migrateStore(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(storeMigrations); i++ {
+		if err := applyMigration(db, i); err != nil {
+			return fmt.Errorf("миграция %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration выполняет миграцию и обновляет номер версии в одной транзакции,
+// чтобы сбой посреди миграции из нескольких команд не оставлял базу частично изменённой
+func applyMigration(db *sql.DB, i int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(storeMigrations[i]); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *ResultStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *ResultStore) Save(r CheckResult) error {
+	var tlsVersion, tlsCipher, certSerial string
+	if r.TLS != nil {
+		tlsVersion, tlsCipher, certSerial = r.TLS.Version, r.TLS.Cipher, r.TLS.CertSerial
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO results (target, ts, success, status, shed, latency_ns, error, remote_ip, protocol, tls_version, tls_cipher, cert_serial, region, maintenance)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Target, r.Timestamp.UnixNano(), r.Success, r.Status, r.Shed, int64(r.Latency), r.Error,
+		r.RemoteIP, r.Protocol, tlsVersion, tlsCipher, certSerial, r.Region, r.Maintenance,
+	)
+	return err
+}
+
+// Query возвращает результаты за период [from, to) в порядке времени.
+// Пустое имя цели означает все цели.
+func (s *ResultStore) Query(target string, from, to time.Time) ([]CheckResult, error) {
+	query := `SELECT target, ts, success, status, shed, latency_ns, error, remote_ip, protocol, tls_version, tls_cipher, cert_serial, region, maintenance
+		FROM results WHERE ts >= ? AND ts < ?`
+	args := []interface{}{from.UnixNano(), to.UnixNano()}
+	if target != "" {
+		query += ` AND target = ?`
+		args = append(args, target)
+	}
+	query += ` ORDER BY ts`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []CheckResult
+	for rows.Next() {
+		var r CheckResult
+		var ts, latency int64
+		var tlsInfo TLSDetails
+		if err := rows.Scan(&r.Target, &ts, &r.Success, &r.Status, &r.Shed, &latency, &r.Error,
+			&r.RemoteIP, &r.Protocol, &tlsInfo.Version, &tlsInfo.Cipher, &tlsInfo.CertSerial, &r.Region, &r.Maintenance); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.Unix(0, ts)
+		r.Latency = time.Duration(latency)
+		if tlsInfo.Version != "" {
+			r.TLS = &tlsInfo
+		}
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// Counts возвращает число проверок цели за период [from, to) и число успешных среди них;
+// сбои во время обслуживания не учитываются
+func (s *ResultStore) Counts(target string, from, to time.Time) (total, successful int, err error) {
+	err = s.db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(success), 0) FROM results
+		WHERE target = ? AND ts >= ? AND ts < ? AND shed = 0 AND (success = 1 OR maintenance = 0)`,
+		target, from.UnixNano(), to.UnixNano(),
+	).Scan(&total, &successful)
+	return total, successful, err
+}
+
+// AddAnnotation сохраняет аннотацию и возвращает её с присвоенным идентификатором
+func (s *ResultStore) AddAnnotation(a Annotation) (Annotation, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO annotations (start_ns, end_ns, target, kind, text) VALUES (?, ?, ?, ?, ?)`,
+		a.Start.UnixNano(), a.End.UnixNano(), a.Target, a.Kind, a.Text,
+	)
+	if err != nil {
+		return a, err
+	}
+	a.ID, err = res.LastInsertId()
+	return a, err
+}
+
+// Annotations возвращает аннотации, пересекающиеся с периодом [from, to), в порядке времени.
+// Для непустого имени цели возвращаются её аннотации и общие.
+func (s *ResultStore) Annotations(target string, from, to time.Time) ([]Annotation, error) {
+	query := `SELECT id, start_ns, end_ns, target, kind, text FROM annotations WHERE start_ns < ? AND end_ns >= ?`
+	args := []interface{}{to.UnixNano(), from.UnixNano()}
+	if target != "" {
+		query += ` AND (target = '' OR target = ?)`
+		args = append(args, target)
+	}
+	query += ` ORDER BY start_ns`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Annotation
+	for rows.Next() {
+		var a Annotation
+		var start, end int64
+		if err := rows.Scan(&a.ID, &start, &end, &a.Target, &a.Kind, &a.Text); err != nil {
+			return nil, err
+		}
+		a.Start, a.End = time.Unix(0, start), time.Unix(0, end)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// DeleteAnnotation удаляет аннотацию и сообщает, существовала ли она
+func (s *ResultStore) DeleteAnnotation(id int64) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM annotations WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// LastCheck возвращает время последней сохранённой проверки цели; нулевое время, если проверок не было
+func (s *ResultStore) LastCheck(target string) (time.Time, error) {
+	var ts sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MAX(ts) FROM results WHERE target = ?`, target).Scan(&ts); err != nil {
+		return time.Time{}, err
+	}
+	if !ts.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, ts.Int64), nil
+}
+
+// AddMissedWindow сохраняет период, в который цель не проверялась
+func (s *ResultStore) AddMissedWindow(w MissedWindow) error {
+	_, err := s.db.Exec(
+		`INSERT INTO missed_windows (target, start_ns, end_ns) VALUES (?, ?, ?)`,
+		w.Target, w.Start.UnixNano(), w.End.UnixNano(),
+	)
+	return err
+}
+
+// MissedWindows возвращает пропущенные периоды, пересекающиеся с [from, to), в порядке времени.
+// Пустое имя цели означает все цели.
+func (s *ResultStore) MissedWindows(target string, from, to time.Time) ([]MissedWindow, error) {
+	query := `SELECT target, start_ns, end_ns FROM missed_windows WHERE start_ns < ? AND end_ns > ?`
+	args := []interface{}{to.UnixNano(), from.UnixNano()}
+	if target != "" {
+		query += ` AND target = ?`
+		args = append(args, target)
+	}
+	query += ` ORDER BY start_ns`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []MissedWindow
+	for rows.Next() {
+		var w MissedWindow
+		var start, end int64
+		if err := rows.Scan(&w.Target, &start, &end); err != nil {
+			return nil, err
+		}
+		w.Start, w.End = time.Unix(0, start), time.Unix(0, end)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// RecentLatencies возвращает задержки последних limit успешных проверок цели, от новых к старым
+func (s *ResultStore) RecentLatencies(target string, limit int) ([]time.Duration, error) {
+	rows, err := s.db.Query(
+		`SELECT latency_ns FROM results WHERE target = ? AND success = 1 AND shed = 0 ORDER BY ts DESC LIMIT ?`,
+		target, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []time.Duration
+	for rows.Next() {
+		var ns int64
+		if err := rows.Scan(&ns); err != nil {
+			return nil, err
+		}
+		out = append(out, time.Duration(ns))
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createOldStore создаёт базу в схеме версии version с одним результатом цели old
+func createOldStore(t *testing.T, path string, version int) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmts := append([]string{storeSchema}, storeMigrations[:version]...)
+	stmts = append(stmts,
+		fmt.Sprintf(`PRAGMA user_version = %d`, version),
+		`INSERT INTO results (target, ts, success, latency_ns) VALUES ('old', 1000, 1, 5000000)`,
+	)
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("версия %d: %v", version, err)
+		}
+	}
+}
+
+// storeColumns возвращает имена столбцов таблицы
+func storeColumns(t *testing.T, s *ResultStore, table string) map[string]bool {
+	t.Helper()
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		columns[name] = true
+	}
+	return columns
+}
+
+func TestStoreMigrations(t *testing.T) {
+	wantColumns := map[string][]string{
+		"results":        {"status", "remote_ip", "protocol", "tls_version", "tls_cipher", "cert_serial", "region", "maintenance"},
+		"annotations":    {"start_ns", "end_ns", "target", "kind", "text"},
+		"missed_windows": {"target", "start_ns", "end_ns"},
+	}
+
+	for version := 0; version < len(storeMigrations); version++ {
+		t.Run(fmt.Sprintf("версия %d", version), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "results.db")
+			createOldStore(t, path, version)
+
+			s, err := openStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			var got int
+			if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != len(storeMigrations) {
+				t.Errorf("user_version %d, ожидалась %d", got, len(storeMigrations))
+			}
+			for table, names := range wantColumns {
+				columns := storeColumns(t, s, table)
+				for _, name := range names {
+					if !columns[name] {
+						t.Errorf("в %s нет столбца %s", table, name)
+					}
+				}
+			}
+
+			// Старые результаты читаются со значениями новых столбцов по умолчанию
+			results, err := s.Query("old", time.Unix(0, 0), time.Unix(1, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || !results[0].Success || results[0].Latency != 5*time.Millisecond ||
+				results[0].Status != 0 || results[0].Maintenance || results[0].Region != "" {
+				t.Errorf("старые результаты: %+v", results)
+			}
+			if err := s.Save(CheckResult{Target: "new", Timestamp: time.Unix(2, 0), Success: true, Maintenance: true}); err != nil {
+				t.Errorf("запись после миграции: %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	for i := 0; i < 2; i++ {
+		s, err := openStore(path)
+		if err != nil {
+			t.Fatalf("открытие %d: %v", i+1, err)
+		}
+		s.Close()
+	}
+}
+
+func TestStoreCounts(t *testing.T) {
+	s, err := openStore(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	base := time.Unix(1000, 0)
+	results := []CheckResult{
+		{Target: "api", Success: true},
+		{Target: "api", Success: true},
+		{Target: "api", Error: "status 500"},
+		{Target: "api", Error: "перегрузка", Shed: true},
+		{Target: "api", Error: "status 503", Maintenance: true},
+		{Target: "api", Success: true, Maintenance: true},
+		{Target: "other", Error: "status 500"},
+	}
+	for i, r := range results {
+		r.Timestamp = base.Add(time.Duration(i) * time.Second)
+		if err := s.Save(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		target         string
+		from, to       time.Time
+		total, success int
+	}{
+		{"весь период", "api", base, base.Add(time.Minute), 4, 3},
+		{"правая граница не включается", "api", base, base.Add(2 * time.Second), 2, 2},
+		{"только обслуживание", "api", base.Add(4 * time.Second), base.Add(6 * time.Second), 1, 1},
+		{"другая цель", "other", base, base.Add(time.Minute), 1, 0},
+		{"нет результатов", "api", base.Add(time.Hour), base.Add(2 * time.Hour), 0, 0},
+	}
+	for _, tt := range tests {
+		total, successful, err := s.Counts(tt.target, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != tt.total || successful != tt.success {
+			t.Errorf("%s: получено %d/%d, ожидалось %d/%d", tt.name, successful, total, tt.success, tt.total)
+		}
+	}
+}
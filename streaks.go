@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// flakyThreshold — доля смен успеха и неуспеха между соседними проверками,
+// начиная с которой сбои считаются перемежающимися
+const flakyThreshold = 0.2
+
+// streakStats — серии неуспешных проверок цели
+type streakStats struct {
+	Name     string
+	Checks   int
+	Failures int
+	// Наибольшее число неуспешных проверок подряд и число серий сбоев
+	LongestFailures int
+	FailureStreaks  int
+	First, Last     CheckResult // первая и последняя неуспешные проверки
+	// Доля соседних пар проверок с разным исходом: 0 — исход не менялся, 1 — чередовался каждый раз
+	Flakiness float64
+}
+
+// computeStreaks считает серии сбоев по результатам одной цели без учёта
+// отброшенных и прогревочных проверок
+func computeStreaks(name string, results []CheckResult) streakStats {
+	rs := make([]CheckResult, 0, len(results))
+	for _, r := range results {
+		if !r.Shed && !r.Warmup {
+			rs = append(rs, r)
+		}
+	}
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].Timestamp.Before(rs[j].Timestamp) })
+
+	st := streakStats{Name: name, Checks: len(rs)}
+	run, changes := 0, 0
+	for i, r := range rs {
+		if i > 0 && r.Success != rs[i-1].Success {
+			changes++
+		}
+		if r.Success {
+			run = 0
+			continue
+		}
+		if st.Failures == 0 {
+			st.First = r
+		}
+		st.Last = r
+		st.Failures++
+		if run == 0 {
+			st.FailureStreaks++
+		}
+		run++
+		st.LongestFailures = max(st.LongestFailures, run)
+	}
+	if len(rs) > 1 {
+		st.Flakiness = float64(changes) / float64(len(rs)-1)
+	}
+	return st
+}
+
+// verdict кратко описывает характер сбоев: один период или нестабильная работа
+func (s streakStats) verdict() string {
+	switch {
+	case s.FailureStreaks == 1:
+		return "один период сбоев"
+	case s.Flakiness >= flakyThreshold:
+		return "нестабильна: сбои перемежаются с успешными проверками"
+	default:
+		return fmt.Sprintf("%d периодов сбоев", s.FailureStreaks)
+	}
+}
+
+// printStreaks выводит серии сбоев целей, у которых были неуспешные проверки
+func printStreaks(out io.Writer, results []CheckResult) {
+	names, groups := groupByTarget(results)
+
+	var failing []streakStats
+	for _, name := range names {
+		if st := computeStreaks(name, groups[name]); st.Failures > 0 {
+			failing = append(failing, st)
+		}
+	}
+	if len(failing) == 0 {
+		return
+	}
+
+	fmt.Fprintln(out, "\nСбои:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "цель\tсбоев\tподряд макс.\tпервый\tпоследний\tнестабильность\tвывод")
+	for _, s := range failing {
+		fmt.Fprintf(w, "%s\t%d из %d\t%d\t%s\t%s\t%.2f\t%s\n", s.Name, s.Failures, s.Checks, s.LongestFailures,
+			s.First.Timestamp.Format("2006-01-02 15:04:05"), s.Last.Timestamp.Format("2006-01-02 15:04:05"),
+			s.Flakiness, s.verdict())
+	}
+	w.Flush()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// targetRegistry — изменяемый во время работы набор целей
+type targetRegistry struct {
+	mu       sync.RWMutex
+	defaults TargetDefaults
+	targets  []Target
+	paused   map[string]bool
+}
+
+func newTargetRegistry(targets []Target, defaults TargetDefaults) *targetRegistry {
+	return &targetRegistry{
+		defaults: defaults,
+		targets:  append([]Target(nil), targets...),
+		paused:   make(map[string]bool),
+	}
+}
+
+// active возвращает цели, которые нужно проверять, критичные — первыми
+func (r *targetRegistry) active() []Target {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Target, 0, len(r.targets))
+	for _, t := range r.targets {
+		if !r.paused[t.Name] {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].priorityLevel() < out[j].priorityLevel()
+	})
+	return out
+}
+
+type targetInfo struct {
+	Target `yaml:",inline"`
+	Paused bool `yaml:"paused"`
+}
+
+func (r *targetRegistry) list() []targetInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]targetInfo, 0, len(r.targets))
+	for _, t := range r.targets {
+		out = append(out, targetInfo{Target: t, Paused: r.paused[t.Name]})
+	}
+	return out
+}
+
+func (r *targetRegistry) get(name string) (Target, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.targets {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// add применяет defaults, проверяет цель и добавляет её; имена целей уникальны
+func (r *targetRegistry) add(t Target) (Target, error) {
+	t.inherit(r.defaults)
+	if err := t.normalize(); err != nil {
+		return t, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.targets {
+		if existing.Name == t.Name {
+			return t, fmt.Errorf("цель %s уже существует", t.Name)
+		}
+	}
+	r.targets = append(r.targets, t)
+	return t, nil
+}
+
+func (r *targetRegistry) remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, t := range r.targets {
+		if t.Name == name {
+			r.targets = append(r.targets[:i], r.targets[i+1:]...)
+			delete(r.paused, name)
+			return true
+		}
+	}
+	return false
+}
+
+func (r *targetRegistry) setPaused(name string, paused bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.targets {
+		if t.Name == name {
+			if paused {
+				r.paused[name] = true
+			} else {
+				delete(r.paused, name)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// replace заменяет набор уже проверенных целей, например полученных агентом от координатора;
+// приостановка сохраняется для оставшихся целей
+func (r *targetRegistry) replace(targets []Target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		names[t.Name] = true
+	}
+	for name := range r.paused {
+		if !names[name] {
+			delete(r.paused, name)
+		}
+	}
+	r.targets = append([]Target(nil), targets...)
+}
+
+// update применяет цели и defaults перечитанной конфигурации. Цели из managed (прежней
+// конфигурации), которых нет в targets, удаляются; добавленные через API остаются.
+// Неизменённые цели сохраняются вместе с накопленным состоянием: окном задержек
+// адаптивного таймаута, cookie сессии и паузой по Retry-After.
+func (r *targetRegistry) update(targets []Target, defaults TargetDefaults, managed map[string]bool) (changed []Target, removed []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := make(map[string]Target, len(r.targets))
+	for _, t := range r.targets {
+		current[t.Name] = t
+	}
+	names := make(map[string]bool, len(targets))
+	out := make([]Target, 0, len(targets))
+	for _, t := range targets {
+		names[t.Name] = true
+		if prev, ok := current[t.Name]; ok && len(diffValues(prev, t)) == 0 {
+			t = prev
+		} else {
+			changed = append(changed, t)
+		}
+		out = append(out, t)
+	}
+	for _, t := range r.targets {
+		if names[t.Name] {
+			continue
+		}
+		if managed[t.Name] {
+			removed = append(removed, t.Name)
+			delete(r.paused, t.Name)
+			continue
+		}
+		out = append(out, t)
+	}
+	r.targets = out
+	r.defaults = defaults
+	return changed, removed
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Подстановка переменных окружения ${NAME} или ${NAME:-значение по умолчанию}
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// requestTemplates — разобранные шаблоны URL, заголовков и тела цели.
+// Переменные окружения подставляются один раз при разборе, шаблоны выполняются
+// заново для каждого запроса; данными шаблона служит строка набора данных цели.
+type requestTemplates struct {
+	url     *template.Template
+	body    *template.Template
+	headers map[string]*template.Template
+	// Счётчик запросов цели для функции seq; общий для копий цели
+	seq *uint64
+}
+
+// compileTemplates разбирает строки запроса цели; для строк без подстановок шаблон не создаётся
+func (t *Target) compileTemplates() error {
+	tmpl := &requestTemplates{headers: make(map[string]*template.Template), seq: new(uint64)}
+
+	var err error
+	if tmpl.url, err = tmpl.compile("url", t.URL); err != nil {
+		return err
+	}
+	if tmpl.body, err = tmpl.compile("body", t.Body); err != nil {
+		return err
+	}
+	for name, value := range t.Headers {
+		h, err := tmpl.compile("заголовок "+name, value)
+		if err != nil {
+			return err
+		}
+		if h != nil {
+			tmpl.headers[name] = h
+		}
+	}
+
+	t.templates = nil
+	if tmpl.url != nil || tmpl.body != nil || len(tmpl.headers) > 0 {
+		t.templates = tmpl
+	}
+
+	// Для строки набора данных шаблоны выполняются сразу, чтобы ошибки в именах
+	// столбцов обнаружились при загрузке конфигурации
+	if t.DatasetRow != nil && t.templates != nil {
+		if _, err := t.render(); err != nil {
+			return err
+		}
+		atomic.StoreUint64(tmpl.seq, 0)
+	}
+	return nil
+}
+
+func (r *requestTemplates) compile(field, src string) (*template.Template, error) {
+	if !strings.Contains(src, "${") && !strings.Contains(src, "{{") {
+		return nil, nil
+	}
+
+	expanded, err := expandEnv(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	tmpl, err := template.New(field).Funcs(r.funcs()).Option("missingkey=error").Parse(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	return tmpl, nil
+}
+
+// expandEnv подставляет переменные окружения; незаданная переменная без значения по умолчанию — ошибка
+func expandEnv(src string) (string, error) {
+	var missing []string
+	out := envReference.ReplaceAllStringFunc(src, func(m string) string {
+		parts := envReference.FindStringSubmatch(m)
+		if v, ok := os.LookupEnv(parts[1]); ok {
+			return v
+		}
+		if strings.Contains(m, ":-") {
+			return parts[2]
+		}
+		missing = append(missing, parts[1])
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("не заданы переменные окружения: %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// funcs возвращает функции, доступные в шаблонах запроса
+func (r *requestTemplates) funcs() template.FuncMap {
+	return template.FuncMap{
+		"now":         time.Now,
+		"timestamp":   func() int64 { return time.Now().Unix() },
+		"timestampMs": func() int64 { return time.Now().UnixMilli() },
+		"uuid":        newUUID,
+		"randInt":     randInt,
+		"randString":  randString,
+		"env":         os.Getenv,
+		// Номер запроса цели начиная с 1
+		"seq": func() uint64 { return atomic.LoadUint64(r.seq) },
+	}
+}
+
+// remoteTemplateFuncs возвращает функции шаблонов для целей из API: без env,
+// читающей окружение хоста
+func remoteTemplateFuncs() template.FuncMap {
+	funcs := (&requestTemplates{seq: new(uint64)}).funcs()
+	delete(funcs, "env")
+	return funcs
+}
+
+// renderedRequest — значения запроса после подстановки шаблонов
+type renderedRequest struct {
+	url     string
+	body    string
+	headers map[string]string
+}
+
+// render выполняет шаблоны цели для очередного запроса
+func (t Target) render() (renderedRequest, error) {
+	out := renderedRequest{url: t.URL, body: t.Body, headers: t.Headers}
+	if t.templates == nil {
+		return out, nil
+	}
+
+	atomic.AddUint64(t.templates.seq, 1)
+
+	row := t.rowValues()
+	var err error
+	if out.url, err = execTemplate(t.templates.url, t.URL, row); err != nil {
+		return out, err
+	}
+	if out.body, err = execTemplate(t.templates.body, t.Body, row); err != nil {
+		return out, err
+	}
+	if len(t.templates.headers) > 0 {
+		out.headers = make(map[string]string, len(t.Headers))
+		for name, value := range t.Headers {
+			if out.headers[name], err = execTemplate(t.templates.headers[name], value, row); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// renderURL возвращает URL цели после подстановки шаблонов
+func (t Target) renderURL() (string, error) {
+	if t.templates == nil || t.templates.url == nil {
+		return t.URL, nil
+	}
+	return execTemplate(t.templates.url, t.URL, t.rowValues())
+}
+
+// rowValues возвращает значения столбцов строки набора данных для шаблонов
+func (t Target) rowValues() map[string]string {
+	if t.DatasetRow == nil {
+		return nil
+	}
+	return t.DatasetRow.Values
+}
+
+func execTemplate(tmpl *template.Template, fallback string, data map[string]string) (string, error) {
+	if tmpl == nil {
+		return fallback, nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("шаблон %s: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// newUUID возвращает случайный UUID версии 4
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// randInt возвращает случайное число из [min, max]
+func randInt(min, max int) int {
+	if max <= min {
+		return min
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
+	if err != nil {
+		return min
+	}
+	return min + int(n.Int64())
+}
+
+// randString возвращает случайную строку из латинских букв и цифр длиной n
+func randString(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[randInt(0, len(alphabet)-1)]
+	}
+	return string(b)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestTemplates(t *testing.T) {
+	t.Setenv("APICHECKER_TEST_TOKEN", "s3cret")
+
+	tests := []struct {
+		name    string
+		url     string
+		body    string
+		row     map[string]string
+		wantURL string
+		want    string
+		wantErr string
+	}{
+		{name: "без шаблонов", url: "https://example.com/", body: `{"a": {"b": 1}}`, want: `{"a": {"b": 1}}`},
+		{name: "столбец набора данных", url: "https://example.com/items/{{.id}}", row: map[string]string{"id": "42"}, wantURL: "https://example.com/items/42"},
+		{name: "нет столбца", url: "https://example.com/items/{{.sku}}", row: map[string]string{"id": "42"}, wantErr: `map has no entry for key "sku"`},
+		{name: "неизвестная функция", body: `{"user": "{{username}}"}`, wantErr: `function "username" not defined`},
+		{name: "переменная окружения", body: "token=${APICHECKER_TEST_TOKEN}", want: "token=s3cret"},
+		{name: "значение по умолчанию", body: "region=${APICHECKER_TEST_UNSET:-eu}", want: "region=eu"},
+		{name: "нет переменной окружения", body: "token=${APICHECKER_TEST_UNSET}", wantErr: "не заданы переменные окружения: APICHECKER_TEST_UNSET"},
+		{name: "env в шаблоне", body: `{{env "APICHECKER_TEST_TOKEN"}}`, want: "s3cret"},
+		{
+			name: "значения подставляются без экранирования",
+			body: `{"q": "{{.q}}"}`,
+			row:  map[string]string{"q": `a&b <c> "d"`},
+			want: `{"q": "a&b <c> "d""}`,
+		},
+		{
+			name:    "экранирование для URL и JSON",
+			url:     "https://example.com/?q={{.q | urlquery}}",
+			body:    `{"q": {{printf "%q" .q}}}`,
+			row:     map[string]string{"q": `a&b "d"`},
+			wantURL: "https://example.com/?q=a%26b+%22d%22",
+			want:    `{"q": "a&b \"d\""}`,
+		},
+		{name: "литерал {{ в шаблоне", body: `{"tmpl": "{{"{{"}}name}}", "n": {{seq}}}`, want: `{"tmpl": "{{name}}", "n": 1}`},
+		{name: "необработанный литерал {{", body: `{"tmpl": "{{name"}`, wantErr: "body:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := Target{URL: tt.url, Body: tt.body}
+			if target.URL == "" {
+				target.URL = "https://example.com/"
+			}
+			if tt.row != nil {
+				target.DatasetRow = &DatasetRow{Dataset: "items", Values: tt.row}
+			}
+			err := target.compileTemplates()
+			var got renderedRequest
+			if err == nil {
+				got, err = target.render()
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if tt.wantURL != "" && got.url != tt.wantURL {
+				t.Errorf("url %q, ожидался %q", got.url, tt.wantURL)
+			}
+			if got.body != tt.want {
+				t.Errorf("body %q, ожидалось %q", got.body, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTemplatesSeq(t *testing.T) {
+	target := Target{URL: "https://example.com/?n={{seq}}", Headers: map[string]string{"X-Static": "a", "X-Seq": "{{seq}}"}}
+	if err := target.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	for want := 1; want <= 3; want++ {
+		got, err := target.render()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := strconv.Itoa(want)
+		if got.url != "https://example.com/?n="+n || got.headers["X-Seq"] != n || got.headers["X-Static"] != "a" {
+			t.Errorf("запрос %d: url %s, заголовки %v", want, got.url, got.headers)
+		}
+	}
+}
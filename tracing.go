@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TracingConfig — экспорт спанов проверок в OpenTelemetry по OTLP/HTTP (JSON)
+type TracingConfig struct {
+	// Адрес приёма трасс, например http://otel-collector:4318/v1/traces
+	Endpoint    string            `yaml:"endpoint"`
+	ServiceName string            `yaml:"service_name"` // по умолчанию apichecker
+	Headers     map[string]string `yaml:"headers"`      // например, ключ доступа к коллектору
+	// Передавать цели заголовок traceparent, чтобы трассы сервера продолжали трассу проверки;
+	// по умолчанию да
+	Propagate *bool `yaml:"propagate"`
+}
+
+const (
+	tracingBatchSize     = 512
+	tracingFlushInterval = 5 * time.Second
+	tracingQueueSize     = 4096
+	tracingTimeout       = 10 * time.Second
+)
+
+// Виды и статусы спанов OTLP
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindClient   = 3
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+// tracer — экспорт спанов проверок. Проверки выполняются в разных местах программы,
+// поэтому экспорт, как и кэш транспортов, общий для всех целей.
+var tracer *checkTracer
+
+type checkTracer struct {
+	endpoint  string
+	service   string
+	headers   map[string]string
+	propagate bool
+	client    *http.Client
+
+	spans   chan otlpSpan
+	dropped uint64
+	mu      sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newCheckTracer(cfg TracingConfig) (*checkTracer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("tracing: не указан endpoint")
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		expanded, err := expandEnv(v)
+		if err != nil {
+			return nil, fmt.Errorf("tracing.headers.%s: %w", k, err)
+		}
+		headers[k] = expanded
+	}
+	t := &checkTracer{
+		endpoint:  cfg.Endpoint,
+		service:   cfg.ServiceName,
+		headers:   headers,
+		propagate: cfg.Propagate == nil || *cfg.Propagate,
+		client:    &http.Client{Timeout: tracingTimeout},
+		spans:     make(chan otlpSpan, tracingQueueSize),
+		done:      make(chan struct{}),
+	}
+	if t.service == "" {
+		t.service = "apichecker"
+	}
+	go t.run()
+	return t, nil
+}
+
+// traceContext — идентификаторы трассы одной проверки
+type traceContext struct {
+	traceID, spanID string
+}
+
+func newTraceContext() traceContext {
+	return traceContext{traceID: randomHex(16), spanID: randomHex(8)}
+}
+
+// traceparent возвращает заголовок W3C Trace Context для запроса к цели
+func (c traceContext) traceparent() string {
+	return "00-" + c.traceID + "-" + c.spanID + "-01"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// record создаёт спан проверки и дочерние спаны фаз запроса
+func (t *checkTracer) record(tc traceContext, target Target, req *http.Request, result CheckResult) {
+	// Спан проверки включает загрузку тела ответа
+	end := time.Now()
+	root := otlpSpan{
+		TraceID:   tc.traceID,
+		SpanID:    tc.spanID,
+		Name:      "check " + target.Name,
+		Kind:      otlpSpanKindClient,
+		Start:     otlpTime(result.Timestamp),
+		End:       otlpTime(end),
+		Status:    otlpStatus{Code: otlpStatusOK},
+		Attribute: []otlpAttribute{stringAttr("apichecker.target", target.Name), stringAttr("http.request.method", req.Method), stringAttr("url.full", redactURL(req.URL))},
+	}
+	if result.Status != 0 {
+		root.Attribute = append(root.Attribute, intAttr("http.response.status_code", int64(result.Status)))
+	}
+	if result.RemoteIP != "" {
+		root.Attribute = append(root.Attribute, stringAttr("network.peer.address", result.RemoteIP))
+	}
+	if !result.Success {
+		root.Status = otlpStatus{Code: otlpStatusError, Message: result.Error}
+	}
+	t.enqueue(root)
+
+	p := result.Phases
+	if p == nil {
+		return
+	}
+	for _, ph := range []struct {
+		name  string
+		start time.Time
+		d     time.Duration
+	}{
+		{"dns", p.dnsAt, p.DNS},
+		{"connect", p.connectAt, p.Connect},
+		{"tls", p.tlsAt, p.TLS},
+		{"ttfb", p.requestAt, p.TTFB},
+	} {
+		if ph.start.IsZero() || ph.d == 0 {
+			continue
+		}
+		t.enqueue(otlpSpan{
+			TraceID:  tc.traceID,
+			SpanID:   randomHex(8),
+			ParentID: tc.spanID,
+			Name:     ph.name,
+			Kind:     otlpSpanKindInternal,
+			Start:    otlpTime(ph.start),
+			End:      otlpTime(ph.start.Add(ph.d)),
+		})
+	}
+}
+
+// enqueue не блокирует проверку: при переполненной очереди спан отбрасывается
+func (t *checkTracer) enqueue(s otlpSpan) {
+	select {
+	case t.spans <- s:
+	default:
+		t.mu.Lock()
+		t.dropped++
+		t.mu.Unlock()
+	}
+}
+
+func (t *checkTracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(tracingFlushInterval)
+	defer ticker.Stop()
+
+	var batch []otlpSpan
+	for {
+		select {
+		case s, ok := <-t.spans:
+			if !ok {
+				t.export(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= tracingBatchSize {
+				t.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			t.export(batch)
+			batch = nil
+		}
+	}
+}
+
+func (t *checkTracer) export(batch []otlpSpan) {
+	t.mu.Lock()
+	dropped := t.dropped
+	t.dropped = 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		slog.Warn("Трассировка: спаны отброшены из-за переполнения очереди", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	payload := otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{stringAttr("service.name", t.service)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "apichecker"}, Spans: batch}},
+	}}}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Трассировка: ошибка сериализации спанов", "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		slog.Error("Трассировка: ошибка запроса", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		slog.Warn("Трассировка: ошибка отправки спанов", "error", err)
+		return
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		slog.Warn("Трассировка: коллектор ответил ошибкой", "status", resp.Status)
+	}
+}
+
+// Close отправляет накопленные спаны; вызывается после завершения проверок
+func (t *checkTracer) Close() {
+	t.once.Do(func() {
+		close(t.spans)
+		<-t.done
+	})
+}
+
+// Типы OTLP/JSON (opentelemetry-proto, коллектор принимает их на /v1/traces)
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID   string          `json:"traceId"`
+	SpanID    string          `json:"spanId"`
+	ParentID  string          `json:"parentSpanId,omitempty"`
+	Name      string          `json:"name"`
+	Kind      int             `json:"kind"`
+	Start     string          `json:"startTimeUnixNano"`
+	End       string          `json:"endTimeUnixNano"`
+	Attribute []otlpAttribute `json:"attributes,omitempty"`
+	Status    otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"` // int64 в OTLP/JSON передаётся строкой
+}
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int64) otlpAttribute {
+	s := strconv.FormatInt(value, 10)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ProxyDirect в поле proxy отключает прокси, в том числе заданный переменными окружения
+const ProxyDirect = "direct"
+
+// Транспорты разделяются целями с одинаковыми proxy, dns_server, resolve, http_version и connection,
+// чтобы соединения переиспользовались
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[string]*http.Transport)
+)
+
+// compileTransport проверяет proxy, dns_server, resolve, http_version и connection цели и готовит для них транспорт.
+// Без этих настроек используется стандартный транспорт, который учитывает HTTP_PROXY, HTTPS_PROXY и NO_PROXY.
+func (t *Target) compileTransport() error {
+	t.transport = nil
+	if err := t.compileDialer(); err != nil {
+		return err
+	}
+	if t.Proxy == "" && t.dialer == nil && t.HTTPVersion == "" && !t.cold() {
+		return nil
+	}
+
+	raw, err := expandEnv(t.Proxy)
+	if err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+	key := raw + "|" + t.HTTPVersion + "|" + fmt.Sprint(t.cold())
+	if t.dialer != nil {
+		key += "|" + t.dialer.key(t.DNSServer)
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	if tr, ok := transports[key]; ok {
+		t.transport = tr
+		return nil
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if t.dialer != nil {
+		tr.DialContext = t.dialer.DialContext
+	}
+	applyHTTPVersion(tr, t.HTTPVersion)
+	tr.DisableKeepAlives = t.cold()
+	switch raw {
+	case "":
+	case ProxyDirect:
+		tr.Proxy = nil
+	default:
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("proxy: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("proxy: неподдерживаемая схема %q, нужна http, https, socks5 или socks5h", u.Scheme)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("proxy: не указан адрес прокси-сервера")
+		}
+		// Логин и пароль из URL передаются прокси-серверу: Proxy-Authorization для HTTP,
+		// аутентификация по имени и паролю для SOCKS5
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	transports[key] = tr
+	t.transport = tr
+	return nil
+}
+
+// roundTripper возвращает транспорт запросов цели; nil означает стандартный
+func (t Target) roundTripper() http.RoundTripper {
+	if t.transport == nil {
+		return nil
+	}
+	return t.transport
+}
+
+// networkFlags — сетевые настройки из флагов командной строки; применяются к целям
+// и defaults, у которых соответствующие настройки не заданы в конфигурации
+type networkFlags struct {
+	Proxy     string
+	DNSServer string
+	Resolve   resolveFlags
+	IPv4      bool
+	IPv6      bool
+	DualStack bool
+	// Версия HTTP для целей без http_version
+	HTTPVersion string
+	// Режим соединений для целей без connection
+	Connection string
+}
+
+func (f networkFlags) apply(cfg *Config) error {
+	ipVersion, err := f.ipVersion()
+	if err != nil {
+		return err
+	}
+	if f.Proxy == "" && f.DNSServer == "" && len(f.Resolve) == 0 && ipVersion == "" && f.HTTPVersion == "" && f.Connection == "" {
+		return nil
+	}
+
+	f.fill(&cfg.Defaults.Proxy, &cfg.Defaults.DNSServer, &cfg.Defaults.Resolve)
+	// В defaults режим dual не переносится: цели через API добавляются по одной
+	if cfg.Defaults.IPVersion == "" && ipVersion != IPVersionDual {
+		cfg.Defaults.IPVersion = ipVersion
+	}
+	if cfg.Defaults.HTTPVersion == "" && f.HTTPVersion != HTTPVersionCompare {
+		cfg.Defaults.HTTPVersion = f.HTTPVersion
+	}
+	if cfg.Defaults.Connection == "" {
+		cfg.Defaults.Connection = f.Connection
+	}
+
+	var targets []Target
+	for _, t := range cfg.Targets {
+		f.fill(&t.Proxy, &t.DNSServer, &t.Resolve)
+		if t.IPVersion == "" {
+			t.IPVersion = ipVersion
+		}
+		if t.HTTPVersion == "" {
+			t.HTTPVersion = f.HTTPVersion
+		}
+		if t.Connection == "" {
+			t.Connection = f.Connection
+		}
+		for _, ft := range t.expandVariants() {
+			if err := ft.checkHTTPVersion(); err != nil {
+				return fmt.Errorf("%s: %w", ft.Name, err)
+			}
+			if err := ft.checkConnection(); err != nil {
+				return fmt.Errorf("%s: %w", ft.Name, err)
+			}
+			if err := ft.compileTransport(); err != nil {
+				return fmt.Errorf("%s: %w", ft.Name, err)
+			}
+			targets = append(targets, ft)
+		}
+	}
+	cfg.Targets = targets
+	return nil
+}
+
+func (f networkFlags) ipVersion() (string, error) {
+	switch {
+	case f.IPv4 && f.IPv6, f.DualStack && (f.IPv4 || f.IPv6):
+		return "", fmt.Errorf("флаги -4, -6 и -dual-stack несовместимы")
+	case f.IPv4:
+		return IPVersion4, nil
+	case f.IPv6:
+		return IPVersion6, nil
+	case f.DualStack:
+		return IPVersionDual, nil
+	}
+	return "", nil
+}
+
+func (f networkFlags) fill(proxy, dnsServer *string, resolve *map[string]string) {
+	if *proxy == "" {
+		*proxy = f.Proxy
+	}
+	if *dnsServer == "" {
+		*dnsServer = f.DNSServer
+	}
+	if len(*resolve) == 0 && len(f.Resolve) > 0 {
+		*resolve = f.Resolve
+	}
+}
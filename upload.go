@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// UploadConfig — куда отправить файл результатов после запуска
+type UploadConfig struct {
+	Type string `yaml:"type"` // s3, gcs или http
+	// Бакет для s3 и gcs
+	Bucket string `yaml:"bucket"`
+	// Шаблон ключа объекта, например "results/{{.Date}}/{{.Host}}-{{.Time}}.json"
+	Key string `yaml:"key"`
+	// s3: регион (по умолчанию из AWS_REGION) и адрес S3-совместимого хранилища,
+	// например MinIO; с endpoint бакет указывается в пути
+	Region   string `yaml:"region"`
+	Endpoint string `yaml:"endpoint"`
+	// http: адрес (тоже шаблон), метод (по умолчанию POST) и заголовки
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+const (
+	defaultUploadKey = "apichecker/{{.Date}}/{{.Host}}-{{.Time}}.json"
+	uploadTimeout    = time.Minute
+)
+
+// uploadData — значения для шаблонов ключа и адреса
+type uploadData struct {
+	Date    string // 2006-01-02
+	Time    string // 150405
+	Host    string
+	Version string
+	// Имена целей через дефис и метки запуска (-tag)
+	Target string
+	Tags   map[string]string
+}
+
+// uploader отправляет файл результатов в одно место
+type uploader struct {
+	cfg  UploadConfig
+	key  *template.Template
+	url  *template.Template
+	name string
+}
+
+func newUploaders(cfgs []UploadConfig) ([]*uploader, error) {
+	var out []*uploader
+	for i, cfg := range cfgs {
+		u, err := newUploader(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("uploads #%d: %w", i+1, err)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func newUploader(cfg UploadConfig) (*uploader, error) {
+	u := &uploader{cfg: cfg}
+	var err error
+	switch cfg.Type {
+	case "s3", "gcs":
+		if cfg.Bucket == "" {
+			return nil, fmt.Errorf("%s: не указан bucket", cfg.Type)
+		}
+		if cfg.Key == "" {
+			cfg.Key = defaultUploadKey
+		}
+		if u.key, err = template.New("key").Parse(cfg.Key); err != nil {
+			return nil, fmt.Errorf("%s: key: %w", cfg.Type, err)
+		}
+		if cfg.Type == "s3" && cfg.Region == "" {
+			cfg.Region = os.Getenv("AWS_REGION")
+		}
+		if cfg.Type == "s3" && cfg.Region == "" {
+			return nil, fmt.Errorf("s3: не указан region и не задана переменная AWS_REGION")
+		}
+		u.name = cfg.Type + "://" + cfg.Bucket
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http: не указан url")
+		}
+		if u.url, err = template.New("url").Parse(cfg.URL); err != nil {
+			return nil, fmt.Errorf("http: url: %w", err)
+		}
+		if cfg.Method == "" {
+			cfg.Method = http.MethodPost
+		}
+		u.name = cfg.URL
+	default:
+		return nil, fmt.Errorf("неизвестный тип %q (s3, gcs или http)", cfg.Type)
+	}
+	u.cfg = cfg
+	return u, nil
+}
+
+func newUploadData(run *RunMetadata, targets []Target) uploadData {
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	return uploadData{
+		Date:    run.Started.Format("2006-01-02"),
+		Time:    run.Started.Format("150405"),
+		Host:    run.Hostname,
+		Version: run.Version,
+		Target:  strings.Join(names, "-"),
+		Tags:    run.Tags,
+	}
+}
+
+// uploadResults отправляет файл результатов во все места; ошибки записываются в журнал
+func uploadResults(uploaders []*uploader, data []byte, values uploadData) {
+	for _, u := range uploaders {
+		ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+		location, err := u.upload(ctx, data, values)
+		cancel()
+		if err != nil {
+			slog.Error("Ошибка при выгрузке результатов", "destination", u.name, "error", err)
+			continue
+		}
+		slog.Info("Результаты выгружены", "location", location)
+	}
+}
+
+func (u *uploader) upload(ctx context.Context, data []byte, values uploadData) (string, error) {
+	if u.cfg.Type == "http" {
+		var target bytes.Buffer
+		if err := u.url.Execute(&target, values); err != nil {
+			return "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, u.cfg.Method, target.String(), bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range u.cfg.Headers {
+			req.Header.Set(k, os.ExpandEnv(v))
+		}
+		return target.String(), doUpload(req)
+	}
+
+	var key bytes.Buffer
+	if err := u.key.Execute(&key, values); err != nil {
+		return "", err
+	}
+	objectKey := strings.TrimPrefix(key.String(), "/")
+	if u.cfg.Type == "gcs" {
+		return "gs://" + u.cfg.Bucket + "/" + objectKey, u.uploadGCS(ctx, objectKey, data)
+	}
+	return "s3://" + u.cfg.Bucket + "/" + objectKey, u.uploadS3(ctx, objectKey, data)
+}
+
+func doUpload(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("статус %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// uploadGCS загружает объект через JSON API Cloud Storage; токен доступа берётся
+// из GOOGLE_OAUTH_ACCESS_TOKEN (например, gcloud auth print-access-token)
+func (u *uploader) uploadGCS(ctx context.Context, key string, data []byte) error {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		return fmt.Errorf("не задана переменная GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	endpoint := "https://storage.googleapis.com"
+	if u.cfg.Endpoint != "" {
+		endpoint = strings.TrimSuffix(u.cfg.Endpoint, "/")
+	}
+	target := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", endpoint, url.PathEscape(u.cfg.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return doUpload(req)
+}
+
+// uploadS3 загружает объект запросом PUT с подписью AWS Signature Version 4; ключи берутся
+// из AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY и, для временных, AWS_SESSION_TOKEN
+func (u *uploader) uploadS3(ctx context.Context, key string, data []byte) error {
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("не заданы AWS_ACCESS_KEY_ID и AWS_SECRET_ACCESS_KEY")
+	}
+
+	var target string
+	if u.cfg.Endpoint != "" {
+		target = strings.TrimSuffix(u.cfg.Endpoint, "/") + "/" + u.cfg.Bucket + "/" + awsURIEncode(key)
+	} else {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", u.cfg.Bucket, u.cfg.Region, awsURIEncode(key))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWSv4(req, data, accessKey, secretKey, u.cfg.Region, "s3", time.Now().UTC())
+	return doUpload(req)
+}
+
+// signAWSv4 подписывает запрос по AWS Signature Version 4 с подписью всех его заголовков
+func signAWSv4(req *http.Request, payload []byte, accessKey, secretKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalSum := sha256.Sum256([]byte(canonical))
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), day)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode кодирует ключ объекта по правилам S3: всё, кроме A-Z, a-z, 0-9, -._~ и /
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Режимы стартовой проверки
+const (
+	StartupCheckOff  = ""
+	StartupCheckWarn = "warn"
+	StartupCheckFail = "fail"
+)
+
+// validationProblem — проблема, найденная при стартовой проверке цели.
+// Config означает ошибку в конфигурации, а не временную недоступность цели.
+type validationProblem struct {
+	Target  string
+	Problem string
+	Config  bool
+}
+
+// validateTargets выполняет по одной проверке каждой цели и ищет ошибки конфигурации:
+// неразрешимые имена хостов, неверные учётные данные, неверные адреса
+func validateTargets(targets []Target) []validationProblem {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		problems []validationProblem
+	)
+
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			if p, ok := validateTarget(target); ok {
+				mu.Lock()
+				problems = append(problems, p)
+				mu.Unlock()
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	return problems
+}
+
+func validateTarget(target Target) (validationProblem, bool) {
+	problem := validationProblem{Target: target.Name, Config: true}
+
+	raw, err := target.renderURL()
+	if err != nil {
+		problem.Problem = err.Error()
+		return problem, true
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		problem.Problem = fmt.Sprintf("некорректный url %q", target.URL)
+		return problem, true
+	}
+
+	if host := u.Hostname(); net.ParseIP(host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := target.lookupHost(ctx, host, urlPort(u))
+		cancel()
+		if err != nil {
+			problem.Problem = fmt.Sprintf("не удаётся разрешить имя %s: %v", host, err)
+			return problem, true
+		}
+	}
+
+	result := executeCheck(context.Background(), target)
+	// Статус из expected_status — намеренная проверка, а не ошибка в конфигурации,
+	// например цель, которая должна отвечать 401 без токена
+	if !target.statusExpected(result.Status) {
+		switch result.Status {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			problem.Problem = fmt.Sprintf("доступ запрещён (%d): проверьте учётные данные", result.Status)
+			return problem, true
+		case http.StatusNotFound, http.StatusMethodNotAllowed:
+			problem.Problem = fmt.Sprintf("статус %d: проверьте url и метод", result.Status)
+			return problem, true
+		}
+	}
+
+	if !result.Success {
+		problem.Problem = "цель сейчас недоступна: " + result.Error
+		problem.Config = false
+		return problem, true
+	}
+
+	return problem, false
+}
+
+// runStartupCheck выполняет стартовую проверку и сообщает, можно ли продолжать запуск
+func runStartupCheck(targets []Target, mode string) error {
+	slog.Info("Стартовая проверка целей...")
+
+	configProblems := 0
+	for _, p := range validateTargets(targets) {
+		if p.Config {
+			configProblems++
+			slog.Error("Стартовая проверка", "target", p.Target, "problem", p.Problem)
+		} else {
+			slog.Warn("Стартовая проверка", "target", p.Target, "problem", p.Problem)
+		}
+	}
+
+	if configProblems > 0 && mode == StartupCheckFail {
+		return fmt.Errorf("найдено ошибок конфигурации: %d", configProblems)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestValidateTargetStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("code"))
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		code     string
+		expected []string
+		problem  bool
+		config   bool
+	}{
+		{"200", "200", nil, false, false},
+		{"401 — ошибка конфигурации", "401", nil, true, true},
+		{"404 — ошибка конфигурации", "404", nil, true, true},
+		{"ожидаемый 401", "401", []string{"401"}, false, false},
+		{"ожидаемый класс 4xx", "404", []string{"2xx", "4xx"}, false, false},
+		{"405 вне ожидаемых", "405", []string{"401"}, true, true},
+		{"503 — недоступность", "503", nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := Target{Name: "api", URL: srv.URL + "/?code=" + tt.code, ExpectedStatus: tt.expected}
+			if err := target.normalize(); err != nil {
+				t.Fatal(err)
+			}
+			p, ok := validateTarget(target)
+			if ok != tt.problem || (ok && p.Config != tt.config) {
+				t.Errorf("проблема %v (%+v), ожидалась %v, config %v", ok, p, tt.problem, tt.config)
+			}
+		})
+	}
+}
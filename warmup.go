@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// warmupFlag — флаг -warmup: число прогревочных проверок каждой цели или длительность прогрева
+type warmupFlag struct {
+	checks   int
+	duration time.Duration
+}
+
+func (w *warmupFlag) String() string {
+	if w.duration > 0 {
+		return w.duration.String()
+	}
+	return strconv.Itoa(w.checks)
+}
+
+func (w *warmupFlag) Set(s string) error {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return fmt.Errorf("число проверок не может быть отрицательным")
+		}
+		*w = warmupFlag{checks: n}
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return fmt.Errorf("нужно число проверок или длительность, например 5 или 10s")
+	}
+	*w = warmupFlag{duration: d}
+	return nil
+}
+
+func (w warmupFlag) enabled() bool {
+	return w.checks > 0 || w.duration > 0
+}
+
+// context возвращает контекст фазы прогрева: с длительностью он завершается по её истечении
+func (w warmupFlag) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.duration > 0 {
+		return context.WithTimeout(ctx, w.duration)
+	}
+	return context.WithCancel(ctx)
+}
+
+// runWarmup проверяет цели подряд, без интервалов, чтобы прогреть кэши DNS, сессии TLS
+// и кэши сервера. Результаты помечаются Warmup и не учитываются в статистике.
+func runWarmup(ctx context.Context, targets []Target, w warmupFlag) []CheckResult {
+	ctx, cancel := w.context(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var results []CheckResult
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t Target) {
+			defer wg.Done()
+			for i := 0; w.duration > 0 || i < w.checks; i++ {
+				if ctx.Err() != nil {
+					return
+				}
+				r := executeCheck(ctx, t)
+				if r.Aborted {
+					// Проверка прервана окончанием прогрева
+					return
+				}
+				r.Warmup = true
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}(t)
+	}
+	wg.Wait()
+	return results
+}